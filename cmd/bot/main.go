@@ -6,10 +6,10 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // la imagen alpine no trae zoneinfo (horarios de /schedule)
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
@@ -18,24 +18,32 @@ import (
 	"github.com/jose-valero/faceit-queue-bot/internal/adapters/faceit"
 	httpfaceit "github.com/jose-valero/faceit-queue-bot/internal/adapters/httpfaceit"
 	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/teams"
 	"github.com/jose-valero/faceit-queue-bot/internal/infra/config"
 	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/webhookauth"
 
 	"github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
-func startWebhookListener(ctx context.Context, dsn string, onEvent func(id int64, typ string, payload string)) error {
+// startWebhookListener: LISTEN faceit_webhook y avisa por wake cada NOTIFY. También avisa
+// al (re)conectar, así el worker retoma lo que llegó mientras no escuchábamos.
+func startWebhookListener(ctx context.Context, dsn string, wake chan<- struct{}) {
+	poke := func() {
+		select {
+		case wake <- struct{}{}:
+		default: // ya hay un aviso pendiente
+		}
+	}
 	go func() {
-		for {
+		for ctx.Err() == nil {
 			conn, err := pgx.Connect(ctx, dsn)
 			if err != nil {
 				log.Printf("listen connect: %v", err)
 				time.Sleep(2 * time.Second)
 				continue
 			}
-			defer conn.Close(ctx)
-
 			if _, err := conn.Exec(ctx, "LISTEN faceit_webhook"); err != nil {
 				log.Printf("listen exec: %v", err)
 				_ = conn.Close(ctx)
@@ -43,28 +51,36 @@ func startWebhookListener(ctx context.Context, dsn string, onEvent func(id int64
 				continue
 			}
 			log.Println("👂 listening on channel faceit_webhook")
+			poke()
 
 			for {
-				n, err := conn.WaitForNotification(ctx)
-				if err != nil {
+				if _, err := conn.WaitForNotification(ctx); err != nil {
 					log.Printf("listen wait: %v", err)
-					_ = conn.Close(ctx)
+					_ = conn.Close(context.Background())
 					break // reconectar
 				}
-				id, _ := strconv.ParseInt(n.Payload, 10, 64)
-				var typ, payload string
-				// leemos el evento
-				if err := conn.QueryRow(ctx,
-					"SELECT type, payload::text FROM webhook_events WHERE id=$1", id,
-				).Scan(&typ, &payload); err != nil {
-					log.Printf("listen fetch %d: %v", id, err)
-					continue
-				}
-				onEvent(id, typ, payload)
+				poke()
 			}
 		}
 	}()
-	return nil
+}
+
+// pruneQueue: aplica las gracias AFK/LEFT de la policy de una cola
+func pruneQueue(policyRepo *storage.PolicyRepo, queueSvc *service.QueueService, guildID, queue string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	pol, err := policyRepo.Get(ctx, guildID, queue)
+	cancel()
+	if err != nil {
+		return
+	}
+
+	afk := time.Duration(pol.AFKTimeoutSeconds) * time.Second
+	left := time.Duration(pol.DropIfLeftSeconds) * time.Second
+	if afk <= 0 && left <= 0 {
+		return
+	}
+
+	_, _, _ = queueSvc.Prune(context.Background(), guildID, queue, afk, left)
 }
 
 func main() {
@@ -84,26 +100,20 @@ func main() {
 	}
 	log.Println("✅ DB lista y migrada")
 
-	// justo después de "✅ DB lista y migrada"
-	_ = startWebhookListener(context.Background(), cfg.DatabaseURL, func(id int64, typ, payload string) {
-		log.Printf("[WEBHOOK] id=%d type=%s payload=%s", id, typ, payload)
-
-		// (opcional) si querés disparar lógica:
-		switch typ {
-		case "match_object_created", "match_status_configuring", "match_status_ready", "match_demo_ready", "match_status_finished", "match_status_cancelled", "match_status_aborted":
-			// ejemplo muy simple: podrías parsear payload y llamar roomsSvc.HandleMatchEvent
-			// aquí lo dejamos en log para validar visualmente
-		case "hub_user_role_added", "hub_user_role_removed":
-			// por ahora solo log
-		}
-	})
-
 	// Repos
 	usersRepo := storage.NewUserRepo(db)
 	queueRepo := storage.NewQueueRepo(db)
 	policyRepo := storage.NewPolicyRepo(db)
 	uiRepo := storage.NewUIRepo(db)
 	roomsRepo := storage.NewMatchRoomsRepo(db)
+	matchRepo := storage.NewPendingMatchRepo(db)
+	penaltyRepo := storage.NewPenaltyRepo(db)
+	banRepo := storage.NewBanRepo(db)
+	oauthStates := storage.NewOAuthStateRepo(db)
+	settingsRepo := storage.NewGuildSettingsRepo(db)
+	partyRepo := storage.NewPartyRepo(db)
+	scheduleRepo := storage.NewScheduleRepo(db)
+	webhookEvents := storage.NewWebhookEventRepo(db)
 
 	// FACEIT client (antes de services que lo usan)
 	fc := faceit.New(cfg.FaceitAPIKey)
@@ -125,39 +135,90 @@ func main() {
 	log.Printf("✅ Conectado como %s (%s)", s.State.User.Username, s.State.User.ID)
 
 	// Services
-	linkSvc := service.NewLinkService(fc, usersRepo, cfg.FaceitHubID)
-	queueSvc := service.NewQueueService(fc, usersRepo, queueRepo, policyRepo, cfg.FaceitHubID)
+	// config por guild; lo del .env queda como default (y es todo lo que usa un deploy de un solo guild)
+	settingsSvc := service.NewGuildSettingsService(settingsRepo, fc, storage.GuildSettings{
+		GuildID:         cfg.DiscordGuild,
+		VoiceCategoryID: cfg.VoiceCategoryID,
+		AFKChannelID:    cfg.AFKChannelID,
+	}, cfg.FaceitHubID)
+	linkSvc := service.NewLinkService(fc, usersRepo, settingsSvc)
+	if cfg.FaceitOAuthClientID != "" {
+		oauth := faceit.NewOAuth(cfg.FaceitOAuthClientID, cfg.FaceitOAuthClientSecret, cfg.FaceitOAuthRedirectURL)
+		linkSvc.EnableOAuth(oauth, oauthStates, cfg.OAuthStateSecret)
+		log.Println("🔐 /link via OAuth FACEIT")
+	}
+	notifier := discordrouter.NewNotifier(s, uiRepo, cfg.DiscordGuild, cfg.NotifyChannelID)
+	queueSvc := service.NewQueueService(fc, usersRepo, queueRepo, policyRepo, penaltyRepo, banRepo, partyRepo, notifier, settingsSvc)
+	banSvc := service.NewBanService(banRepo, queueRepo, notifier)
+	penaltySvc := service.NewPenaltyService(penaltyRepo, queueRepo, policyRepo, notifier)
+	queueSvc.EnableReliability(penaltySvc)
+	queueSvc.EnablePriority(discordrouter.NewMembers(s))
+	scheduleSvc := service.NewScheduleService(scheduleRepo, settingsSvc, policyRepo, queueRepo)
+	queueSvc.EnableSchedules(scheduleSvc)
+	partySvc := service.NewPartyService(partyRepo, usersRepo, queueRepo, settingsSvc, notifier)
 	policySvc := service.NewPolicyService(policyRepo)
+	matchSvc := service.NewMatchService(matchRepo, usersRepo, policyRepo, penaltyRepo)
+	if cfg.TeamStrategy != "" {
+		st, ok := teams.StrategyByName(cfg.TeamStrategy)
+		if !ok {
+			log.Fatalf("TEAM_STRATEGY inválido: %q (auto | exhaustive | greedy)", cfg.TeamStrategy)
+		}
+		matchSvc.UseStrategy(st)
+	}
 
 	// Rooms service (ya tenemos s y fc)
-	roomsSvc := service.NewMatchRoomsService(s, fc, usersRepo, roomsRepo, cfg.DiscordGuild, "XCG Faceit Match")
+	roomsSvc := service.NewMatchRoomsService(s, fc, usersRepo, roomsRepo, settingsSvc, "XCG Faceit Match")
+
+	// eventos que guarda el receptor de webhooks (cmd/webhook): LISTEN + worker serial
+	webhookSvc := service.NewWebhookService(webhookEvents, storage.NewWebhookFailureRepo(db), roomsSvc, linkSvc)
+	wake := make(chan struct{}, 1)
+	startWebhookListener(context.Background(), cfg.DatabaseURL, wake)
+	go webhookSvc.Run(context.Background(), wake)
 
 	// Webhook FACEIT (callback opcional)
-	web := httpfaceit.New(cfg.WebhookSecret, usersRepo, func(ctx context.Context, matchID, status string) {
-		roomsSvc.HandleMatchEvent(ctx, matchID, status)
-	})
+	web := httpfaceit.New(cfg.WebhookSecret, linkSvc.HandleMemberEvent, func(ctx context.Context, matchID, status string) {
+		if err := roomsSvc.HandleMatchEvent(ctx, matchID, status); err != nil {
+			log.Printf("[webhook.http] match=%s status=%s: %v", matchID, status, err)
+		}
+	}, httpfaceit.WithVerifier(webhookauth.New(webhookauth.Config{
+		SigningSecrets: cfg.WebhookSigningSecrets,
+		StaticSecrets:  []string{cfg.WebhookSecret, cfg.WebhookSecretPrevious},
+		Tolerance:      time.Duration(cfg.WebhookToleranceSeconds) * time.Second,
+	}), storage.NewWebhookDedupRepo(db)), httpfaceit.WithOAuthCallback(func(ctx context.Context, state, code string) (string, error) {
+		msg, err := linkSvc.CompleteOAuth(ctx, state, code)
+		// el mensaje viene con markdown de Discord; en la página va plano
+		return strings.ReplaceAll(msg, "**", ""), err
+	}))
 	go web.Start(cfg.HTTPAddr)
 
 	// Router
 	r := discordrouter.NewRouter(
 		s,
 		cfg.DiscordGuild,
-		discordrouter.VoiceCfg{
-			AllowedCategoryID: cfg.VoiceCategoryID,
-			AFKChannelID:      cfg.AFKChannelID,
-		},
+		cfg.CommandsGlobal,
+		settingsSvc,
 		linkSvc,
 		queueSvc,
 		policySvc,
 		uiRepo,
 		cfg.AdminRoleIDs,
 		roomsSvc,
+		matchSvc,
+		partySvc,
+		banSvc,
+		penaltySvc,
+		scheduleSvc,
+		webhookSvc,
 	)
 	if err := r.Register(); err != nil {
 		log.Fatalf("registrando comandos: %v", err)
 	}
 	r.Handlers()
-	log.Printf("✅ comandos registrados en guild %s", cfg.DiscordGuild)
+	if cfg.CommandsGlobal {
+		log.Printf("✅ comandos registrados globalmente")
+	} else {
+		log.Printf("✅ comandos registrados en %d guild(s)", len(s.State.Guilds))
+	}
 
 	// Pruner (gracias AFK/LEFT) — ya usás segundos
 	go func() {
@@ -165,20 +226,26 @@ func main() {
 		defer t.Stop()
 		for range t.C {
 			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			pol, err := policyRepo.Get(ctx, cfg.DiscordGuild)
+			uis, err := uiRepo.ListAll(ctx)
 			cancel()
 			if err != nil {
+				log.Printf("[pruner] colas: %v", err)
 				continue
 			}
-
-			afk := time.Duration(pol.AFKTimeoutSeconds) * time.Second
-			left := time.Duration(pol.DropIfLeftSeconds) * time.Second
-			if afk <= 0 && left <= 0 {
-				continue
+			for _, ui := range uis {
+				pruneQueue(policyRepo, queueSvc, ui.GuildID, ui.QueueName)
 			}
+		}
+	}()
 
-			_, _, _ = queueSvc.Prune(context.Background(), cfg.DiscordGuild, afk, left)
-			// opcional: r.RefreshIfVisible(cfg.DiscordGuild)
+	// salas de voz de matches locales vencidas
+	go func() {
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for range t.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			roomsSvc.CleanupExpired(ctx)
+			cancel()
 		}
 	}()
 
@@ -31,6 +31,7 @@ func handler(ctx context.Context) (string, error) {
 	defer cancel()
 
 	_, _ = pool.Exec(cctx, `DELETE FROM webhook_dedup WHERE received_at < now() - INTERVAL '7 days';`)
+	_, _ = pool.Exec(cctx, `DELETE FROM webhook_failures WHERE resolved_at < now() - INTERVAL '30 days';`)
 	_, _ = pool.Exec(cctx, `
 DELETE FROM faceit_match_status
 WHERE updated_at < now() - INTERVAL '30 days'
@@ -3,26 +3,42 @@ package main
 import (
 	"context"
 	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/faceitevent"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/webhookauth"
 )
 
 var (
 	db          *pgxpool.Pool
 	secretHdr   = getenv("WEBHOOK_HEADER_NAME", "X-Faceit-Secret")
 	secretValue = os.Getenv("WEBHOOK_HEADER_VALUE")
+
+	// con WEBHOOK_SIGNING_SECRET se exige firma HMAC; si no, el secreto en header/query de siempre.
+	// Los *_PREVIOUS sirven para rotar sin cortar entregas.
+	verifier = webhookauth.New(webhookauth.Config{
+		SigningSecrets: []string{os.Getenv("WEBHOOK_SIGNING_SECRET"), os.Getenv("WEBHOOK_SIGNING_SECRET_PREVIOUS")},
+		StaticSecrets:  []string{secretValue, os.Getenv("WEBHOOK_HEADER_VALUE_PREVIOUS")},
+		Tolerance:      time.Duration(atoi(os.Getenv("WEBHOOK_TOLERANCE_SECONDS"))) * time.Second,
+	})
 )
 
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
 func getenv(k, def string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -99,6 +115,14 @@ func readSecret(req events.APIGatewayV2HTTPRequest) string {
 	return ""
 }
 
+// header: API Gateway v2 manda los headers en minúsculas
+func header(req events.APIGatewayV2HTTPRequest, k string) string {
+	if v := req.Headers[strings.ToLower(k)]; v != "" {
+		return v
+	}
+	return req.Headers[k]
+}
+
 func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	// LOGS ÚTILES (se ven siempre)
 	ua := ""
@@ -110,14 +134,7 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 	fmt.Printf("webhook hit | path=%s method=%s ip=%s ua=%q b64=%v headers=%d\n",
 		req.RawPath, req.RequestContext.HTTP.Method, ip, ua, req.IsBase64Encoded, len(req.Headers))
 
-	// 1) validar secreto (una sola vez)
-	got := readSecret(req)
-	if secretValue == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secretValue)) != 1 {
-		fmt.Println("auth: unauthorized (missing/invalid secret)")
-		return events.APIGatewayV2HTTPResponse{StatusCode: 401, Body: "unauthorized"}, nil
-	}
-
-	// 2) body crudo
+	// 1) body crudo (la firma es sobre estos bytes)
 	body := req.Body
 	if req.IsBase64Encoded {
 		dec, err := base64.StdEncoding.DecodeString(req.Body)
@@ -128,54 +145,55 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 		body = string(dec)
 	}
 
-	// 2.5) persistir evento y notificar al bot (si hay DB)
-	if db != nil && body != "" {
-		var evt map[string]any
-		_ = json.Unmarshal([]byte(body), &evt)
-		t := str(get(evt, "type"))
-		if t == "" {
-			// algunos payloads usan "event", caemos ahí
-			t = str(get(evt, "event"))
-			if t == "" {
-				t = "unknown"
-			}
-		}
+	// 2) validar firma o secreto (una sola vez)
+	replayKey, err := verifier.Verify(webhookauth.Request{
+		Signature: header(req, webhookauth.SignatureHeader),
+		Timestamp: header(req, webhookauth.TimestampHeader),
+		Static:    readSecret(req),
+		Body:      []byte(body),
+	})
+	if err != nil {
+		fmt.Println("auth:", err)
+		return events.APIGatewayV2HTTPResponse{StatusCode: webhookauth.Status(err), Body: "unauthorized"}, nil
+	}
 
-		// insert + notify
-		var id int64
-		ctxIns, cancelIns := context.WithTimeout(ctx, 2*time.Second)
-		err := db.QueryRow(ctxIns,
-			`INSERT INTO webhook_events(type, payload) VALUES ($1, $2::jsonb) RETURNING id`,
-			t, body,
-		).Scan(&id)
-		cancelIns()
-		if err != nil {
-			fmt.Println("events insert:", err)
-		} else {
-			// pg_notify para que el bot escuche en tiempo real
-			_, _ = db.Exec(context.Background(),
-				`SELECT pg_notify('faceit_webhook', $1)`, fmt.Sprint(id),
-			)
+	// 3) dedup (event_id y firma) + persistir + notificar al bot, todo en una transacción (si hay DB)
+	ev, decErr := faceitevent.Decode([]byte(body))
+	if decErr != nil {
+		fmt.Println("decode:", decErr)
+		// JSON roto no entra en webhook_events (jsonb) y reintentarlo no lo arregla
+		if !errors.Is(decErr, faceitevent.ErrNoType) && !errors.Is(decErr, faceitevent.ErrEmpty) {
+			return events.APIGatewayV2HTTPResponse{StatusCode: 400, Body: "invalid payload"}, nil
 		}
 	}
-
-	// 3) dedup (si hay DB)
-	if db != nil {
-		sum := sha256.Sum256([]byte(body))
-		key := hex.EncodeToString(sum[:])
-
-		dctx, cancel := context.WithTimeout(ctx, 2*time.Second)
-		if _, err := db.Exec(dctx, `INSERT INTO webhook_dedup(dedup_key) VALUES ($1) ON CONFLICT DO NOTHING`, key); err != nil {
-			fmt.Println("dedup insert error:", err)
+	var storedID int64
+	if db != nil && body != "" {
+		id, dup, err := storeEvent(ctx, ev, body, replayKey)
+		if err != nil {
+			// rollback: no quedó nada guardado, ni las claves de dedup; que FACEIT reintente
+			fmt.Println("store event:", err)
+			return events.APIGatewayV2HTTPResponse{StatusCode: 500, Body: "error"}, nil
+		}
+		if dup {
+			// misma respuesta para un event_id repetido y para una firma reenviada (replay)
+			fmt.Printf("duplicate delivery | event_id=%q type=%s\n", ev.ID, ev.Type)
+			return events.APIGatewayV2HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"ok":true,"duplicate":true}`,
+			}, nil
 		}
-		cancel()
+		fmt.Printf("stored event id=%d type=%s\n", id, ev.Type)
+		storedID = id
 	}
 
-	// 4) router (opcional)
-	if db != nil && body != "" {
-		var evt map[string]any
-		_ = json.Unmarshal([]byte(body), &evt)
-		_ = processEvent(ctx, db, evt)
+	// 4) router (opcional); si falla igual respondemos 200: el evento ya quedó guardado
+	// y la falla en webhook_failures, que reintenta el bot (reintentarlo FACEIT no sirve,
+	// lo frena el dedup)
+	if db != nil && decErr == nil {
+		if err := processWithRetry(ctx, ev); err != nil && storedID > 0 {
+			recordFailure(ctx, storedID, ev, err)
+		}
 	}
 
 	// 5) OK
@@ -186,6 +204,57 @@ func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.AP
 	}, nil
 }
 
+// dedupKey: el event_id de FACEIT si vino (los reintentos lo repiten aunque cambie el body);
+// si no, el hash del body como antes
+func dedupKey(ev faceitevent.Event, body string) string {
+	if ev.ID != "" {
+		return "event:" + ev.ID
+	}
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// storeEvent: anota las claves de dedup (la del evento y, con firma, la de replay); si alguna
+// ya estaba es una entrega repetida y no se toca nada. Si no, guarda el evento y avisa al
+// bot. Todo en una transacción: las claves y el NOTIFY quedan recién con el commit.
+func storeEvent(ctx context.Context, ev faceitevent.Event, body, replayKey string) (int64, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	keys := []string{dedupKey(ev, body)}
+	if replayKey != "" {
+		keys = append(keys, replayKey)
+	}
+	tag, err := tx.Exec(ctx, `INSERT INTO webhook_dedup(dedup_key) SELECT unnest($1::text[]) ON CONFLICT DO NOTHING`, keys)
+	if err != nil {
+		return 0, false, err
+	}
+	if tag.RowsAffected() < int64(len(keys)) {
+		return 0, true, nil // el rollback suelta la clave que sí era nueva
+	}
+
+	t := string(ev.Type)
+	if t == "" {
+		t = "unknown"
+	}
+	var id int64
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO webhook_events(type, payload) VALUES ($1, $2::jsonb) RETURNING id`, t, body,
+	).Scan(&id); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_notify('faceit_webhook', $1)`, fmt.Sprint(id)); err != nil {
+		return 0, false, err
+	}
+	return id, false, tx.Commit(ctx)
+}
+
 func main() { lambda.Start(handler) }
 
 // ---------- dominio mínimo ----------
@@ -204,74 +273,67 @@ SET status = EXCLUDED.status,
 	return err
 }
 
-func processEvent(ctx context.Context, pool *pgxpool.Pool, evt map[string]any) error {
-	r := Repo{db: pool}
-	t := str(get(evt, "type"))
-
-	// Faceit suele usar "data" o "payload"
-	data := obj(evt["data"])
-	if len(data) == 0 {
-		data = obj(evt["payload"])
-	}
-
-	matchID := firstNonEmpty(str(get(data, "match_id")), str(get(data, "matchId")))
-	hubID := firstNonEmpty(str(get(data, "hub_id")), str(get(data, "hubId")), str(get(evt, "hub_id")))
-	demoURL := firstNonEmpty(str(get(data, "demo_url")), str(get(data, "demoUrl")))
-	now := time.Now().UTC()
-
-	var err error
-	switch t {
-	case "match_object_created":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "created", "", &now)
-	case "match_status_configuring":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "configuring", "", &now)
-	case "match_status_ready":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "ready", "", &now)
-	case "match_demo_ready":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "finished", demoURL, &now)
-	case "match_status_finished":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "finished", "", &now)
-	case "match_status_cancelled":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "cancelled", "", &now)
-	case "match_status_aborted":
-		err = r.UpsertMatchStatus(ctx, matchID, hubID, "aborted", "", &now)
-	default:
-		// otros eventos los ignoramos por ahora
+func processEvent(ctx context.Context, pool *pgxpool.Pool, ev faceitevent.Event) error {
+	// sólo nos interesan los eventos de match (el resto queda en webhook_events para el bot)
+	if ev.Match == nil || ev.Match.MatchID == "" {
 		return nil
 	}
+	r := Repo{db: pool}
+	now := time.Now().UTC()
+	m := ev.Match
+	err := r.UpsertMatchStatus(ctx, m.MatchID, m.HubID, m.Status, m.DemoURL, &now)
 	if err != nil {
 		fmt.Println("UpsertMatchStatus:", err)
 	}
 	return err
 }
 
-// ---------- helpers JSON ----------
-func get(m map[string]any, key string) any {
-	if m == nil {
-		return nil
-	}
-	return m[key]
-}
-func obj(v any) map[string]any {
-	if o, ok := v.(map[string]any); ok {
-		return o
+// processBackoff: esperas entre intentos de processEvent dentro de la misma invocación
+// (la Lambda no vive para reintentar más tarde; lo que sigue es el dead letter)
+var processBackoff = []time.Duration{200 * time.Millisecond, time.Second}
+
+func processWithRetry(ctx context.Context, ev faceitevent.Event) error {
+	err := processEvent(ctx, db, ev)
+	for _, d := range processBackoff {
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d):
+		}
+		err = processEvent(ctx, db, ev)
 	}
-	return map[string]any{}
+	return err
 }
-func str(v any) string {
-	if s, ok := v.(string); ok {
-		return s
+
+// recordFailure: deja la falla en webhook_failures (source "webhook") con el primer
+// reintento en un minuto: lo hace el worker del bot, que rehace el upsert con su backoff.
+// Se ve en /webhook failures y se puede forzar con /webhook replay.
+func recordFailure(ctx context.Context, eventID int64, ev faceitevent.Event, cause error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	hubID := ""
+	if ev.Match != nil {
+		hubID = ev.Match.HubID
 	}
-	return ""
-}
-func firstNonEmpty(ss ...string) string {
-	for _, s := range ss {
-		if s != "" {
-			return s
-		}
+	_, err := db.Exec(ctx, `
+INSERT INTO webhook_failures (event_id, source, hub_id, attempts, last_error, next_attempt_at)
+VALUES ($1, 'webhook', $2, 1, $3, now() + INTERVAL '1 minute')
+ON CONFLICT (event_id, source) DO UPDATE
+SET attempts = webhook_failures.attempts + 1,
+    last_error = EXCLUDED.last_error,
+    next_attempt_at = EXCLUDED.next_attempt_at,
+    last_failed_at = now(),
+    resolved_at = NULL
+`, eventID, hubID, cause.Error())
+	if err != nil {
+		fmt.Println("record failure:", err)
 	}
-	return ""
 }
+
+// ---------- helpers ----------
 func nullIfEmpty(s string) any {
 	if s == "" {
 		return nil
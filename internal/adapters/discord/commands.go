@@ -4,11 +4,27 @@ import "github.com/bwmarrin/discordgo"
 
 var adminPerms int64 = discordgo.PermissionAdministrator
 
+// queueOpt: opción "queue" (cola con nombre) para los subcomandos que la aceptan
+func queueOpt() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+	}
+}
+
 var Commands = []*discordgo.ApplicationCommand{
 	{
 		Name:                     "queueui",
-		Description:              "Publica o reposta la UI de la cola en este canal",
+		Description:              "Publica o reposta la UI de una cola en este canal (crea la cola si es nueva)",
 		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Nombre de la cola (vacío = main)"},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "category",
+				Description:  "Categoría de voz propia de esta cola (vacío = la del servidor)",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory},
+			},
+		},
 	},
 	{
 		Name:        "fcplayer",
@@ -22,12 +38,11 @@ var Commands = []*discordgo.ApplicationCommand{
 	},
 	{
 		Name:        "link",
-		Description: "XCG: Vincula tu cuenta de FACEIT (via nickname)",
+		Description: "XCG: Vincula tu cuenta de FACEIT (login en FACEIT o nickname)",
 		Options: []*discordgo.ApplicationCommandOption{{
 			Type:        discordgo.ApplicationCommandOptionString,
 			Name:        "nick",
-			Description: "Tu nickname en FACEIT",
-			Required:    true,
+			Description: "Tu nickname en FACEIT (sólo si el server no usa login FACEIT)",
 		}},
 	},
 	{
@@ -42,9 +57,192 @@ var Commands = []*discordgo.ApplicationCommand{
 		Name:        "queue",
 		Description: "Gestiona la cola XCG",
 		Options: []*discordgo.ApplicationCommandOption{
-			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "join", Description: "Unirte a la cola"},
-			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "leave", Description: "Salir de la cola"},
-			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "status", Description: "Ver la cola"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "join", Description: "Unirte a la cola", Options: queueOpt()},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "leave", Description: "Salir de la cola", Options: queueOpt()},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "status", Description: "Ver la cola", Options: queueOpt()},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "position", Description: "Tu lugar en la cola y cuánto falta", Options: queueOpt()},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "history",
+				Description: "Movimientos recientes de un jugador en la cola (admin)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "admin",
+				Description: "Manejo de la cola para eventos (admin)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Meter a un jugador en la cola (sin chequeos de ban/cooldown/rango)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+							{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "move",
+						Description: "Mover a un jugador a otro puesto",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+							{Type: discordgo.ApplicationCommandOptionInteger, Name: "position", Description: "Puesto nuevo (1 = primero)", Required: true},
+							{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "swap",
+						Description: "Intercambiar los lugares de dos jugadores",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+							{Type: discordgo.ApplicationCommandOptionUser, Name: "other", Description: "El otro jugador", Required: true},
+							{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "clear", Description: "Vaciar la cola", Options: queueOpt()},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "lock",
+						Description: "Cerrar la cola a joins nuevos (los que están siguen)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionString, Name: "reason", Description: "Motivo (se muestra al que intenta unirse)"},
+							{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "unlock", Description: "Reabrir la cola", Options: queueOpt()},
+				},
+			},
+		},
+	},
+	{
+		Name:        "party",
+		Description: "Armá una party para entrar juntos a la cola y jugar en el mismo equipo",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "invite",
+				Description: "Invitar a un jugador a tu party",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "leave", Description: "Salir de tu party"},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "kick",
+				Description: "Sacar a alguien de tu party (líder)",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "Ver tu party"},
+		},
+	},
+	{
+		Name:                     "queueban",
+		Description:              "Bans de cola (admins)",
+		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Banear a un jugador de las colas",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "reason", Description: "Motivo", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "minutes", Description: "Duración en minutos (vacío = escalada: 30m, 2h, 24h, 7d, permanente)"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Levantar el ban de un jugador",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Jugador", Required: true},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "Ver bans activos"},
+		},
+	},
+	{
+		Name:                     "priority",
+		Description:              "Prioridad en la cola por rol (admins)",
+		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "Dar (o cambiar) prioridad a un rol",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "Rol", Required: true},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "weight", Description: "Peso (1-100; más alto = antes en la cola)", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "Quitarle la prioridad a un rol",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "Rol", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "aging",
+				Description: "Cada cuántos minutos esperando se suma +1 de prioridad",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "minutes", Description: "Minutos (0 = orden estricto por prioridad)", Required: true},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "Ver roles con prioridad"},
+		},
+	},
+	{
+		Name:                     "schedule",
+		Description:              "Horarios de apertura de las colas (admins)",
+		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "add",
+				Description: "Agregar una ventana de apertura",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "days", Description: "Días (ej: lun-vie, sab,dom, todos)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "from", Description: "Abre a las HH:MM", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "to", Description: "Cierra a las HH:MM (antes que from = al día siguiente)", Required: true},
+					{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "clear", Description: "Borrar los horarios (la cola queda siempre abierta)", Options: queueOpt()},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "Ver horarios y estado", Options: queueOpt()},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "timezone",
+				Description: "Zona horaria de los horarios del servidor",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "tz", Description: "Zona IANA (ej: America/Argentina/Buenos_Aires)", Required: true},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "webhook",
+		Description:              "Webhooks de FACEIT que fallaron (admins)",
+		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "failures", Description: "Ver los eventos con error y sus reintentos"},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "replay",
+				Description: "Volver a procesar un evento guardado",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "ID del evento (de /webhook failures)", Required: true},
+				},
+			},
 		},
 	},
 	{
@@ -52,17 +250,116 @@ var Commands = []*discordgo.ApplicationCommand{
 		Description:              "Ver o cambiar reglas de la cola (admins)",
 		DefaultMemberPermissions: &adminPerms,
 		Options: []*discordgo.ApplicationCommandOption{
-			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "Ver configuración"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "Ver configuración", Options: queueOpt()},
 			{
 				Type:        discordgo.ApplicationCommandOptionSubCommand,
 				Name:        "set",
 				Description: "Actualizar configuración (sólo lo que pases)",
 				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionString, Name: "queue", Description: "Cola (vacío = main)"},
 					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "require_member", Description: "Requerir membresía del hub"},
 					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "voice_required", Description: "Requerir estar en voz"},
 					{Type: discordgo.ApplicationCommandOptionInteger, Name: "afk_timeout_seconds", Description: "AFK timeout (segundos)"},
 					{Type: discordgo.ApplicationCommandOptionInteger, Name: "drop_if_left_seconds", Description: "Drop si deja el server (segundos)"},
 					{Type: discordgo.ApplicationCommandOptionInteger, Name: "cooldown_after_loss_seconds", Description: "Cooldown tras derrota (segundos)"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "require_verified", Description: "Exigir cuenta FACEIT verificada para unirse"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "min_level", Description: "Nivel FACEIT mínimo (1-10, 0 = sin límite)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_level", Description: "Nivel FACEIT máximo (1-10, 0 = sin límite)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "min_elo", Description: "Elo mínimo (0 = sin límite)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_elo", Description: "Elo máximo (0 = sin límite)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "leave_limit", Description: "Salidas/podas toleradas antes del cooldown automático (0 = apagado)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "leave_window_minutes", Description: "Ventana para contar salidas (minutos)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "leave_penalty_seconds", Description: "Primer cooldown por salidas (se duplica al reincidir)"},
+					{Type: discordgo.ApplicationCommandOptionBoolean, Name: "flush_on_close", Description: "Vaciar la cola cuando cierra por horario (/schedule)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "match_size", Description: "Jugadores para armar un match (par, ej: 10)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "ready_check_seconds", Description: "Ventana para aceptar el match (0 = sin ready-check)"},
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "ready_penalty_seconds", Description: "Penalización por declinar/no aceptar (segundos)"},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "team_mode",
+						Description: "Cómo se arman los equipos",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Auto-balance por elo", Value: "balance"},
+							{Name: "Draft de capitanes", Value: "captains"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "join_enforcement",
+						Description: "Qué hacer si falla la validación post-join",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Sólo avisar", Value: "warn"},
+							{Name: "Bloquear en la cola", Value: "block"},
+							{Name: "Sacar de la cola", Value: "remove"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:                     "setup",
+		Description:              "Configura el bot para este servidor (admins)",
+		DefaultMemberPermissions: &adminPerms,
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "show", Description: "Ver configuración del servidor"},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "queues",
+				Description: "En cuántas colas puede estar un jugador a la vez",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_per_player", Description: "Máximo de colas simultáneas (>= 1)", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "party",
+				Description: "Tamaño máximo de las parties",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "max_size", Description: "Jugadores por party (1-5, 1 = sin parties)", Required: true},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "voice",
+				Description: "Categoría de voz permitida y canal AFK",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "category",
+						Description:  "Categoría donde hay que estar para la cola",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory},
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "afk",
+						Description:  "Canal AFK",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				Name:        "hub",
+				Description: "Hubs FACEIT de este servidor",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "add",
+						Description: "Agregar un hub (se valida contra FACEIT)",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionString, Name: "hub_id", Description: "ID del hub", Required: true},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Name:        "remove",
+						Description: "Quitar un hub",
+						Options: []*discordgo.ApplicationCommandOption{
+							{Type: discordgo.ApplicationCommandOptionString, Name: "hub_id", Description: "ID del hub", Required: true},
+						},
+					},
+					{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "Ver hubs configurados"},
 				},
 			},
 		},
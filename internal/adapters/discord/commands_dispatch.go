@@ -4,12 +4,14 @@ package discord
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
 )
 
 // esto es basicamente mi reciver function
@@ -46,12 +48,23 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 
 		//--> para vincular jugador, saber quien es quien
 	case "link":
-		nick, _ := optStr(ic, "nick")
+		if r.link.OAuthEnabled() {
+			r.replyOAuthLink(ctx, ic)
+			return
+		}
+		nick, ok := optStr(ic, "nick")
+		if !ok || nick == "" {
+			ReplyEphemeral(s, ic, "Usa `/link nick:<tu_nick_FACEIT>`")
+			return
+		}
 		msg, err := r.link.Link(ctx, nick, ic.Member.User.ID, ic.GuildID)
 		if err != nil {
-			msg = "⚠️ No se pudo vincular: " + err.Error()
+			ReplyEphemeral(s, ic, "⚠️ No se pudo vincular: "+err.Error())
+			return
 		}
 		ReplyEphemeral(s, ic, msg)
+		// vínculo por nick: ofrecer verificar que la cuenta es suya
+		r.replyVerifyCode(ctx, ic)
 
 		//--> para desvincular jugador
 	case "unlink":
@@ -63,14 +76,41 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 
 	//--> datos basicos para saber si estas vinculado o no, muestra hace cuando te vinculaste y el id de Faceit
 	case "whoami":
-		msg, err := r.link.WhoAmI(ctx, ic.Member.User.ID)
+		msg, err := r.link.WhoAmI(ctx, ic.GuildID, ic.Member.User.ID)
 		if err != nil {
 			msg = "No estás linkeado. Usa `/link nick:<tu_nick_FACEIT_tal_cual_como_esta_en_tu perfil>`"
 		}
+		// penalizaciones del guild (vigente + historial), estés linkeado o no
+		if pen, err := r.penalties.Describe(ctx, ic.GuildID, ic.Member.User.ID); err == nil {
+			msg += "\n\n" + pen
+		}
 		ReplyEphemeral(s, ic, msg)
 
 	//--> para ver e
 	case "queue":
+		// /queue admin <sub>: las opciones vienen anidadas en el grupo (↙️ queue_admin.go)
+		if group, gsub, opts, ok := subcmdGroup(ic); ok && group == "admin" {
+			if r.requireAdminOrRoles(s, ic) {
+				r.handleQueueAdmin(ctx, ic, gsub, opts)
+			}
+			return
+		}
+		// todos los subcomandos (menos history) aceptan queue:<nombre>; sin nombre es la principal
+		rawQueue, _ := optStr(ic, "queue")
+		queue, err := r.resolveQueue(ctx, ic.GuildID, rawQueue)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		// /queue position es para todos (es sólo lectura)
+		if sub, ok := subcmdName(ic); ok && sub == "position" {
+			msg, err := r.queue.Position(ctx, ic.GuildID, queue, ic.Member.User.ID)
+			if err != nil {
+				msg = "⚠️ No pude consultar tu lugar: " + err.Error()
+			}
+			ReplyEphemeral(s, ic, msg)
+			return
+		}
 		// la interaccion por comandos la hacemos solo para admins por que es modo de prueba
 		// la intencion es que el jugador se una con la UI y no con los comandos.
 		if !r.requireAdminOrRoles(s, ic) {
@@ -90,36 +130,50 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 			// primero validamos que el usuario este en un canal de voz permitido
 			stop := step("component.queue_join.total")
 			defer stop()
-			if pol, err := r.policy.GetPolicy(ctx, ic.GuildID); err == nil && pol.VoiceRequired {
-				ok, why := r.userInAllowedVoice(ic.GuildID, ic.Member.User.ID)
+			if pol, err := r.policy.GetPolicy(ctx, ic.GuildID, queue); err == nil && pol.VoiceRequired {
+				ok, why := r.partyInAllowedVoice(ctx, ic.GuildID, queue, ic.Member.User.ID)
 				if !ok {
 					ReplyEphemeral(s, ic, "🎮 Debes estar en voz. "+why)
 					return
 				}
 			}
-			msg, err := r.queue.Join(ctx, ic.GuildID, ic.Member.User.ID)
+			msg, err := r.queue.Join(ctx, ic.GuildID, queue, ic.Member.User.ID)
 			if err != nil {
 				msg = "⚠️ No se pudo unir a la cola: " + err.Error()
 			}
 			ReplyEphemeral(s, ic, msg)
 			defer step("queue.join")()
 			defer step("ui.fast")()
-			go r.refreshQueueUI(ic.GuildID)
+			go r.refreshQueueUI(ic.GuildID, queue)
+			go r.tryPopMatch(ic.GuildID, queue)
 
 		case "leave":
-			msg, err := r.queue.Leave(ctx, ic.GuildID, ic.Member.User.ID)
+			msg, err := r.queue.Leave(ctx, ic.GuildID, queue, ic.Member.User.ID)
 			if err != nil {
 				msg = "⚠️ No se pudo salir de la cola: " + err.Error()
 			}
 			ReplyEphemeral(s, ic, msg)
-			go r.refreshQueueUI(ic.GuildID)
+			go r.refreshQueueUI(ic.GuildID, queue)
 
 		case "status":
-			msg, err := r.queue.Status(ctx, ic.GuildID)
+			msg, err := r.queue.Status(ctx, ic.GuildID, queue)
 			if err != nil {
 				msg = "⚠️ No se pudo consultar la cola: " + err.Error()
 			}
 			ReplyEphemeral(s, ic, msg)
+
+		//--> para ver por qué alguien entró/salió de la cola
+		case "history":
+			uid, ok := optUserID(ic, "user")
+			if !ok {
+				ReplyEphemeral(s, ic, "⚠️ Falta el jugador.")
+				return
+			}
+			msg, err := r.queue.History(ctx, ic.GuildID, uid, 15)
+			if err != nil {
+				msg = "⚠️ No pude leer el historial: " + err.Error()
+			}
+			ReplyEphemeral(s, ic, msg)
 		}
 
 	//--> para configurar las policy por los comandos
@@ -127,6 +181,12 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 		if !r.requireAdminOrRoles(s, ic) {
 			return
 		}
+		rawQueue, _ := optStr(ic, "queue")
+		queue, err := r.resolveQueue(ctx, ic.GuildID, rawQueue)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
 		if sub, ok := subcmdName(ic); ok && sub == "set" {
 			var patch service.PolicyPatch
 			if v, ok := optBool(ic, "require_member"); ok {
@@ -144,22 +204,282 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 			if v, ok := optInt(ic, "cooldown_after_loss_seconds"); ok {
 				patch.CooldownAfterLossSeconds = &v
 			}
+			if v, ok := optInt(ic, "match_size"); ok {
+				patch.MatchSize = &v
+			}
+			if v, ok := optInt(ic, "ready_check_seconds"); ok {
+				patch.ReadyCheckSeconds = &v
+			}
+			if v, ok := optInt(ic, "ready_penalty_seconds"); ok {
+				patch.ReadyPenaltySeconds = &v
+			}
+			if v, ok := optStr(ic, "team_mode"); ok {
+				patch.TeamMode = &v
+			}
+			if v, ok := optStr(ic, "join_enforcement"); ok {
+				patch.JoinEnforcement = &v
+			}
+			if v, ok := optBool(ic, "require_verified"); ok {
+				patch.RequireVerified = &v
+			}
+			if v, ok := optInt(ic, "min_level"); ok {
+				patch.MinSkillLevel = &v
+			}
+			if v, ok := optInt(ic, "max_level"); ok {
+				patch.MaxSkillLevel = &v
+			}
+			if v, ok := optInt(ic, "min_elo"); ok {
+				patch.MinElo = &v
+			}
+			if v, ok := optInt(ic, "max_elo"); ok {
+				patch.MaxElo = &v
+			}
+			if v, ok := optInt(ic, "leave_limit"); ok {
+				patch.LeaveLimit = &v
+			}
+			if v, ok := optInt(ic, "leave_window_minutes"); ok {
+				patch.LeaveWindowMinutes = &v
+			}
+			if v, ok := optInt(ic, "leave_penalty_seconds"); ok {
+				patch.LeavePenaltySeconds = &v
+			}
+			if v, ok := optBool(ic, "flush_on_close"); ok {
+				patch.FlushOnClose = &v
+			}
 
-			msg, err := r.policy.Update(ctx, ic.GuildID, patch)
+			msg, err := r.policy.Update(ctx, ic.GuildID, queue, patch)
 			if err != nil {
 				ReplyEphemeral(s, ic, "⚠️ No pude actualizar: "+err.Error())
 				return
 			}
 			ReplyEphemeral(s, ic, "✅ Policy actualizada.\n"+msg)
+			go r.refreshQueueUI(ic.GuildID, queue)
 			return
 		}
+		msg, err := r.policy.Show(ctx, ic.GuildID, queue)
+		if err != nil {
+			msg = "⚠️ No pude leer la policy: " + err.Error()
+		}
+		ReplyEphemeral(s, ic, msg)
+
+	//--> parties: la invitación se publica en el canal con botones para el invitado
+	case "party":
+		uid := ic.Member.User.ID
+		target, _ := optUserID(ic, "user")
+		sub, _ := subcmdName(ic)
+		var msg string
+		var err error
+		switch sub {
+		case "invite":
+			var partyID int64
+			partyID, msg, err = r.parties.Invite(ctx, ic.GuildID, uid, target)
+			if err == nil && partyID != 0 {
+				r.postPartyInvite(ic.ChannelID, partyID, uid, target)
+			}
+		case "leave":
+			msg, err = r.parties.Leave(ctx, ic.GuildID, uid)
+		case "kick":
+			msg, err = r.parties.Kick(ctx, ic.GuildID, uid, target)
+		default:
+			msg, err = r.parties.Show(ctx, ic.GuildID, uid)
+		}
+		if err != nil {
+			msg = "⚠️ No pude actualizar la party: " + err.Error()
+		}
+		ReplyEphemeral(s, ic, msg)
+
+	//--> bans de cola (admins); el kick del panel también puede banear
+	case "queueban":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		target, _ := optUserID(ic, "user")
+		sub, _ := subcmdName(ic)
+		var msg string
+		var err error
+		switch sub {
+		case "add":
+			reason, _ := optStr(ic, "reason")
+			minutes, _ := optInt(ic, "minutes")
+			var queues []string
+			queues, msg, err = r.bans.Add(ctx, ic.GuildID, target, ic.Member.User.ID, reason, time.Duration(minutes)*time.Minute)
+			for _, q := range queues {
+				go r.refreshQueueUI(ic.GuildID, q)
+			}
+		case "remove":
+			msg, err = r.bans.Remove(ctx, ic.GuildID, target, ic.Member.User.ID)
+		default:
+			msg, err = r.bans.List(ctx, ic.GuildID)
+		}
+		if err != nil {
+			msg = "⚠️ No pude actualizar los bans: " + err.Error()
+		}
+		ReplyEphemeral(s, ic, msg)
+
+	//--> prioridad por rol; la cola se reordena sola (el orden lo arma la DB)
+	case "priority":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		role, _ := optRoleID(ic, "role")
+		sub, _ := subcmdName(ic)
+		var msg string
+		var err error
+		switch sub {
+		case "set":
+			weight, _ := optInt(ic, "weight")
+			msg, err = r.settings.SetPriorityRole(ctx, ic.GuildID, role, weight)
+		case "remove":
+			msg, err = r.settings.RemovePriorityRole(ctx, ic.GuildID, role)
+		case "aging":
+			minutes, _ := optInt(ic, "minutes")
+			msg, err = r.settings.SetPriorityAging(ctx, ic.GuildID, minutes)
+			if err == nil {
+				go r.refreshGuildQueues(ic.GuildID)
+			}
+		default:
+			msg, err = r.settings.ListPriorityRoles(ctx, ic.GuildID)
+		}
+		if err != nil {
+			msg = "⚠️ No pude actualizar la prioridad: " + err.Error()
+		}
+		ReplyEphemeral(s, ic, msg)
+
+	//--> horarios de apertura por cola
+	case "schedule":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		sub, _ := subcmdName(ic)
+		if sub == "timezone" {
+			tz, _ := optStr(ic, "tz")
+			msg, err := r.settings.SetTimezone(ctx, ic.GuildID, tz)
+			if err != nil {
+				msg = "⚠️ No pude cambiar la zona horaria: " + err.Error()
+			} else {
+				go r.refreshGuildQueues(ic.GuildID)
+			}
+			ReplyEphemeral(s, ic, msg)
+			return
+		}
+		rawQueue, _ := optStr(ic, "queue")
+		queue, err := r.resolveQueue(ctx, ic.GuildID, rawQueue)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		var msg string
+		switch sub {
+		case "add":
+			days, _ := optStr(ic, "days")
+			from, _ := optStr(ic, "from")
+			to, _ := optStr(ic, "to")
+			msg, err = r.schedules.Add(ctx, ic.GuildID, queue, days, from, to)
+		case "clear":
+			msg, err = r.schedules.Clear(ctx, ic.GuildID, queue)
+		default:
+			msg, err = r.schedules.Show(ctx, ic.GuildID, queue)
+		}
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ No pude actualizar los horarios: "+err.Error())
+			return
+		}
+		ReplyEphemeral(s, ic, msg)
+		if sub != "show" {
+			go r.refreshQueueUI(ic.GuildID, queue)
+		}
+
+	//--> dead letter de webhooks de FACEIT (sólo los de los hubs de este servidor)
+	case "webhook":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		hubs := r.settings.HubIDs(ctx, ic.GuildID)
+		var msg string
+		var err error
+		sub, _ := subcmdName(ic)
+		switch sub {
+		case "replay":
+			id, _ := optInt(ic, "id")
+			if id < 1 {
+				ReplyEphemeral(s, ic, "⚠️ Pasá un id de evento válido.")
+				return
+			}
+			msg, err = r.webhooks.Replay(ctx, int64(id), hubs)
+		default:
+			msg, err = r.webhooks.Failures(ctx, hubs)
+		}
+		if err != nil {
+			msg = "⚠️ No pude leer los webhooks: " + err.Error()
+		}
+		ReplyEphemeral(s, ic, msg)
+
+	//--> config del guild (hub, voz); lo que no se pase queda como estaba
+	case "setup":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		var hubID string
+		sub, _ := subcmdName(ic)
+		if group, gsub, opts, ok := subcmdGroup(ic); ok && group == "hub" {
+			sub = "hub " + gsub
+			for _, o := range opts {
+				if o.Name == "hub_id" {
+					hubID = o.StringValue()
+				}
+			}
+		}
+		var msg string
+		var err error
+		switch sub {
+		case "voice":
+			cur, gerr := r.settings.Get(ctx, ic.GuildID)
+			if gerr != nil {
+				ReplyEphemeral(s, ic, "⚠️ "+gerr.Error())
+				return
+			}
+			category, afk := cur.VoiceCategoryID, cur.AFKChannelID
+			if v, ok := optChannelID(ic, "category"); ok {
+				category = v
+			}
+			if v, ok := optChannelID(ic, "afk"); ok {
+				afk = v
+			}
+			msg, err = r.settings.SetVoice(ctx, ic.GuildID, category, afk)
+		case "queues":
+			n, _ := optInt(ic, "max_per_player")
+			msg, err = r.settings.SetMaxQueues(ctx, ic.GuildID, n)
+		case "party":
+			n, _ := optInt(ic, "max_size")
+			msg, err = r.settings.SetMaxPartySize(ctx, ic.GuildID, n)
+		case "hub add":
+			msg, err = r.settings.AddHub(ctx, ic.GuildID, hubID)
+		case "hub remove":
+			msg, err = r.settings.RemoveHub(ctx, ic.GuildID, hubID)
+		case "hub list":
+			msg, err = r.settings.ListHubs(ctx, ic.GuildID)
+		default:
+			msg, err = r.settings.Show(ctx, ic.GuildID)
+		}
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ No pude actualizar el setup: "+err.Error())
+			return
+		}
+		ReplyEphemeral(s, ic, msg)
 
 	case "queueui":
-		if err := r.publishQueueUI(ctx, ic.GuildID, ic.ChannelID); err != nil {
+		rawQueue, _ := optStr(ic, "name")
+		queue, err := service.NormalizeQueueName(rawQueue)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		category, _ := optChannelID(ic, "category")
+		if err := r.publishQueueUI(ctx, ic.GuildID, queue, ic.ChannelID, category); err != nil {
 			ReplyEphemeral(s, ic, "⚠️ No pude publicar la UI: "+err.Error())
 			return
 		}
-		ReplyEphemeral(s, ic, "✅ UI publicada aquí. Usa los botones para unirte/salir.")
+		ReplyEphemeral(s, ic, "✅ UI de la cola **"+queue+"** publicada aquí. Usa los botones para unirte/salir.")
 
 		//--> este caso es para prueba
 		// agregos ids de personas en canales y deberia crear y mover a los jugadores
@@ -207,7 +527,7 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 			return
 		}
 
-		if err := r.rooms.DebugEnsureRooms(context.Background(), matchID); err != nil {
+		if err := r.rooms.DebugEnsureRooms(context.Background(), ic.GuildID, matchID); err != nil {
 			ReplyEphemeral(s, ic, "⚠️ ensure: "+err.Error())
 			return
 		}
@@ -218,3 +538,57 @@ func (r *Router) handleSlashCommand(s *discordgo.Session, ic *discordgo.Interact
 		ReplyEphemeral(s, ic, "✅ Match **"+matchID+"** creado y jugadores movidos si estaban en voz.")
 	}
 }
+
+// replyOAuthLink: botón con la URL firmada de "Iniciar sesión con FACEIT" (vence en minutos, un solo uso)
+func (r *Router) replyOAuthLink(ctx context.Context, ic *discordgo.InteractionCreate) {
+	u, err := r.link.StartOAuth(ctx, ic.Member.User.ID, ic.GuildID)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ No pude generar el link: "+err.Error())
+		return
+	}
+	_, err = r.s.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
+		Content: "🔐 Iniciá sesión en FACEIT para vincular tu cuenta. El link es personal y vence en 10 minutos.",
+		Flags:   discordgo.MessageFlagsEphemeral,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style: discordgo.LinkButton,
+					Label: "Iniciar sesión con FACEIT",
+					URL:   u,
+				},
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("[link] oauth followup: %v", err)
+	}
+}
+
+// replyVerifyCode: código para poner en el perfil FACEIT + botón "Verificar" (no hace nada si ya está verificado)
+func (r *Router) replyVerifyCode(ctx context.Context, ic *discordgo.InteractionCreate) {
+	code, exp, err := r.link.IssueVerifyCode(ctx, ic.GuildID, ic.Member.User.ID)
+	if err != nil {
+		if !errors.Is(err, service.ErrAlreadyVerified) && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[link] verify code: %v", err)
+		}
+		return
+	}
+	_, err = r.s.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
+		Content: fmt.Sprintf("🔐 **Verificá que la cuenta es tuya:** poné `%s` en tu nombre de Steam (o tu nombre en CS2), "+
+			"esperá a que FACEIT lo muestre y tocá **Verificar**. El código vence <t:%d:R>.", code, exp.Unix()),
+		Flags: discordgo.MessageFlagsEphemeral,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SuccessButton,
+					Label:    "Verificar",
+					CustomID: "link_verify",
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔎"},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("[link] verify followup: %v", err)
+	}
+}
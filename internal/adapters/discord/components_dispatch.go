@@ -10,6 +10,9 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+// panelBanDuration: el "kick + ban" del panel admin
+const panelBanDuration = 30 * time.Minute
+
 func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.InteractionCreate) {
 	data := ic.MessageComponentData()
 
@@ -18,7 +21,10 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer cancel()
 
-	switch data.CustomID {
+	// custom IDs con argumento: "accion:arg" (ej: "ready_accept:42", "queue_join:5v5")
+	action, arg, _ := strings.Cut(data.CustomID, ":")
+
+	switch action {
 
 	case "queue_join":
 		stop := step("component.queue_join.total")
@@ -27,15 +33,21 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 			ReplyEphemeral(s, ic, "⏳ Esperá un segundo…")
 			return
 		}
-		if pol, err := r.policy.GetPolicy(ctx, ic.GuildID); err == nil && pol.VoiceRequired {
-			ok, why := r.userInAllowedVoice(ic.GuildID, ic.Member.User.ID)
+		// botones viejos (sin arg) son de la principal
+		queue, err := r.resolveQueue(ctx, ic.GuildID, arg)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		if pol, err := r.policy.GetPolicy(ctx, ic.GuildID, queue); err == nil && pol.VoiceRequired {
+			ok, why := r.partyInAllowedVoice(ctx, ic.GuildID, queue, ic.Member.User.ID)
 			if !ok {
 				ReplyEphemeral(r.s, ic, "🎮 "+why)
 				return
 			}
 		}
 		t := time.Now()
-		msg, err := r.queue.Join(ctx, ic.GuildID, ic.Member.User.ID)
+		msg, err := r.queue.Join(ctx, ic.GuildID, queue, ic.Member.User.ID)
 		log.Printf("[trace M] queue.Join dur=%s err=%v", time.Since(t), err)
 		if err != nil {
 			msg = "⚠️ No se pudo unir a la cola: " + err.Error()
@@ -43,19 +55,52 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 		ReplyEphemeral(r.s, ic, msg)
 		defer step("queue.join")()
 		defer step("ui.fast")()
-		go r.refreshQueueUI(ic.GuildID)
+		go r.refreshQueueUI(ic.GuildID, queue)
+		go r.tryPopMatch(ic.GuildID, queue)
 
 	case "queue_leave":
 		if !r.clickLimiter.Allow(ic.Member.User.ID) {
 			ReplyEphemeral(s, ic, "⏳ Esperá un segundo…")
 			return
 		}
-		msg, err := r.queue.Leave(ctx, ic.GuildID, ic.Member.User.ID)
+		queue, err := r.resolveQueue(ctx, ic.GuildID, arg)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		msg, err := r.queue.Leave(ctx, ic.GuildID, queue, ic.Member.User.ID)
 		if err != nil {
 			msg = "⚠️ No se pudo salir de la cola: " + err.Error()
 		}
 		ReplyEphemeral(r.s, ic, msg)
-		go r.refreshQueueUI(ic.GuildID)
+		go r.refreshQueueUI(ic.GuildID, queue)
+
+	case "queue_position":
+		if !r.clickLimiter.Allow(ic.Member.User.ID) {
+			ReplyEphemeral(s, ic, "⏳ Esperá un segundo…")
+			return
+		}
+		queue, err := r.resolveQueue(ctx, ic.GuildID, arg)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		msg, err := r.queue.Position(ctx, ic.GuildID, queue, ic.Member.User.ID)
+		if err != nil {
+			msg = "⚠️ No pude consultar tu lugar: " + err.Error()
+		}
+		ReplyEphemeral(r.s, ic, msg)
+
+	case "link_verify":
+		if !r.clickLimiter.Allow(ic.Member.User.ID) {
+			ReplyEphemeral(s, ic, "⏳ Esperá un segundo…")
+			return
+		}
+		msg, err := r.link.VerifyCode(ctx, ic.GuildID, ic.Member.User.ID)
+		if err != nil {
+			msg = "⚠️ No pude consultar tu perfil de FACEIT: " + err.Error()
+		}
+		ReplyEphemeral(r.s, ic, msg)
 
 	case "admin_panel":
 		if !r.clickLimiter.Allow(ic.Member.User.ID) {
@@ -69,7 +114,12 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		items, err := r.queue.ListRich(ctx, ic.GuildID, 25)
+		queue, err := r.resolveQueue(ctx, ic.GuildID, arg)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		items, err := r.queue.ListRich(ctx, ic.GuildID, queue, 25)
 		if err != nil {
 			ReplyEphemeral(s, ic, "⚠️ No pude listar la cola: "+err.Error())
 			return
@@ -97,18 +147,30 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 				Description: desc,
 			})
 		}
-		row := discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				discordgo.SelectMenu{
-					CustomID:    "kick_select",
-					Placeholder: "Selecciona a quién kickear",
-					Options:     opts,
+		rows := []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    "kick_select:" + queue,
+						Placeholder: "Selecciona a quién kickear",
+						Options:     opts,
+					},
+				},
+			},
+			// kick que no se puede deshacer volviendo a entrar
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    "kickban_select:" + queue,
+						Placeholder: "Kick + ban por 30 min",
+						Options:     opts,
+					},
 				},
 			},
 		}
 		_, err = s.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
-			Content:    "Elige un jugador para **kickear**:",
-			Components: []discordgo.MessageComponent{row},
+			Content:    "Elige un jugador para **kickear** (o kickear y banear 30 min):",
+			Components: rows,
 		})
 		if err != nil {
 			ReplyEphemeral(s, ic, "⚠️ No pude mostrar el panel admin: "+err.Error())
@@ -128,17 +190,55 @@ func (r *Router) handleMessageComponent(s *discordgo.Session, ic *discordgo.Inte
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		msg, err := r.queue.Leave(ctx, ic.GuildID, uid)
+		queue, err := r.resolveQueue(ctx, ic.GuildID, arg)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ "+err.Error())
+			return
+		}
+		ok, err := r.queue.Kick(ctx, ic.GuildID, queue, uid, ic.Member.User.ID, "kick desde panel admin")
 		if err != nil {
 			ReplyEphemeral(s, ic, "⚠️ Error al kickear: "+err.Error())
 			return
 		}
 
-		if strings.Contains(msg, "No estabas") {
+		if !ok {
 			ReplyEphemeral(s, ic, "ℹ️ Ese jugador no estaba en la cola.")
 		} else {
 			ReplyEphemeral(s, ic, "✅ Jugador kickeado.")
 		}
-		go r.refreshQueueUI(ic.GuildID)
+		go r.refreshQueueUI(ic.GuildID, queue)
+
+		//--> solo admins: saca de todas las colas y banea (QueueService.Join lo rechaza hasta que venza)
+	case "kickban_select":
+		if !r.requireAdminOrRoles(s, ic) {
+			return
+		}
+		if len(data.Values) == 0 {
+			ReplyEphemeral(s, ic, "⚠️ Selección inválida.")
+			return
+		}
+		uid := strings.TrimPrefix(data.Values[0], "uid:")
+		queues, msg, err := r.bans.Add(ctx, ic.GuildID, uid, ic.Member.User.ID, "kick + ban desde panel admin", panelBanDuration)
+		if err != nil {
+			ReplyEphemeral(s, ic, "⚠️ Error al banear: "+err.Error())
+			return
+		}
+		ReplyEphemeral(s, ic, msg)
+		for _, q := range queues {
+			go r.refreshQueueUI(ic.GuildID, q)
+		}
+
+	case "ready_accept", "ready_decline":
+		r.handleReadyResponse(ctx, ic, arg, action == "ready_accept")
+
+	case "party_accept", "party_decline":
+		r.handlePartyResponse(ctx, ic, arg, action == "party_accept")
+
+	case "draft_pick":
+		if len(data.Values) == 0 {
+			ReplyEphemeral(s, ic, "⚠️ Selección inválida.")
+			return
+		}
+		r.handleDraftPick(ctx, ic, arg, strings.TrimPrefix(data.Values[0], "uid:"))
 	}
 }
@@ -0,0 +1,160 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// postDraft: publica el draft de capitanes (embed + select del capitán de turno)
+func (r *Router) postDraft(ctx context.Context, m storage.PendingMatch) error {
+	ui, err := r.matchUI(ctx, m)
+	if err != nil {
+		return err
+	}
+	content, embed, comps := renderDraft(m)
+	msg, err := r.s.ChannelMessageSendComplex(ui.QueueChannelID, &discordgo.MessageSend{
+		Content:    content,
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: comps,
+	})
+	if err != nil {
+		return err
+	}
+	return r.matches.SetDraftMessage(ctx, m.ID, msg.ChannelID, msg.ID)
+}
+
+// renderDraft: equipos hasta ahora + jugadores libres; el select sólo existe mientras haya picks
+func renderDraft(m storage.PendingMatch) (string, *discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	byID := make(map[string]storage.PendingMatchPlayer, len(m.Players))
+	for _, p := range m.Players {
+		byID[p.DiscordUserID] = p
+	}
+	line := func(p storage.PendingMatchPlayer) string {
+		l := fmt.Sprintf("<@%s> — %s", p.DiscordUserID, p.Nickname)
+		if p.Rating != nil {
+			l += fmt.Sprintf(" · %d", *p.Rating)
+		}
+		return l
+	}
+
+	var t1, t2, free strings.Builder
+	t1.WriteString("👑 " + line(byID[m.Captain1ID]) + "\n")
+	t2.WriteString("👑 " + line(byID[m.Captain2ID]) + "\n")
+	for _, pk := range m.Picks {
+		l := fmt.Sprintf("%d. %s\n", pk.PickNo+1, line(byID[pk.DiscordUserID]))
+		if pk.Team == 1 {
+			t1.WriteString(l)
+		} else {
+			t2.WriteString(l)
+		}
+	}
+	var opts []discordgo.SelectMenuOption
+	for _, p := range m.Players {
+		if p.Team != 0 {
+			continue
+		}
+		free.WriteString(line(p) + "\n")
+		desc := "sin elo"
+		if p.Rating != nil {
+			desc = fmt.Sprintf("elo %d", *p.Rating)
+		}
+		// Discord corta en 100 caracteres; por runas para no partir un nick en UTF-8 inválido
+		opts = append(opts, discordgo.SelectMenuOption{Label: truncate(p.Nickname, 100), Value: "uid:" + p.DiscordUserID, Description: desc})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🎯 Match #%d — draft de capitanes", m.ID),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Team A", Value: t1.String(), Inline: true},
+			{Name: "Team B", Value: t2.String(), Inline: true},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if free.Len() > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Disponibles", Value: free.String()})
+	}
+
+	captain, team, ok := service.DraftTurn(m)
+	if !ok {
+		embed.Title = fmt.Sprintf("✅ Match #%d — draft terminado", m.ID)
+		return "", embed, []discordgo.MessageComponent{}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Pick %d · turno del Team %s", len(m.Picks)+1, teamLetter(team))}
+	content := fmt.Sprintf("👑 <@%s> te toca elegir.", captain)
+	if m.PickDeadline != nil {
+		content += fmt.Sprintf(" Si no elegís <t:%d:R>, elijo yo por vos.", m.PickDeadline.Unix())
+	}
+	comps := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID:    fmt.Sprintf("draft_pick:%d", m.ID),
+					Placeholder: "Capitán: elegí a tu próximo jugador",
+					Options:     opts,
+				},
+			},
+		},
+	}
+	return content, embed, comps
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func teamLetter(team int) string {
+	if team == 2 {
+		return "B"
+	}
+	return "A"
+}
+
+func (r *Router) editDraftMessage(m storage.PendingMatch) {
+	if m.DraftChannel == "" || m.DraftMessage == "" {
+		return
+	}
+	content, embed, comps := renderDraft(m)
+	em := []*discordgo.MessageEmbed{embed}
+	if _, err := r.s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    m.DraftChannel,
+		ID:         m.DraftMessage,
+		Content:    &content,
+		Embeds:     &em,
+		Components: &comps,
+	}); err != nil {
+		log.Printf("[draft] edit match=%d: %v", m.ID, err)
+	}
+}
+
+func (r *Router) handleDraftPick(ctx context.Context, ic *discordgo.InteractionCreate, arg, pickedID string) {
+	matchID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ Match inválido.")
+		return
+	}
+	res, msg, err := r.matches.Pick(ctx, matchID, ic.Member.User.ID, pickedID)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ No pude registrar el pick: "+err.Error())
+		return
+	}
+	ReplyEphemeral(r.s, ic, msg)
+
+	r.editDraftMessage(res.Match)
+	if res.Done {
+		if err := r.announceMatch(ctx, res.Match); err != nil {
+			log.Printf("[draft] announce match=%d: %v", res.Match.ID, err)
+		}
+	}
+}
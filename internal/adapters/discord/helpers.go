@@ -104,6 +104,65 @@ func optInt(ic *discordgo.InteractionCreate, name string) (int, bool) {
 	return 0, false
 }
 
+// optUserID: id del usuario elegido en una opción tipo User
+func optUserID(ic *discordgo.InteractionCreate, name string) (string, bool) {
+	if ic.Type != discordgo.InteractionApplicationCommand {
+		return "", false
+	}
+	for _, o := range ic.ApplicationCommandData().Options {
+		if o.Name == name && o.Type == discordgo.ApplicationCommandOptionUser {
+			return o.Value.(string), true
+		}
+		if o.Type == discordgo.ApplicationCommandOptionSubCommand {
+			for _, so := range o.Options {
+				if so.Name == name && so.Type == discordgo.ApplicationCommandOptionUser {
+					return so.Value.(string), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func optChannelID(ic *discordgo.InteractionCreate, name string) (string, bool) {
+	if ic.Type != discordgo.InteractionApplicationCommand {
+		return "", false
+	}
+	for _, o := range ic.ApplicationCommandData().Options {
+		if o.Name == name && o.Type == discordgo.ApplicationCommandOptionChannel {
+			return o.Value.(string), true
+		}
+		if o.Type == discordgo.ApplicationCommandOptionSubCommand {
+			for _, so := range o.Options {
+				if so.Name == name && so.Type == discordgo.ApplicationCommandOptionChannel {
+					return so.Value.(string), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// optRoleID: id del rol elegido en una opción tipo Role
+func optRoleID(ic *discordgo.InteractionCreate, name string) (string, bool) {
+	if ic.Type != discordgo.InteractionApplicationCommand {
+		return "", false
+	}
+	for _, o := range ic.ApplicationCommandData().Options {
+		if o.Name == name && o.Type == discordgo.ApplicationCommandOptionRole {
+			return o.Value.(string), true
+		}
+		if o.Type == discordgo.ApplicationCommandOptionSubCommand {
+			for _, so := range o.Options {
+				if so.Name == name && so.Type == discordgo.ApplicationCommandOptionRole {
+					return so.Value.(string), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
 func subcmdName(ic *discordgo.InteractionCreate) (string, bool) {
 	if ic.Type != discordgo.InteractionApplicationCommand {
 		return "", false
@@ -116,6 +175,19 @@ func subcmdName(ic *discordgo.InteractionCreate) (string, bool) {
 	return "", false
 }
 
+// subcmdGroup: para "/cmd grupo sub ..." devuelve grupo, sub y las opciones del sub
+func subcmdGroup(ic *discordgo.InteractionCreate) (string, string, []*discordgo.ApplicationCommandInteractionDataOption, bool) {
+	if ic.Type != discordgo.InteractionApplicationCommand {
+		return "", "", nil, false
+	}
+	for _, o := range ic.ApplicationCommandData().Options {
+		if o.Type == discordgo.ApplicationCommandOptionSubCommandGroup && len(o.Options) > 0 {
+			return o.Name, o.Options[0].Name, o.Options[0].Options, true
+		}
+	}
+	return "", "", nil, false
+}
+
 func (r *Router) statusSuffix(it service.QueueItemRich, graceAFK, graceLeft time.Duration) (string, time.Duration) {
 	var nextRefresh time.Duration
 	suf := " (waiting)"
@@ -151,6 +223,8 @@ func (r *Router) statusSuffix(it service.QueueItemRich, graceAFK, graceLeft time
 		} else {
 			suf = " (afk)"
 		}
+	case "blocked":
+		suf = " (⛔ " + it.StatusReason + ")"
 	}
 	return suf, nextRefresh
 }
@@ -90,7 +90,11 @@ func (r *Router) initLevelBadges() {
 	// (b) Default estático (tus IDs)
 	maps.Copy(r.levelEmojis, faceitBadgeByLevel)
 
-	// (c) Intento de autodescubrimiento en el guild: si encuentra, pisa el default
+	// (c) Intento de autodescubrimiento en el guild home: si encuentra, pisa el default
+	// (los emojis custom del bot sirven en cualquier guild donde esté)
+	if r.guildID == "" {
+		return
+	}
 	g, _ := r.s.State.Guild(r.guildID)
 	if g == nil {
 		g, _ = r.s.Guild(r.guildID)
@@ -0,0 +1,325 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// tryPopMatch: si la cola llegó al tamaño del match lo arma, lo anuncia y repinta la UI.
+// Es seguro llamarlo de más: el pop se serializa por guild en la DB.
+func (r *Router) tryPopMatch(guildID, queue string) {
+	if r.matches == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m, err := r.matches.TryForm(ctx, guildID, queue)
+	if err != nil {
+		log.Printf("[match] pop guild=%s queue=%s: %v", guildID, queue, err)
+	}
+	if m == nil {
+		return
+	}
+	log.Printf("[match] pop guild=%s queue=%s match=%d players=%d status=%s", guildID, queue, m.ID, len(m.Players), m.Status)
+
+	switch {
+	case err != nil:
+		// los jugadores ya salieron de la cola pero no se pudieron armar los equipos:
+		// se anuncia igual, sin equipos, para que nadie quede esperando a ciegas
+		if err := r.announceMatch(ctx, *m); err != nil {
+			log.Printf("[match] announce match=%d: %v", m.ID, err)
+		}
+	case m.Status == "ready_check":
+		if err := r.postReadyCheck(ctx, *m); err != nil {
+			log.Printf("[match] ready-check match=%d: %v", m.ID, err)
+		}
+	case m.Status == "drafting":
+		if err := r.postDraft(ctx, *m); err != nil {
+			log.Printf("[match] draft match=%d: %v", m.ID, err)
+		}
+	default:
+		if err := r.announceMatch(ctx, *m); err != nil {
+			log.Printf("[match] announce match=%d: %v", m.ID, err)
+		}
+	}
+	// el pop saca a los jugadores de todas sus colas: repintar todas
+	r.refreshGuildQueues(guildID)
+}
+
+// postReadyCheck: mensaje con Aceptar/Declinar para los jugadores del match
+func (r *Router) postReadyCheck(ctx context.Context, m storage.PendingMatch) error {
+	ui, err := r.matchUI(ctx, m)
+	if err != nil {
+		return err
+	}
+	mentions := make([]string, 0, len(m.Players))
+	for _, p := range m.Players {
+		mentions = append(mentions, "<@"+p.DiscordUserID+">")
+	}
+	msg, err := r.s.ChannelMessageSendComplex(ui.QueueChannelID, &discordgo.MessageSend{
+		Content:    strings.Join(mentions, " "),
+		Embeds:     []*discordgo.MessageEmbed{renderReadyEmbed(m)},
+		Components: readyButtons(m.ID, false),
+	})
+	if err != nil {
+		return err
+	}
+	return r.matches.SetMessage(ctx, m.ID, msg.ChannelID, msg.ID)
+}
+
+func renderReadyEmbed(m storage.PendingMatch) *discordgo.MessageEmbed {
+	var b strings.Builder
+	accepted := 0
+	for _, p := range m.Players {
+		icon := "⏳"
+		switch p.ReadyState {
+		case "accepted":
+			icon = "✅"
+			accepted++
+		case "declined":
+			icon = "❌"
+		case "timeout":
+			icon = "💤"
+		}
+		fmt.Fprintf(&b, "%s <@%s> — %s\n", icon, p.DiscordUserID, p.Nickname)
+	}
+
+	title := fmt.Sprintf("⚔️ Match #%d encontrado — ¿listos?", m.ID)
+	switch m.Status {
+	case "confirmed", "drafting":
+		title = fmt.Sprintf("✅ Match #%d confirmado", m.ID)
+	case "failed":
+		title = fmt.Sprintf("❌ Match #%d cancelado", m.ID)
+	}
+	desc := b.String()
+	if m.Status == "ready_check" && m.ReadyDeadline != nil {
+		desc = fmt.Sprintf("Aceptá antes de <t:%d:R>.\n\n", m.ReadyDeadline.Unix()) + desc
+	}
+	if m.Status == "failed" {
+		desc += "\nLos que aceptaron volvieron al principio de la cola."
+	}
+	return &discordgo.MessageEmbed{
+		Title:       title,
+		Description: desc,
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d/%d aceptaron", accepted, len(m.Players))},
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+}
+
+func readyButtons(matchID int64, disabled bool) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SuccessButton,
+					Label:    "Aceptar",
+					CustomID: fmt.Sprintf("ready_accept:%d", matchID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "✅"},
+					Disabled: disabled,
+				},
+				discordgo.Button{
+					Style:    discordgo.DangerButton,
+					Label:    "Declinar",
+					CustomID: fmt.Sprintf("ready_decline:%d", matchID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "✖️"},
+					Disabled: disabled,
+				},
+			},
+		},
+	}
+}
+
+// editReadyMessage: repinta el mensaje del ready-check (sin botones si ya terminó)
+func (r *Router) editReadyMessage(m storage.PendingMatch) {
+	if m.ChannelID == "" || m.MessageID == "" {
+		return
+	}
+	em := []*discordgo.MessageEmbed{renderReadyEmbed(m)}
+	cc := readyButtons(m.ID, m.Status != "ready_check")
+	if _, err := r.s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    m.ChannelID,
+		ID:         m.MessageID,
+		Embeds:     &em,
+		Components: &cc,
+	}); err != nil {
+		log.Printf("[match] edit ready-check match=%d: %v", m.ID, err)
+	}
+}
+
+func (r *Router) handleReadyResponse(ctx context.Context, ic *discordgo.InteractionCreate, arg string, accept bool) {
+	matchID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ Match inválido.")
+		return
+	}
+	res, msg, err := r.matches.Respond(ctx, matchID, ic.Member.User.ID, accept)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ No pude registrar tu respuesta: "+err.Error())
+		return
+	}
+	ReplyEphemeral(r.s, ic, msg)
+
+	r.editReadyMessage(res.Match)
+	switch {
+	case res.Confirmed:
+		if err := r.announceMatch(ctx, res.Match); err != nil {
+			log.Printf("[match] announce match=%d: %v", res.Match.ID, err)
+		}
+	case res.Drafting:
+		if err := r.postDraft(ctx, res.Match); err != nil {
+			log.Printf("[match] draft match=%d: %v", res.Match.ID, err)
+		}
+	case res.Failed:
+		r.onReadyCheckFailed(res.Match)
+	}
+}
+
+// onReadyCheckFailed: la cola cambió (volvieron los que aceptaron), repintar y reintentar pop
+func (r *Router) onReadyCheckFailed(m storage.PendingMatch) {
+	log.Printf("[match] ready-check failed match=%d guild=%s", m.ID, m.GuildID)
+	go r.refreshQueueUI(m.GuildID, m.QueueName)
+	go r.tryPopMatch(m.GuildID, m.QueueName)
+}
+
+// Ticker para cerrar ready-checks vencidos y elegir por los capitanes que se pasaron del plazo
+// del draft (también levanta los que quedaron de antes de un reinicio)
+func (r *Router) runReadyCheckSweeper() {
+	if r.matches == nil {
+		return
+	}
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	for range t.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		failed, err := r.matches.ExpireReadyChecks(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("[match] sweeper: %v", err)
+			continue
+		}
+		for _, m := range failed {
+			r.editReadyMessage(m)
+			r.onReadyCheckFailed(m)
+		}
+		r.expireDraftPicks()
+	}
+}
+
+// expireDraftPicks: picks automáticos de los drafts vencidos; repinta y anuncia los que terminan
+func (r *Router) expireDraftPicks() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	picked, err := r.matches.ExpireDraftPicks(ctx)
+	if err != nil {
+		log.Printf("[draft] sweeper: %v", err)
+		return
+	}
+	for _, res := range picked {
+		r.editDraftMessage(res.Match)
+		if res.Done {
+			if err := r.announceMatch(ctx, res.Match); err != nil {
+				log.Printf("[draft] announce match=%d: %v", res.Match.ID, err)
+			}
+		}
+	}
+}
+
+// announceMatch: publica el match en el canal de la cola (el mismo de la UI)
+func (r *Router) announceMatch(ctx context.Context, m storage.PendingMatch) error {
+	ui, err := r.matchUI(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	mentions := make([]string, 0, len(m.Players))
+	var t1, t2 strings.Builder
+	teamed := false // sin equipos si falló el balance
+	for _, p := range m.Players {
+		mention := "<@" + p.DiscordUserID + ">"
+		mentions = append(mentions, mention)
+		line := fmt.Sprintf("[%s](%s) — %s", p.Nickname, faceitPlayerURL(p.Nickname), mention)
+		if p.PartyID != 0 {
+			line = "👥 " + line
+		}
+		if p.Rating != nil {
+			line += fmt.Sprintf(" · %d", *p.Rating)
+		}
+		if p.Team == 2 {
+			t2.WriteString(line + "\n")
+		} else {
+			t1.WriteString(line + "\n")
+		}
+		teamed = teamed || p.Team != 0
+	}
+	avg1, avg2 := service.TeamAverages(m)
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🔥 Match #%d listo", m.ID),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: fmt.Sprintf("Team A · avg %.0f", avg1), Value: orDash(t1.String()), Inline: true},
+			{Name: fmt.Sprintf("Team B · avg %.0f", avg2), Value: orDash(t2.String()), Inline: true},
+		},
+		Footer:    &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d jugadores · diferencia %.0f elo", len(m.Players), abs(avg1-avg2))},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if !teamed {
+		embed.Fields = []*discordgo.MessageEmbedField{{Name: "Jugadores", Value: orDash(t1.String())}}
+		embed.Footer.Text = fmt.Sprintf("%d jugadores · ⚠️ no pude armar los equipos: repártanse a mano", len(m.Players))
+	}
+	_, err = r.s.ChannelMessageSendComplex(ui.QueueChannelID, &discordgo.MessageSend{
+		Content: strings.Join(mentions, " "),
+		Embeds:  []*discordgo.MessageEmbed{embed},
+	})
+	if r.rooms != nil && teamed {
+		// canales de voz por equipo (las parties quedan juntas)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			if err := r.rooms.OpenForLocalMatch(ctx, m); err != nil {
+				log.Printf("[match] rooms match=%d: %v", m.ID, err)
+			}
+		}()
+	}
+	return err
+}
+
+// matchUI: canal donde se publica el match: el de su cola, o el de cualquier cola del guild
+// (la principal puede usarse sin UI publicada)
+func (r *Router) matchUI(ctx context.Context, m storage.PendingMatch) (storage.GuildUI, error) {
+	ui, err := r.uiStorage.Get(ctx, m.GuildID, m.QueueName)
+	if err != storage.ErrNotFound {
+		return ui, err
+	}
+	uis, err := r.uiStorage.ListByGuild(ctx, m.GuildID)
+	if err != nil {
+		return storage.GuildUI{}, err
+	}
+	if len(uis) == 0 {
+		return storage.GuildUI{}, storage.ErrNotFound
+	}
+	return uis[0], nil
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "—"
+	}
+	return s
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
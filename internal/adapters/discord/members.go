@@ -0,0 +1,27 @@
+package discord
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Members implementa service.MemberRoles: roles de un miembro del guild.
+// Primero el state (cache del gateway); si no está, la API.
+type Members struct {
+	s *discordgo.Session
+}
+
+func NewMembers(s *discordgo.Session) *Members { return &Members{s: s} }
+
+func (m *Members) Roles(guildID, discordUserID string) []string {
+	if mem, err := m.s.State.Member(guildID, discordUserID); err == nil && mem != nil {
+		return mem.Roles
+	}
+	mem, err := m.s.GuildMember(guildID, discordUserID)
+	if err != nil {
+		log.Printf("[members] roles guild=%s user=%s: %v", guildID, discordUserID, err)
+		return nil
+	}
+	return mem.Roles
+}
@@ -0,0 +1,94 @@
+package discord
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// Notifier implementa service.Notifier: manda DM y, si el usuario tiene los DMs cerrados,
+// lo menciona en un canal (el configurado o el de la cola) y borra el mensaje al rato.
+type Notifier struct {
+	s               *discordgo.Session
+	ui              *storage.UIRepo
+	homeGuild       string // DISCORD_GUILD_ID: el único guild donde vale fallbackChannel
+	fallbackChannel string // NOTIFY_CHANNEL_ID; vacío (u otro guild) = canal de la UI de la cola
+	limiter         *userLimiter
+	ttl             time.Duration // cuánto vive el mensaje de fallback
+}
+
+func NewNotifier(s *discordgo.Session, ui *storage.UIRepo, homeGuildID, fallbackChannelID string) *Notifier {
+	return &Notifier{
+		s:               s,
+		ui:              ui,
+		homeGuild:       homeGuildID,
+		fallbackChannel: fallbackChannelID,
+		limiter:         newUserLimiter(5 * time.Second),
+		ttl:             20 * time.Second,
+	}
+}
+
+// Notify: avisos que tienen que llegar siempre (bans, penalizaciones, enforcement)
+func (n *Notifier) Notify(guildID, discordUserID, msg string) {
+	go n.send(guildID, discordUserID, msg)
+}
+
+// NotifyThrottled: acuses de los joins, uno por usuario cada pocos segundos
+// (spamear joins no debe spamear DMs); el resto se descarta
+func (n *Notifier) NotifyThrottled(guildID, discordUserID, msg string) {
+	if !n.limiter.Allow(guildID + ":" + discordUserID) {
+		return
+	}
+	go n.send(guildID, discordUserID, msg)
+}
+
+func (n *Notifier) send(guildID, discordUserID, msg string) {
+	err := n.dm(discordUserID, msg)
+	if err == nil {
+		return
+	}
+	log.Printf("[notify] dm user=%s: %v (fallback a canal)", discordUserID, err)
+
+	chID := ""
+	if guildID == n.homeGuild {
+		chID = n.fallbackChannel // es un canal del guild home: en otro guild mencionaría a la gente afuera
+	}
+	if chID == "" && n.ui != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		uis, uerr := n.ui.ListByGuild(ctx, guildID)
+		cancel()
+		if uerr == nil && len(uis) > 0 {
+			chID = uis[0].QueueChannelID // la principal si está publicada
+		}
+	}
+	if chID == "" {
+		log.Printf("[notify] sin canal de fallback guild=%s user=%s", guildID, discordUserID)
+		return
+	}
+
+	m, err := n.s.ChannelMessageSendComplex(chID, &discordgo.MessageSend{
+		Content:         "<@" + discordUserID + "> " + msg,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{discordUserID}},
+	})
+	if err != nil {
+		log.Printf("[notify] fallback channel=%s user=%s: %v", chID, discordUserID, err)
+		return
+	}
+	// "efímero" a mano: fuera de una interacción no hay flag ephemeral
+	time.AfterFunc(n.ttl, func() {
+		_ = n.s.ChannelMessageDelete(m.ChannelID, m.ID)
+	})
+}
+
+func (n *Notifier) dm(discordUserID, msg string) error {
+	ch, err := n.s.UserChannelCreate(discordUserID)
+	if err != nil {
+		return err
+	}
+	_, err = n.s.ChannelMessageSend(ch.ID, msg)
+	return err
+}
@@ -0,0 +1,69 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// postPartyInvite: invitación pública en el canal con Aceptar/Declinar (sólo el invitado puede usarlos)
+func (r *Router) postPartyInvite(channelID string, partyID int64, leaderID, targetID string) {
+	_, err := r.s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("👥 <@%s>, <@%s> te invita a su party.", targetID, leaderID),
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{targetID}},
+		Components:      partyInviteButtons(partyID),
+	})
+	if err != nil {
+		log.Printf("[party] invite party=%d channel=%s: %v", partyID, channelID, err)
+	}
+}
+
+func partyInviteButtons(partyID int64) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Style:    discordgo.SuccessButton,
+					Label:    "Aceptar",
+					CustomID: fmt.Sprintf("party_accept:%d", partyID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "✅"},
+				},
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					Label:    "Declinar",
+					CustomID: fmt.Sprintf("party_decline:%d", partyID),
+					Emoji:    &discordgo.ComponentEmoji{Name: "✖️"},
+				},
+			},
+		},
+	}
+}
+
+func (r *Router) handlePartyResponse(ctx context.Context, ic *discordgo.InteractionCreate, arg string, accept bool) {
+	partyID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ Invitación inválida.")
+		return
+	}
+	done, msg, err := r.parties.Respond(ctx, ic.GuildID, partyID, ic.Member.User.ID, accept)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ No pude registrar tu respuesta: "+err.Error())
+		return
+	}
+	ReplyEphemeral(r.s, ic, msg)
+	if !done || ic.Message == nil {
+		return
+	}
+	// invitación resuelta: el mensaje queda como registro, sin botones
+	empty := []discordgo.MessageComponent{}
+	if _, err := r.s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		Channel:    ic.ChannelID,
+		ID:         ic.Message.ID,
+		Components: &empty,
+	}); err != nil {
+		log.Printf("[party] edit invite party=%d: %v", partyID, err)
+	}
+}
@@ -0,0 +1,61 @@
+package discord
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleQueueAdmin: /queue admin add|move|swap|clear|lock|unlock. Todo pasa por
+// QueueService, que deja el evento con el admin como actor.
+func (r *Router) handleQueueAdmin(ctx context.Context, ic *discordgo.InteractionCreate, sub string, opts []*discordgo.ApplicationCommandInteractionDataOption) {
+	var rawQueue, userID, otherID, reason string
+	var pos int
+	for _, o := range opts {
+		switch o.Name {
+		case "queue":
+			rawQueue = o.StringValue()
+		case "user":
+			userID = o.Value.(string)
+		case "other":
+			otherID = o.Value.(string)
+		case "position":
+			pos = int(o.IntValue())
+		case "reason":
+			reason = o.StringValue()
+		}
+	}
+	queue, err := r.resolveQueue(ctx, ic.GuildID, rawQueue)
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ "+err.Error())
+		return
+	}
+
+	actor := ic.Member.User.ID
+	var msg string
+	switch sub {
+	case "add":
+		msg, err = r.queue.AdminAdd(ctx, ic.GuildID, queue, userID, actor)
+	case "move":
+		msg, err = r.queue.Move(ctx, ic.GuildID, queue, userID, pos, actor)
+	case "swap":
+		msg, err = r.queue.Swap(ctx, ic.GuildID, queue, userID, otherID, actor)
+	case "clear":
+		msg, err = r.queue.Clear(ctx, ic.GuildID, queue, actor)
+	case "lock":
+		msg, err = r.queue.Lock(ctx, ic.GuildID, queue, actor, reason)
+	case "unlock":
+		msg, err = r.queue.Unlock(ctx, ic.GuildID, queue, actor)
+	default:
+		msg = "Usa `/queue admin add|move|swap|clear|lock|unlock`."
+	}
+	if err != nil {
+		ReplyEphemeral(r.s, ic, "⚠️ No pude hacerlo: "+err.Error())
+		return
+	}
+	ReplyEphemeral(r.s, ic, msg)
+	go r.refreshQueueUI(ic.GuildID, queue)
+	if sub == "add" {
+		go r.tryPopMatch(ic.GuildID, queue)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
 )
 
 // atajos de tunning (para los timers y ajustar aqui)
@@ -19,9 +21,10 @@ const (
 	ctxPruneMax  = 600 * time.Millisecond
 )
 
-// Publica o reposta la UI de la cola en ESTE canal
-func (r *Router) publishQueueUI(ctx context.Context, guildID, channelID string) error {
-	embed, comps, err := r.renderQueueEmbed(ctx, guildID)
+// Publica o reposta la UI de una cola en ESTE canal (publicarla es lo que crea la cola).
+// categoryID != "" fija la categoría de voz propia de la cola.
+func (r *Router) publishQueueUI(ctx context.Context, guildID, queue, channelID, categoryID string) error {
+	embed, comps, err := r.renderQueueEmbed(ctx, guildID, queue)
 	if err != nil {
 		return err
 	}
@@ -32,26 +35,66 @@ func (r *Router) publishQueueUI(ctx context.Context, guildID, channelID string)
 	if err != nil {
 		return err
 	}
-	return r.uiStorage.Upsert(ctx, guildID, channelID, msg.ID)
+	return r.uiStorage.Upsert(ctx, storage.GuildUI{
+		GuildID:         guildID,
+		QueueName:       queue,
+		QueueChannelID:  channelID,
+		QueueMessageID:  msg.ID,
+		VoiceCategoryID: categoryID,
+	})
+}
+
+// resolveQueue: nombre de cola de un comando/botón ("" = principal). Las que no son la
+// principal tienen que existir (o sea, tener la UI publicada con /queueui).
+func (r *Router) resolveQueue(ctx context.Context, guildID, raw string) (string, error) {
+	queue, err := service.NormalizeQueueName(raw)
+	if err != nil {
+		return "", err
+	}
+	if queue == storage.DefaultQueue {
+		return queue, nil
+	}
+	if _, err := r.uiStorage.Get(ctx, guildID, queue); err != nil {
+		if err == storage.ErrNotFound {
+			return "", fmt.Errorf("no existe la cola %q (se crea publicándola con /queueui)", queue)
+		}
+		return "", err
+	}
+	return queue, nil
+}
+
+// refreshGuildQueues: repinta todas las colas publicadas del guild
+func (r *Router) refreshGuildQueues(guildID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	uis, err := r.uiStorage.ListByGuild(ctx, guildID)
+	cancel()
+	if err != nil {
+		log.Printf("[ui.refresh] guild=%s: %v", guildID, err)
+		return
+	}
+	for _, ui := range uis {
+		r.refreshQueueUI(guildID, ui.QueueName)
+	}
 }
 
 // Debounce + re-render + edit de la UI
-func (r *Router) refreshQueueUI(guildID string) {
+func (r *Router) refreshQueueUI(guildID, queue string) {
+	key := guildID + ":" + queue
 	r.refreshMu.Lock()
-	if r.refreshTimer != nil {
-		r.refreshTimer.Stop()
+	if t := r.refreshTimers[key]; t != nil {
+		t.Stop()
 	}
-	r.refreshTimer = time.AfterFunc(uiDebounce, func() {
+	r.refreshTimers[key] = time.AfterFunc(uiDebounce, func() {
 		start := time.Now()
 
 		// 1) Render & Edit PRIMERO (rápido)
 		ctxR, cancelR := context.WithTimeout(context.Background(), ctxRenderMax)
 		tGet := time.Now()
-		ui, err := r.uiStorage.Get(ctxR, guildID)
+		ui, err := r.uiStorage.Get(ctxR, guildID, queue)
 		log.Printf("[ui.refresh] getUI dur=%s err=%v", time.Since(tGet), err)
 		if err == nil && ui.QueueChannelID != "" && ui.QueueMessageID != "" {
 			tR := time.Now()
-			embed, comps, rErr := r.renderQueueEmbed(ctxR, guildID)
+			embed, comps, rErr := r.renderQueueEmbed(ctxR, guildID, queue)
 			log.Printf("[ui.refresh] render dur=%s err=%v", time.Since(tR), err)
 			if rErr == nil {
 				em := []*discordgo.MessageEmbed{embed}
@@ -87,10 +130,10 @@ func (r *Router) refreshQueueUI(guildID string) {
 		go func() {
 			ctxP, cancelP := context.WithTimeout(context.Background(), ctxPruneMax)
 			defer cancelP()
-			pol, _ := r.policy.GetPolicy(ctxP, guildID)
+			pol, _ := r.policy.GetPolicy(ctxP, guildID, queue)
 			afk := time.Duration(pol.AFKTimeoutSeconds) * time.Second
 			left := time.Duration(pol.DropIfLeftSeconds) * time.Second
-			_, _, _ = r.queue.Prune(ctxP, guildID, afk, left)
+			_, _, _ = r.queue.Prune(ctxP, guildID, queue, afk, left)
 		}()
 
 		// tracing opcional
@@ -101,24 +144,46 @@ func (r *Router) refreshQueueUI(guildID string) {
 }
 
 // Render del embed + botones, con countdowns
-func (r *Router) renderQueueEmbed(ctx context.Context, guildID string) (*discordgo.MessageEmbed, discordgo.MessageComponent, error) {
+// gateLine: requisitos de nivel/elo de la cola con los badges de nivel ("" si no filtra)
+func (r *Router) gateLine(pol storage.GuildPolicy) string {
+	if !service.HasSkillGate(pol) {
+		return ""
+	}
+	parts := make([]string, 0, 2)
+	if pol.MinSkillLevel > 0 || pol.MaxSkillLevel > 0 {
+		lo, hi := pol.MinSkillLevel, pol.MaxSkillLevel
+		if lo == 0 {
+			lo = 1
+		}
+		if hi == 0 {
+			hi = 10
+		}
+		parts = append(parts, fmt.Sprintf("Nivel %s → %s", r.levelBadge(lo), r.levelBadge(hi)))
+	}
+	if pol.MinElo > 0 || pol.MaxElo > 0 {
+		parts = append(parts, "Elo "+service.RangeLabel(pol.MinElo, pol.MaxElo))
+	}
+	return "🎯 " + strings.Join(parts, " · ")
+}
+
+func (r *Router) renderQueueEmbed(ctx context.Context, guildID, queue string) (*discordgo.MessageEmbed, discordgo.MessageComponent, error) {
 	tPol := time.Now()
 	const groupSize = 5
 	const softGap = "\n\u200B\n" // separador vertical suave entre grupos
-	pol, _ := r.policy.GetPolicy(ctx, guildID)
+	pol, _ := r.policy.GetPolicy(ctx, guildID, queue)
 	log.Printf("[ui.render] policy dur=%s", time.Since(tPol))
 	graceAFK := time.Duration(pol.AFKTimeoutSeconds) * time.Second
 	graceLeft := time.Duration(pol.DropIfLeftSeconds) * time.Second
 
 	tList := time.Now()
-	items, err := r.queue.ListRichWithGrace(ctx, guildID, 50, graceAFK, graceLeft)
+	items, err := r.queue.ListRichWithGrace(ctx, guildID, queue, 50, graceAFK, graceLeft)
 	log.Printf("[ui.render] list dur=%s err=%v n=%d", time.Since(tList), err, len(items))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Garantiza orden estable por joined_at
-	sort.SliceStable(items, func(i, j int) bool { return items[i].JoinedAt.Before(items[j].JoinedAt) })
+	// Orden de la cola (prioridad, joined_at) con las parties juntas
+	items = service.GroupByParty(items)
 
 	lines := "Nadie en cola."
 	var nextRefresh time.Duration = 0
@@ -148,6 +213,12 @@ func (r *Router) renderQueueEmbed(ctx context.Context, guildID string) (*discord
 				badge := r.levelBadge(lvl)
 				nick := fmt.Sprintf("[%s](%s)", it.Nickname, faceitPlayerURL(it.Nickname))
 				mention := "<@" + it.DiscordUserID + ">"
+				if it.PartyID != 0 {
+					nick = "👥 " + nick
+				}
+				if it.Priority > 0 {
+					nick = "⭐ " + nick
+				}
 
 				suf, nref := r.statusSuffix(it, graceAFK, graceLeft)
 				if nref > 0 && (nextRefresh == 0 || nref < nextRefresh) {
@@ -174,11 +245,34 @@ func (r *Router) renderQueueEmbed(ctx context.Context, guildID string) (*discord
 
 	// Agenda refresh justo después del primer vencimiento
 	if nextRefresh > 0 {
-		go r.scheduleRefresh(guildID, nextRefresh+200*time.Millisecond) // colchón
+		go r.scheduleRefresh(guildID, queue, nextRefresh+200*time.Millisecond) // colchón
 	}
 
+	title := "XCG — Cola"
+	if queue != storage.DefaultQueue {
+		title += " · " + queue
+	}
+	if gate := r.gateLine(pol); gate != "" {
+		lines = gate + "\n\n" + lines
+	}
+	open := true
+	if r.schedules != nil {
+		st := r.schedules.State(ctx, guildID, queue)
+		open = st.Open
+		if line := scheduleLine(st); line != "" {
+			lines = line + "\n\n" + lines
+		}
+	}
+	if l, locked := r.queue.Locked(ctx, guildID, queue); locked {
+		open = false
+		line := "🔒 **Cerrada por un admin**"
+		if l.Reason != "" {
+			line += " — " + l.Reason
+		}
+		lines = line + "\n\n" + lines
+	}
 	embed := &discordgo.MessageEmbed{
-		Title:       "XCG — Cola",
+		Title:       title,
 		Description: lines,
 		Timestamp:   time.Now().Format(time.RFC3339),
 	}
@@ -187,19 +281,26 @@ func (r *Router) renderQueueEmbed(ctx context.Context, guildID string) (*discord
 			discordgo.Button{
 				Style:    discordgo.PrimaryButton,
 				Label:    "La llevo",
-				CustomID: "queue_join",
+				CustomID: "queue_join:" + queue,
 				Emoji:    &discordgo.ComponentEmoji{Name: "🌕"},
+				Disabled: !open,
 			},
 			discordgo.Button{
 				Style:    discordgo.SecondaryButton,
 				Label:    "Chau",
-				CustomID: "queue_leave",
+				CustomID: "queue_leave:" + queue,
 				Emoji:    &discordgo.ComponentEmoji{Name: "👋"},
 			},
+			discordgo.Button{
+				Style:    discordgo.SecondaryButton,
+				Label:    "¿Dónde estoy?",
+				CustomID: "queue_position:" + queue,
+				Emoji:    &discordgo.ComponentEmoji{Name: "🔎"},
+			},
 			discordgo.Button{
 				Style:    discordgo.SecondaryButton,
 				Label:    "Admin",
-				CustomID: "admin_panel",
+				CustomID: "admin_panel:" + queue,
 				Emoji:    &discordgo.ComponentEmoji{Name: "👮"},
 			},
 		},
@@ -208,57 +309,61 @@ func (r *Router) renderQueueEmbed(ctx context.Context, guildID string) (*discord
 	return embed, comps, nil
 }
 
-func (r *Router) scheduleRefresh(guildID string, d time.Duration) {
-	time.AfterFunc(d, func() { r.refreshQueueUI(guildID) })
+func (r *Router) scheduleRefresh(guildID, queue string, d time.Duration) {
+	time.AfterFunc(d, func() { r.refreshQueueUI(guildID, queue) })
 }
 
-// Ticker 1s para actualizar countdowns sin flood (usa tu debounce)
+// Ticker 1s para actualizar countdowns sin flood (usa tu debounce); recorre todas las colas con UI
 func (r *Router) runCountdownRefresher() {
 	t := time.NewTicker(1 * time.Second)
 	defer t.Stop()
 	for range t.C {
-		// ¿Tenemos UI publicada para este guild?
 		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-		ui, err := r.uiStorage.Get(ctx, r.guildID)
+		uis, err := r.uiStorage.ListAll(ctx)
 		cancel()
-		if err != nil || ui.QueueChannelID == "" || ui.QueueMessageID == "" {
-			continue
-		}
-
-		// leer policy
-		ctx2, cancel2 := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-		pol, err := r.policy.GetPolicy(ctx2, r.guildID)
-		cancel2()
 		if err != nil {
 			continue
 		}
-		graceAFK := time.Duration(pol.AFKTimeoutSeconds) * time.Second
-		graceLeft := time.Duration(pol.DropIfLeftSeconds) * time.Second
-		if graceAFK <= 0 && graceLeft <= 0 {
-			continue
+		for _, ui := range uis {
+			r.refreshCountdowns(ui.GuildID, ui.QueueName)
 		}
+	}
+}
 
-		// ¿Hay algún countdown activo?
-		ctx3, cancel3 := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-		items, err := r.queue.List(ctx3, r.guildID, 50)
-		cancel3()
-		if err != nil {
-			continue
-		}
-		now := time.Now()
-		hasCountdown := false
-		for _, it := range items {
-			if it.Status == "left" && graceLeft > 0 && now.Before(it.LastSeenAt.Add(graceLeft)) {
-				hasCountdown = true
-				break
-			}
-			if it.Status == "afk" && graceAFK > 0 && now.Before(it.LastSeenAt.Add(graceAFK)) {
-				hasCountdown = true
-				break
-			}
+func (r *Router) refreshCountdowns(guildID, queue string) {
+	// leer policy
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	pol, err := r.policy.GetPolicy(ctx2, guildID, queue)
+	cancel2()
+	if err != nil {
+		return
+	}
+	graceAFK := time.Duration(pol.AFKTimeoutSeconds) * time.Second
+	graceLeft := time.Duration(pol.DropIfLeftSeconds) * time.Second
+	if graceAFK <= 0 && graceLeft <= 0 {
+		return
+	}
+
+	// ¿Hay algún countdown activo? (afk/left dentro de la gracia)
+	ctx3, cancel3 := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	items, err := r.queue.ListRichWithGrace(ctx3, guildID, queue, 50, graceAFK, graceLeft)
+	cancel3()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	hasCountdown := false
+	for _, it := range items {
+		if it.Status == "left" && graceLeft > 0 && now.Before(it.LastSeenAt.Add(graceLeft)) {
+			hasCountdown = true
+			break
 		}
-		if hasCountdown {
-			r.refreshQueueUI(r.guildID) // usa el debounce (120ms)
+		if it.Status == "afk" && graceAFK > 0 && now.Before(it.LastSeenAt.Add(graceAFK)) {
+			hasCountdown = true
+			break
 		}
 	}
+	if hasCountdown {
+		r.refreshQueueUI(guildID, queue) // usa el debounce (120ms)
+	}
 }
@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
@@ -19,58 +20,141 @@ type VoiceCfg struct {
 
 type Router struct {
 	s       *discordgo.Session
-	guildID string
-
-	voice        VoiceCfg
-	link         *service.LinkService
-	queue        *service.QueueService
-	policy       *service.PolicyService
-	uiStorage    *storage.UIRepo
-	refreshMu    sync.Mutex
-	refreshTimer *time.Timer
-	adminRoleIDs []string
-	rooms        *service.MatchRoomsService
-	levelEmojis  map[int]string
-	clickLimiter *userLimiter
+	guildID string // guild "home" (opcional): registro inicial de comandos y emojis de nivel
+
+	globalCommands bool     // comandos globales en vez de por guild
+	registered     sync.Map // guildID -> struct{}: comandos ya sincronizados
+
+	settings      *service.GuildSettingsService
+	link          *service.LinkService
+	queue         *service.QueueService
+	policy        *service.PolicyService
+	uiStorage     *storage.UIRepo
+	refreshMu     sync.Mutex
+	refreshTimers map[string]*time.Timer // debounce por guild+cola
+	adminRoleIDs  []string
+	rooms         *service.MatchRoomsService
+	matches       *service.MatchService
+	parties       *service.PartyService
+	bans          *service.BanService
+	penalties     *service.PenaltyService
+	schedules     *service.ScheduleService
+	webhooks      *service.WebhookService
+	levelEmojis   map[int]string
+	clickLimiter  *userLimiter
 }
 
 func NewRouter(
 	s *discordgo.Session,
 	guildID string,
-	voice VoiceCfg,
+	globalCommands bool,
+	settings *service.GuildSettingsService,
 	link *service.LinkService,
 	queue *service.QueueService,
 	policy *service.PolicyService,
 	ui *storage.UIRepo,
 	adminRoleIDs []string,
 	rooms *service.MatchRoomsService,
+	matches *service.MatchService,
+	parties *service.PartyService,
+	bans *service.BanService,
+	penalties *service.PenaltyService,
+	schedules *service.ScheduleService,
+	webhooks *service.WebhookService,
 ) *Router {
-	return &Router{
-		s:            s,
-		guildID:      guildID,
-		voice:        voice,
-		link:         link,
-		queue:        queue,
-		policy:       policy,
-		uiStorage:    ui,
-		adminRoleIDs: adminRoleIDs,
-		rooms:        rooms,
-		clickLimiter: newUserLimiter(900 * time.Millisecond),
+	r := &Router{
+		s:              s,
+		guildID:        guildID,
+		globalCommands: globalCommands,
+		settings:       settings,
+		link:           link,
+		queue:          queue,
+		policy:         policy,
+		uiStorage:      ui,
+		refreshTimers:  map[string]*time.Timer{},
+		adminRoleIDs:   adminRoleIDs,
+		rooms:          rooms,
+		matches:        matches,
+		parties:        parties,
+		bans:           bans,
+		penalties:      penalties,
+		schedules:      schedules,
+		webhooks:       webhooks,
+		clickLimiter:   newUserLimiter(900 * time.Millisecond),
 	}
+	// la validación post-join corre en background: repinta la UI cuando toca la cola y
+	// prueba el pop cuando termina (mientras valida, el pop no toma al jugador)
+	queue.OnChange(func(guildID, queue string) {
+		go r.refreshQueueUI(guildID, queue)
+		go r.tryPopMatch(guildID, queue)
+	})
+	return r
 }
 
+// Register sincroniza los slash commands: globales, o por cada guild donde está el bot
+// (los guilds que se sumen después se registran en onGuildCreate).
 func (r *Router) Register() error {
-	appID := r.s.State.User.ID
+	defer r.initLevelBadges()
+	if r.globalCommands {
+		return r.registerCommands("")
+	}
+	if r.guildID != "" {
+		if err := r.registerCommands(r.guildID); err != nil {
+			return err
+		}
+	}
+	for _, g := range r.s.State.Guilds {
+		if err := r.registerCommands(g.ID); err != nil {
+			log.Printf("registrando comandos guild=%s: %v", g.ID, err)
+		}
+	}
+	return nil
+}
+
+// registerCommands: "" = globales. Cada guild se sincroniza una sola vez por proceso.
+func (r *Router) registerCommands(guildID string) error {
+	if _, done := r.registered.LoadOrStore(guildID, struct{}{}); done {
+		return nil
+	}
 	t0 := time.Now()
-	_, err := r.s.ApplicationCommandBulkOverwrite(appID, r.guildID, Commands)
-	if err != nil {
+	if _, err := r.s.ApplicationCommandBulkOverwrite(r.s.State.User.ID, guildID, Commands); err != nil {
+		r.registered.Delete(guildID)
 		return err
 	}
-	log.Printf("✅ comandos sincronizados (%d) in %s", len(Commands), time.Since(t0))
-	r.initLevelBadges()
+	scope := "global"
+	if guildID != "" {
+		scope = "guild " + guildID
+	}
+	log.Printf("✅ comandos sincronizados (%d) %s in %s", len(Commands), scope, time.Since(t0))
 	return nil
 }
 
+func (r *Router) onGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if r.globalCommands {
+		return
+	}
+	if err := r.registerCommands(g.ID); err != nil {
+		log.Printf("registrando comandos guild=%s: %v", g.ID, err)
+	}
+}
+
+// voiceCfg: categoría válida y canal AFK de una cola. La categoría propia de la cola
+// (guild_ui) pisa la del guild (guild_settings o defaults del .env); el AFK es del guild.
+func (r *Router) voiceCfg(guildID, queue string) VoiceCfg {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	g, err := r.settings.Get(ctx, guildID)
+	if err != nil {
+		log.Printf("[settings] guild=%s: %v", guildID, err)
+		return VoiceCfg{}
+	}
+	cfg := VoiceCfg{AllowedCategoryID: g.VoiceCategoryID, AFKChannelID: g.AFKChannelID}
+	if ui, err := r.uiStorage.Get(ctx, guildID, queue); err == nil && ui.VoiceCategoryID != "" {
+		cfg.AllowedCategoryID = ui.VoiceCategoryID
+	}
+	return cfg
+}
+
 func (r *Router) Handlers() {
 	// Interactions
 	r.s.AddHandler(func(s *discordgo.Session, ic *discordgo.InteractionCreate) {
@@ -87,6 +171,15 @@ func (r *Router) Handlers() {
 	// Voice events
 	r.s.AddHandler(r.onVoiceStateUpdate) // ↙️ helper en voice_helpers.go
 
+	// guilds nuevos (o que vuelven a estar disponibles): registrar comandos
+	r.s.AddHandler(r.onGuildCreate)
+
 	// refresher de cuenta regresiva
 	go r.runCountdownRefresher() // ↙️ en queue_ui.go
+
+	// vencimiento de ready-checks
+	go r.runReadyCheckSweeper() // ↙️ en match_ui.go
+
+	// apertura/cierre de colas por horario
+	go r.runScheduleTicker() // ↙️ en schedule_ui.go
 }
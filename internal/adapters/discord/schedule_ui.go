@@ -0,0 +1,68 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/app/service"
+)
+
+// scheduleLine: estado del horario para el embed ("" si la cola no tiene horarios)
+func scheduleLine(st service.ScheduleState) string {
+	switch {
+	case !st.Scheduled:
+		return ""
+	case !st.Open && !st.Next.IsZero():
+		return fmt.Sprintf("🔒 **Cerrada** — abre <t:%d:F> (<t:%d:R>)", st.Next.Unix(), st.Next.Unix())
+	case !st.Open:
+		return "🔒 **Cerrada**"
+	case !st.Next.IsZero():
+		return fmt.Sprintf("🕒 Abierta hasta <t:%d:t>", st.Next.Unix())
+	}
+	return ""
+}
+
+// runScheduleTicker: repinta las colas cuando abren o cierran por horario y, si la policy
+// lo pide, vacía las que quedaron cerradas (en cada vuelta: si el bot estuvo caído al cierre
+// se vacía igual, y como el join está cerrado no vuelve a entrar nadie).
+func (r *Router) runScheduleTicker() {
+	if r.schedules == nil {
+		return
+	}
+	last := map[string]bool{} // guild:cola -> abierta en la vuelta anterior
+	t := time.NewTicker(30 * time.Second)
+	defer t.Stop()
+	for range t.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		uis, err := r.uiStorage.ListAll(ctx)
+		if err != nil {
+			cancel()
+			log.Printf("[schedule] list ui: %v", err)
+			continue
+		}
+		for _, ui := range uis {
+			flushed, st, err := r.schedules.FlushIfClosed(ctx, ui.GuildID, ui.QueueName)
+			if err != nil {
+				log.Printf("[schedule] flush guild=%s queue=%s: %v", ui.GuildID, ui.QueueName, err)
+			}
+			key := ui.GuildID + ":" + ui.QueueName
+			prev, seen := last[key]
+			last[key] = st.Open
+			if len(flushed) > 0 {
+				msg := "🔒 La cola cerró por horario y se vació."
+				if !st.Next.IsZero() {
+					msg += fmt.Sprintf(" Abre <t:%d:R>.", st.Next.Unix())
+				}
+				if _, err := r.s.ChannelMessageSend(ui.QueueChannelID, msg); err != nil {
+					log.Printf("[schedule] notice guild=%s queue=%s: %v", ui.GuildID, ui.QueueName, err)
+				}
+			}
+			if len(flushed) > 0 || (seen && prev != st.Open) {
+				go r.refreshQueueUI(ui.GuildID, ui.QueueName)
+			}
+		}
+		cancel()
+	}
+}
@@ -2,6 +2,8 @@ package discord
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -18,54 +20,84 @@ func (r *Router) safeGetChannel(id string) (*discordgo.Channel, error) {
 	return ch, nil
 }
 
-func (r *Router) userInAllowedVoice(guildID, userID string) (bool, string) {
+func (r *Router) userInAllowedVoice(guildID, queue, userID string) (bool, string) {
 	vs, err := r.s.State.VoiceState(guildID, userID)
 	if err != nil || vs == nil {
 		return false, "No estás en voz."
 	}
-	if r.voice.AFKChannelID != "" && vs.ChannelID == r.voice.AFKChannelID {
+	voice := r.voiceCfg(guildID, queue)
+	if voice.AFKChannelID != "" && vs.ChannelID == voice.AFKChannelID {
 		return false, "Estás en **AFK**."
 	}
 	ch, err := r.safeGetChannel(vs.ChannelID)
 	if err != nil {
 		return false, "No pude leer tu canal de voz."
 	}
-	if r.voice.AllowedCategoryID != "" && ch.ParentID != r.voice.AllowedCategoryID {
+	if voice.AllowedCategoryID != "" && ch.ParentID != voice.AllowedCategoryID {
 		return false, "Estás en una categoría de voz **no permitida**."
 	}
 	return true, ""
 }
 
+// partyInAllowedVoice: como userInAllowedVoice, pero para toda la party del usuario
+// (la party entra entera a la cola, así que tienen que estar todos en voz)
+func (r *Router) partyInAllowedVoice(ctx context.Context, guildID, queue, userID string) (bool, string) {
+	members := []string{userID}
+	if r.parties != nil {
+		members = r.parties.Members(ctx, guildID, userID)
+	}
+	for _, id := range members {
+		ok, why := r.userInAllowedVoice(guildID, queue, id)
+		if ok {
+			continue
+		}
+		if id != userID {
+			why = fmt.Sprintf("<@%s> (de tu party): %s", id, why)
+		}
+		return false, why
+	}
+	return true, ""
+}
+
+// onVoiceStateUpdate: re-evalúa cada cola en la que está el usuario (cada una puede
+// tener su propia categoría de voz, así que el mismo canal vale para una y no para otra)
 func (r *Router) onVoiceStateUpdate(s *discordgo.Session, vs *discordgo.VoiceStateUpdate) {
-	if vs.GuildID != r.guildID {
+	if vs.GuildID == "" {
 		return
 	}
 	uid := vs.UserID
 
-	// Sin canal -> left
-	if vs.ChannelID == "" {
-		_ = r.queue.MarkLeft(context.Background(), vs.GuildID, uid)
-		go r.refreshQueueUI(vs.GuildID)
-		return
-	}
-	// AFK explícito
-	if r.voice.AFKChannelID != "" && vs.ChannelID == r.voice.AFKChannelID {
-		_ = r.queue.MarkAFK(context.Background(), vs.GuildID, uid)
-		go r.refreshQueueUI(vs.GuildID)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	entries, err := r.queue.QueuesOf(ctx, vs.GuildID, uid)
+	if err != nil || len(entries) == 0 {
 		return
 	}
 
-	// ¿Está en una categoría permitida?
-	ch, err := r.safeGetChannel(vs.ChannelID)
-	if err != nil {
-		return
+	var ch *discordgo.Channel
+	if vs.ChannelID != "" {
+		if ch, err = r.safeGetChannel(vs.ChannelID); err != nil {
+			return
+		}
 	}
-	if r.voice.AllowedCategoryID != "" && ch.ParentID != r.voice.AllowedCategoryID {
-		_ = r.queue.MarkLeft(context.Background(), vs.GuildID, uid)
-		go r.refreshQueueUI(vs.GuildID)
-		return
+
+	for _, e := range entries {
+		queue := e.QueueName
+		voice := r.voiceCfg(vs.GuildID, queue)
+		switch {
+		case ch == nil:
+			// Sin canal -> left
+			_ = r.queue.MarkLeft(ctx, vs.GuildID, queue, uid, "salió de voz")
+		case voice.AFKChannelID != "" && vs.ChannelID == voice.AFKChannelID:
+			// AFK explícito
+			_ = r.queue.MarkAFK(ctx, vs.GuildID, queue, uid, "canal AFK")
+		case voice.AllowedCategoryID != "" && ch.ParentID != voice.AllowedCategoryID:
+			_ = r.queue.MarkLeft(ctx, vs.GuildID, queue, uid, "categoría de voz no permitida")
+		default:
+			// OK válido → refresca last_seen (si volvió de afk/left puede completar un match)
+			_ = r.queue.TouchValid(ctx, vs.GuildID, queue, uid)
+			go r.tryPopMatch(vs.GuildID, queue)
+		}
+		go r.refreshQueueUI(vs.GuildID, queue)
 	}
-	// OK válido → refresca last_seen
-	_ = r.queue.TouchValid(context.Background(), vs.GuildID, uid)
-	go r.refreshQueueUI(vs.GuildID)
 }
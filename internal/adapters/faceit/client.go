@@ -39,6 +39,15 @@ func (c *Client) IsMemberOfHub(ctx context.Context, playerID, hubID string) (boo
 	}
 }
 
+// GetHub: datos básicos del hub (sirve para validar un hub_id antes de guardarlo)
+func (c *Client) GetHub(ctx context.Context, hubID string) (*domain.Hub, error) {
+	var dto hubDTO
+	if err := c.doJSON(ctx, "GET", fmt.Sprintf("/hubs/%s", url.PathEscape(hubID)), nil, &dto); err != nil {
+		return nil, err
+	}
+	return &domain.Hub{ID: dto.HubID, Name: dto.Name, GameID: dto.GameID}, nil
+}
+
 // func (c *Client) PlayerHasHub(ctx context.Context, playerID, hubID string) (bool, error) {
 // 	q := url.Values{}
 // 	q.Set("limit", "100") // más que suficiente para la mayoría de cuentas
@@ -64,12 +73,41 @@ func (c *Client) GetPlayerByNickname(ctx context.Context, nick, game string) (*d
 	if err := c.doJSON(ctx, "GET", "/players", q, &dto); err != nil {
 		return nil, err
 	}
+	return dto.toPlayer(game), nil
+}
+
+// GetPlayer: igual que por nickname pero por player_id (no cambia si el jugador se renombra)
+func (c *Client) GetPlayer(ctx context.Context, playerID, game string) (*domain.Player, error) {
+	var dto playerDTO
+	if err := c.doJSON(ctx, "GET", fmt.Sprintf("/players/%s", playerID), nil, &dto); err != nil {
+		return nil, err
+	}
+	return dto.toPlayer(game), nil
+}
+
+func (dto playerDTO) toPlayer(game string) *domain.Player {
 	g := dto.Games[game]
-	return &domain.Player{ID: dto.PlayerID, Nickname: dto.Nickname, Elo: g.FaceitElo, Skill: g.SkillLevel}, nil
+	return &domain.Player{
+		ID:             dto.PlayerID,
+		Nickname:       dto.Nickname,
+		Elo:            g.FaceitElo,
+		Skill:          g.SkillLevel,
+		SteamNickname:  dto.SteamNickname,
+		GamePlayerName: g.GamePlayerName,
+	}
 }
 
 // Ejemplos de métodos que vas a necesitar pronto:
 
+// GetMatchHubID: hub al que pertenece un match (para saber a qué guild rutearlo)
+func (c *Client) GetMatchHubID(ctx context.Context, matchID string) (string, error) {
+	m, err := c.GetMatch(ctx, matchID)
+	if err != nil {
+		return "", err
+	}
+	return m.CompetitionID, nil
+}
+
 func (c *Client) GetMatch(ctx context.Context, matchID string) (*matchDTO, error) {
 	var dto matchDTO
 	err := c.doJSON(ctx, "GET", fmt.Sprintf("/matches/%s", matchID), nil, &dto)
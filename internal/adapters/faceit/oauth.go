@@ -0,0 +1,137 @@
+package faceit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain"
+)
+
+const (
+	defaultAuthorizeURL = "https://accounts.faceit.com"
+	defaultTokenURL     = "https://api.faceit.com/auth/v1/oauth/token"
+	defaultUserInfoURL  = "https://api.faceit.com/auth/v1/resources/userinfo"
+)
+
+// OAuthClient: "Sign in with FACEIT" (authorization code + PKCE).
+// Sólo lo usamos para probar que el usuario es dueño de la cuenta que vincula.
+type OAuthClient struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	http         *http.Client
+
+	authorizeURL string
+	tokenURL     string
+	userInfoURL  string
+}
+
+type OAuthOption func(*OAuthClient)
+
+func WithOAuthHTTPClient(h *http.Client) OAuthOption {
+	return func(o *OAuthClient) { o.http = h }
+}
+func WithOAuthEndpoints(authorize, token, userInfo string) OAuthOption {
+	return func(o *OAuthClient) {
+		o.authorizeURL, o.tokenURL, o.userInfoURL = authorize, token, userInfo
+	}
+}
+
+func NewOAuth(clientID, clientSecret, redirectURL string, opts ...OAuthOption) *OAuthClient {
+	o := &OAuthClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		http:         &http.Client{Timeout: 10 * time.Second},
+		authorizeURL: defaultAuthorizeURL,
+		tokenURL:     defaultTokenURL,
+		userInfoURL:  defaultUserInfoURL,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// AuthCodeURL: URL a la que mandamos al usuario para loguearse en FACEIT
+func (o *OAuthClient) AuthCodeURL(state, codeChallenge string) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", o.clientID)
+	q.Set("redirect_uri", o.redirectURL)
+	q.Set("scope", "openid profile")
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	q.Set("redirect_popup", "true")
+	return o.authorizeURL + "?" + q.Encode()
+}
+
+type tokenDTO struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Exchange: cambia el code del callback por un access token
+func (o *OAuthClient) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	req.SetBasicAuth(o.clientID, o.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var dto tokenDTO
+	if err := o.do(req, &dto); err != nil {
+		return "", err
+	}
+	if dto.AccessToken == "" {
+		return "", fmt.Errorf("faceit oauth: respuesta sin access_token")
+	}
+	return dto.AccessToken, nil
+}
+
+type userInfoDTO struct {
+	GUID     string `json:"guid"`
+	Nickname string `json:"nickname"`
+}
+
+// UserInfo: cuenta FACEIT dueña del token (sólo ID y nickname; elo/nivel van por la Data API)
+func (o *OAuthClient) UserInfo(ctx context.Context, accessToken string) (*domain.Player, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, o.userInfoURL, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var dto userInfoDTO
+	if err := o.do(req, &dto); err != nil {
+		return nil, err
+	}
+	if dto.GUID == "" {
+		return nil, fmt.Errorf("faceit oauth: userinfo sin guid")
+	}
+	return &domain.Player{ID: dto.GUID, Nickname: dto.Nickname}, nil
+}
+
+func (o *OAuthClient) do(req *http.Request, out any) error {
+	res, err := o.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("faceit oauth: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+		return &APIError{Status: res.StatusCode, Body: strings.TrimSpace(string(b))}
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -2,11 +2,13 @@ package faceit
 
 // --- Players ---
 type playerDTO struct {
-	PlayerID string `json:"player_id"`
-	Nickname string `json:"nickname"`
-	Games    map[string]struct {
-		FaceitElo  int `json:"faceit_elo"`
-		SkillLevel int `json:"skill_level"`
+	PlayerID      string `json:"player_id"`
+	Nickname      string `json:"nickname"`
+	SteamNickname string `json:"steam_nickname"`
+	Games         map[string]struct {
+		FaceitElo      int    `json:"faceit_elo"`
+		SkillLevel     int    `json:"skill_level"`
+		GamePlayerName string `json:"game_player_name"`
 	} `json:"games"`
 }
 
@@ -49,10 +51,18 @@ type hubMembersDTO struct {
 	} `json:"items"`
 }
 
+type hubDTO struct {
+	HubID  string `json:"hub_id"`
+	Name   string `json:"name"`
+	GameID string `json:"game_id"`
+}
+
 // --- Matches (detalle) ---
 type matchDTO struct {
-	MatchID string   `json:"match_id"`
-	DemoURL []string `json:"demo_url"`
+	MatchID         string   `json:"match_id"`
+	DemoURL         []string `json:"demo_url"`
+	CompetitionID   string   `json:"competition_id"`   // hub_id cuando es partida de hub
+	CompetitionType string   `json:"competition_type"` // "hub", "championship", ...
 	// agrega campos según necesites
 }
 
@@ -2,102 +2,189 @@ package httpfaceit
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
-	"strings"
+	"time"
 
-	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/faceitevent"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/webhookauth"
 )
 
 type Server struct {
 	secret       string
-	users        *storage.UserRepo
+	onMember     func(ctx context.Context, m faceitevent.MemberEvent) error
 	mux          *http.ServeMux
 	onMatchEvent func(ctx context.Context, matchID, status string)
+	onOAuth      OAuthCallback
+	verifier     *webhookauth.Verifier // nil = sólo el header X-FACEIT-WH con `secret`
+	replays      Replays               // nil = sin protección contra replays (sólo la ventana)
 }
 
-func New(secret string, users *storage.UserRepo, onMatch func(ctx context.Context, matchID, status string)) *Server {
-	s := &Server{secret: secret, users: users, mux: http.NewServeMux(), onMatchEvent: onMatch}
+// Replays: claves de entregas ya aceptadas. Claim devuelve true la primera vez; Release la
+// suelta si al final no pudimos procesar la entrega (así el reintento de FACEIT entra).
+// Implementado por internal/infra/storage.WebhookDedupRepo (tabla webhook_dedup).
+type Replays interface {
+	Claim(ctx context.Context, key string) (bool, error)
+	Release(ctx context.Context, key string) error
+}
+
+// OAuthCallback: recibe state y code del redirect de FACEIT y devuelve el mensaje para el usuario
+type OAuthCallback func(ctx context.Context, state, code string) (string, error)
+
+type Option func(*Server)
+
+// WithVerifier: autenticación de webhooks con firma/rotación de secretos (reemplaza al header
+// fijo); replays puede ser nil
+func WithVerifier(v *webhookauth.Verifier, replays Replays) Option {
+	return func(s *Server) { s.verifier, s.replays = v, replays }
+}
+
+// WithOAuthCallback monta GET /faceit/oauth/callback
+func WithOAuthCallback(fn OAuthCallback) Option {
+	return func(s *Server) { s.onOAuth = fn }
+}
+
+// New: onMember recibe las altas/bajas de hub (LinkService.HandleMemberEvent); puede ser nil
+func New(secret string, onMember func(ctx context.Context, m faceitevent.MemberEvent) error, onMatch func(ctx context.Context, matchID, status string), opts ...Option) *Server {
+	s := &Server{secret: secret, onMember: onMember, mux: http.NewServeMux(), onMatchEvent: onMatch}
+	for _, o := range opts {
+		o(s)
+	}
 	s.routes()
 	return s
 }
 
-func NewCompat(secret string, users *storage.UserRepo) *Server {
-	return New(secret, users, nil)
+func NewCompat(secret string, onMember func(ctx context.Context, m faceitevent.MemberEvent) error) *Server {
+	return New(secret, onMember, nil)
 }
 
 func (s *Server) routes() {
 	s.mux.HandleFunc("/faceit/webhook", s.handleWebhook)
+	if s.onOAuth != nil {
+		s.mux.HandleFunc("/faceit/oauth/callback", s.handleOAuthCallback)
+	}
 }
 
-func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+func (s *Server) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if r.Header.Get("X-FACEIT-WH") != s.secret {
-		http.Error(w, "forbidden", http.StatusForbidden)
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		log.Printf("oauth: faceit devolvió error=%s desc=%s", e, q.Get("error_description"))
+		writeOAuthPage(w, http.StatusBadRequest, "No se completó el inicio de sesión en FACEIT. Volvé a Discord y probá de nuevo con /link.")
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	msg, err := s.onOAuth(ctx, q.Get("state"), q.Get("code"))
+	if err != nil {
+		log.Printf("oauth: callback: %v", err)
+		writeOAuthPage(w, http.StatusBadRequest, "El link de vinculación venció o no es válido. Pedí uno nuevo con /link en Discord.")
+		return
+	}
+	writeOAuthPage(w, http.StatusOK, msg)
+}
+
+func writeOAuthPage(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!doctype html><html><head><meta charset="utf-8"><title>XCG · FACEIT</title></head>
+<body style="font-family:sans-serif;max-width:32rem;margin:4rem auto"><h2>XCG · FACEIT</h2><p>%s</p><p>Ya podés cerrar esta pestaña.</p></body></html>`,
+		html.EscapeString(msg))
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 	body, _ := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
 	_ = r.Body.Close()
 
-	var evt map[string]any
-	_ = json.Unmarshal(body, &evt)
-	t, _ := evt["event"].(string)
+	var replayKey string
+	if s.verifier != nil {
+		key, err := s.verifier.Verify(webhookauth.Request{
+			Signature: r.Header.Get(webhookauth.SignatureHeader),
+			Timestamp: r.Header.Get(webhookauth.TimestampHeader),
+			Static:    r.Header.Get("X-FACEIT-WH"),
+			Body:      body,
+		})
+		if err != nil {
+			log.Printf("webhook: rechazado: %v", err)
+			http.Error(w, err.Error(), webhookauth.Status(err))
+			return
+		}
+		replayKey = key
+	} else if r.Header.Get("X-FACEIT-WH") != s.secret {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
 
-	// payload puede traer player/match
-	var payload map[string]any
-	if p, ok := evt["payload"].(map[string]any); ok {
-		payload = p
+	ev, err := faceitevent.Decode(body)
+	if err != nil {
+		log.Printf("webhook: payload inválido: %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
 	}
 
-	playerID := ""
-	if payload != nil {
-		if s, ok := payload["user_id"].(string); ok {
-			playerID = s
+	// la clave de replay se anota recién con la entrega autenticada y válida
+	if replayKey != "" && s.replays != nil {
+		first, err := s.replays.Claim(r.Context(), replayKey)
+		if err != nil {
+			log.Printf("webhook: dedup: %v", err)
+			http.Error(w, "error", http.StatusInternalServerError)
+			return
 		}
-		if s, ok := payload["player_id"].(string); ok {
-			playerID = s
+		if !first {
+			log.Printf("webhook: entrega repetida %s", ev.Type)
+			writeJSON(w, `{"ok":true,"duplicate":true}`)
+			return
 		}
 	}
 
-	switch strings.ToLower(t) {
-	case "hub_user_added":
-		if playerID != "" {
-			_ = s.users.UpdateMembershipByFaceitID(r.Context(), playerID, true)
+	if err := s.handle(r.Context(), ev); err != nil {
+		log.Printf("webhook: %s: %v", ev.Type, err)
+		// sin la clave, el reintento de FACEIT no se toma como replay
+		if replayKey != "" && s.replays != nil {
+			if rerr := s.replays.Release(context.Background(), replayKey); rerr != nil {
+				log.Printf("webhook: soltar dedup: %v", rerr)
+			}
 		}
-		log.Printf("webhook: member_added player=%s", playerID)
+		http.Error(w, "error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, `{"ok":true}`)
+}
 
-	case "hub_user_removed":
-		if playerID != "" {
-			_ = s.users.UpdateMembershipByFaceitID(r.Context(), playerID, false)
+// handle: error sólo si vale la pena que FACEIT reintente
+func (s *Server) handle(ctx context.Context, ev faceitevent.Event) error {
+	switch {
+	case ev.Member != nil:
+		log.Printf("webhook: %s player=%s", ev.Type, ev.Member.UserID)
+		if s.onMember != nil && ev.Member.UserID != "" {
+			return s.onMember(ctx, *ev.Member)
 		}
-		log.Printf("webhook: member_removed player=%s", playerID)
-	}
 
 	// ——— Match status (opcional) ———
-	if s.onMatchEvent != nil && strings.HasPrefix(strings.ToLower(t), "match_status_") {
-		matchID := ""
-		if payload != nil {
-			if mid, ok := payload["match_id"].(string); ok {
-				matchID = mid
-			}
-		}
-		if matchID != "" {
-			status := strings.TrimPrefix(strings.ToLower(t), "match_status_")
-			if st, ok := payload["status"].(string); ok && st != "" {
-				status = st
-			}
-			go s.onMatchEvent(r.Context(), matchID, status)
-			log.Printf("webhook: match %s status=%s", matchID, status)
+	case ev.Match != nil && ev.Type != faceitevent.MatchDemoReady:
+		if s.onMatchEvent != nil && ev.Match.MatchID != "" {
+			go s.onMatchEvent(context.Background(), ev.Match.MatchID, ev.Match.Status)
+			log.Printf("webhook: match %s status=%s", ev.Match.MatchID, ev.Match.Status)
 		}
 	}
+	return nil
+}
 
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, body)
 }
 
 func (s *Server) Start(addr string) {
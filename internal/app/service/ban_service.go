@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// banTiers: duración de cada escalón cuando el admin no pone una. Pasado el último, permanente.
+var banTiers = []time.Duration{30 * time.Minute, 2 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+// banTierWindow: los bans más viejos que esto no cuentan para la escalada
+const banTierWindow = 30 * 24 * time.Hour
+
+// BanService: bans de cola que ponen los admins. A diferencia de un kick, el jugador
+// no puede volver a entrar hasta que vence (lo chequea QueueService.Join).
+type BanService struct {
+	bans     BanRepo
+	queue    QueueRepo
+	notifier Notifier
+}
+
+func NewBanService(bans BanRepo, queue QueueRepo, notifier Notifier) *BanService {
+	return &BanService{bans: bans, queue: queue, notifier: notifier}
+}
+
+// Add: banea y saca al jugador de todas sus colas (devuelve cuáles, para repintar).
+// d == 0 usa la escalada: cada ban reciente suma un escalón.
+func (s *BanService) Add(ctx context.Context, guildID, discordID, issuerID, reason string, d time.Duration) ([]string, string, error) {
+	if d < 0 {
+		return nil, "", fmt.Errorf("la duración no puede ser negativa")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "sin motivo"
+	}
+	prev, err := s.bans.CountSince(ctx, guildID, discordID, time.Now().Add(-banTierWindow))
+	if err != nil {
+		return nil, "", err
+	}
+	b := storage.QueueBan{GuildID: guildID, DiscordUserID: discordID, Reason: reason, IssuedBy: issuerID, Tier: prev + 1}
+	if d == 0 && b.Tier <= len(banTiers) {
+		d = banTiers[b.Tier-1]
+	}
+	if d > 0 {
+		exp := time.Now().Add(d)
+		b.ExpiresAt = &exp
+	}
+	if b, err = s.bans.Add(ctx, b); err != nil {
+		return nil, "", err
+	}
+
+	entries, err := s.queue.QueuesOf(ctx, guildID, discordID)
+	if err != nil {
+		return nil, "", err
+	}
+	queues := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if ok, err := s.queue.Kick(ctx, guildID, e.QueueName, discordID, issuerID, "ban: "+reason); err == nil && ok {
+			queues = append(queues, e.QueueName)
+		}
+	}
+
+	if s.notifier != nil {
+		s.notifier.Notify(guildID, discordID, "⛔ Te banearon de las colas ("+reason+"). "+banExpiry(b)+".")
+	}
+	msg := fmt.Sprintf("🔨 <@%s> baneado de las colas (escalón %d). %s.", discordID, b.Tier, banExpiry(b))
+	if len(queues) > 0 {
+		msg += fmt.Sprintf(" Lo saqué de %d cola(s).", len(queues))
+	}
+	return queues, msg, nil
+}
+
+func (s *BanService) Remove(ctx context.Context, guildID, discordID, actorID string) (string, error) {
+	n, err := s.bans.Revoke(ctx, guildID, discordID, actorID)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return fmt.Sprintf("ℹ️ <@%s> no tiene bans activos.", discordID), nil
+	}
+	if s.notifier != nil {
+		s.notifier.Notify(guildID, discordID, "✅ Te levantaron el ban de las colas.")
+	}
+	return fmt.Sprintf("✅ Ban levantado para <@%s>.", discordID), nil
+}
+
+func (s *BanService) List(ctx context.Context, guildID string) (string, error) {
+	bans, err := s.bans.ListActive(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	if len(bans) == 0 {
+		return "ℹ️ No hay bans activos.", nil
+	}
+	var b strings.Builder
+	b.WriteString("🔨 **Bans activos**\n")
+	for _, ban := range bans {
+		fmt.Fprintf(&b, "• <@%s> — %s · escalón %d · por <@%s> · %s\n", ban.DiscordUserID, ban.Reason, ban.Tier, ban.IssuedBy, banExpiry(ban))
+	}
+	return b.String(), nil
+}
+
+// banExpiry: "vence <t:…:R>" o "permanente"
+func banExpiry(b storage.QueueBan) string {
+	if b.ExpiresAt == nil {
+		return "Permanente"
+	}
+	return fmt.Sprintf("Vence <t:%d:R>", b.ExpiresAt.Unix())
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// GuildSettingsService: config por guild (hubs FACEIT, categoría de voz, canal AFK).
+// El guild home (DISCORD_GUILD_ID) sin fila usa los defaults del .env, así un deploy de un
+// solo guild sigue igual; los demás arrancan vacíos (cualquier categoría, sin hub).
+type GuildSettingsService struct {
+	repo         GuildSettingsRepo
+	fc           HubLookup
+	defaults     storage.GuildSettings
+	defaultHubID string // FACEIT_HUB_ID: para guilds que todavía no cargaron hubs propios
+}
+
+func NewGuildSettingsService(repo GuildSettingsRepo, fc HubLookup, defaults storage.GuildSettings, defaultHubID string) *GuildSettingsService {
+	return &GuildSettingsService{repo: repo, fc: fc, defaults: defaults, defaultHubID: defaultHubID}
+}
+
+func (s *GuildSettingsService) Get(ctx context.Context, guildID string) (storage.GuildSettings, error) {
+	g, err := s.repo.Get(ctx, guildID)
+	if err == storage.ErrNotFound {
+		// la categoría/AFK del .env son canales del home: en otro guild dejarían a todos afuera
+		d := storage.GuildSettings{GuildID: guildID}
+		if s.isHome(guildID) {
+			d = s.defaults
+		}
+		if d.MaxQueues <= 0 {
+			d.MaxQueues = 1
+		}
+		if d.MaxPartySize <= 0 {
+			d.MaxPartySize = defaultPartySize
+		}
+		return d, nil
+	}
+	return g, err
+}
+
+// isHome: el guild del .env (DISCORD_GUILD_ID), el único que hereda sus defaults
+func (s *GuildSettingsService) isHome(guildID string) bool {
+	return guildID != "" && guildID == s.defaults.GuildID
+}
+
+// defaultPartySize: dúo
+const defaultPartySize = 2
+
+// MaxPartySize: cuántos jugadores puede tener una party (líder incluido)
+func (s *GuildSettingsService) MaxPartySize(ctx context.Context, guildID string) int {
+	g, err := s.Get(ctx, guildID)
+	if err != nil || g.MaxPartySize <= 0 {
+		return defaultPartySize
+	}
+	return g.MaxPartySize
+}
+
+func (s *GuildSettingsService) SetMaxPartySize(ctx context.Context, guildID string, n int) (string, error) {
+	if n < 1 || n > 5 {
+		return "", fmt.Errorf("max_size debe estar entre 1 y 5 (recibí %d; 1 = sin parties)", n)
+	}
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	g.MaxPartySize = n
+	if err := s.repo.Upsert(ctx, g); err != nil {
+		return "", err
+	}
+	return s.Show(ctx, guildID)
+}
+
+// MaxQueues: en cuántas colas puede estar un jugador a la vez (mínimo 1)
+func (s *GuildSettingsService) MaxQueues(ctx context.Context, guildID string) int {
+	g, err := s.Get(ctx, guildID)
+	if err != nil || g.MaxQueues <= 0 {
+		return 1
+	}
+	return g.MaxQueues
+}
+
+func (s *GuildSettingsService) SetMaxQueues(ctx context.Context, guildID string, n int) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("max_per_player debe ser >= 1 (recibí %d)", n)
+	}
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	g.MaxQueues = n
+	if err := s.repo.Upsert(ctx, g); err != nil {
+		return "", err
+	}
+	return s.Show(ctx, guildID)
+}
+
+// Priority: peso en la cola para esos roles (el más alto de los que tenga; 0 = ninguno)
+func (s *GuildSettingsService) Priority(ctx context.Context, guildID string, roles []string) int {
+	if len(roles) == 0 {
+		return 0
+	}
+	prs, err := s.repo.ListPriorityRoles(ctx, guildID)
+	if err != nil {
+		return 0
+	}
+	has := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		has[r] = true
+	}
+	best := 0
+	for _, pr := range prs {
+		if has[pr.RoleID] && pr.Weight > best {
+			best = pr.Weight
+		}
+	}
+	return best
+}
+
+func (s *GuildSettingsService) SetPriorityRole(ctx context.Context, guildID, roleID string, weight int) (string, error) {
+	if weight < 1 || weight > 100 {
+		return "", fmt.Errorf("weight debe estar entre 1 y 100 (recibí %d)", weight)
+	}
+	if err := s.repo.SetPriorityRole(ctx, storage.PriorityRole{GuildID: guildID, RoleID: roleID, Weight: weight}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("✅ <@&%s> tiene prioridad **%d** en las colas (aplica a los que entren desde ahora).", roleID, weight), nil
+}
+
+func (s *GuildSettingsService) RemovePriorityRole(ctx context.Context, guildID, roleID string) (string, error) {
+	ok, err := s.repo.RemovePriorityRole(ctx, guildID, roleID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "ℹ️ Ese rol no tenía prioridad.", nil
+	}
+	return fmt.Sprintf("✅ <@&%s> ya no tiene prioridad.", roleID), nil
+}
+
+// SetPriorityAging: cada `minutes` esperando suman +1 de prioridad (0 = orden estricto)
+func (s *GuildSettingsService) SetPriorityAging(ctx context.Context, guildID string, minutes int) (string, error) {
+	if minutes < 0 {
+		return "", fmt.Errorf("aging_minutes debe ser >= 0 (recibí %d)", minutes)
+	}
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	g.PriorityAging = minutes
+	if err := s.repo.Upsert(ctx, g); err != nil {
+		return "", err
+	}
+	return s.ListPriorityRoles(ctx, guildID)
+}
+
+func (s *GuildSettingsService) ListPriorityRoles(ctx context.Context, guildID string) (string, error) {
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	prs, err := s.repo.ListPriorityRoles(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("⭐ **Prioridad en la cola**\n")
+	if len(prs) == 0 {
+		b.WriteString("• *(ningún rol con prioridad)*\n")
+	}
+	for _, pr := range prs {
+		fmt.Fprintf(&b, "• <@&%s> — **%d**\n", pr.RoleID, pr.Weight)
+	}
+	b.WriteString("• envejecimiento: " + agingLabel(g.PriorityAging))
+	return b.String(), nil
+}
+
+func agingLabel(minutes int) string {
+	if minutes <= 0 {
+		return "**apagado** (orden estricto por prioridad)"
+	}
+	return fmt.Sprintf("**+1 cada %d min** esperando", minutes)
+}
+
+// Location: zona horaria del guild para los horarios de las colas (UTC si no hay o es inválida)
+func (s *GuildSettingsService) Location(ctx context.Context, guildID string) *time.Location {
+	g, err := s.Get(ctx, guildID)
+	if err != nil || g.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(g.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (s *GuildSettingsService) SetTimezone(ctx context.Context, guildID, tz string) (string, error) {
+	tz = strings.TrimSpace(tz)
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" || tz == "Local" {
+		return "", fmt.Errorf("zona horaria inválida %q (usá una IANA, ej: America/Argentina/Buenos_Aires)", tz)
+	}
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	g.Timezone = tz
+	if err := s.repo.Upsert(ctx, g); err != nil {
+		return "", err
+	}
+	return s.Show(ctx, guildID)
+}
+
+// HubIDs: hubs del guild; sin hubs propios, el home usa FACEIT_HUB_ID y el resto nil
+// (el hub del .env es de la comunidad del home, no de cualquiera que sume el bot)
+func (s *GuildSettingsService) HubIDs(ctx context.Context, guildID string) []string {
+	hubs, err := s.repo.ListHubs(ctx, guildID)
+	if err == nil && len(hubs) > 0 {
+		ids := make([]string, 0, len(hubs))
+		for _, h := range hubs {
+			ids = append(ids, h.HubID)
+		}
+		return ids
+	}
+	if id := s.defaultHubFor(guildID); id != "" {
+		return []string{id}
+	}
+	return nil
+}
+
+// defaultHubFor: FACEIT_HUB_ID si el guild es el home, "" si no
+func (s *GuildSettingsService) defaultHubFor(guildID string) string {
+	if s.isHome(guildID) {
+		return s.defaultHubID
+	}
+	return ""
+}
+
+// GuildForHub: guild dueño de un hub; si nadie lo configuró y es el hub default, el guild default
+func (s *GuildSettingsService) GuildForHub(ctx context.Context, hubID string) (string, bool) {
+	if id, err := s.repo.GuildForHub(ctx, hubID); err == nil {
+		return id, true
+	}
+	if hubID != "" && hubID == s.defaultHubID && s.defaults.GuildID != "" {
+		return s.defaults.GuildID, true
+	}
+	return "", false
+}
+
+// AddHub: valida el hub contra la API de FACEIT antes de guardarlo
+func (s *GuildSettingsService) AddHub(ctx context.Context, guildID, hubID string) (string, error) {
+	hubID = strings.TrimSpace(hubID)
+	if hubID == "" {
+		return "", fmt.Errorf("hub_id vacío")
+	}
+	// un hub por guild: los webhooks de sus matches se rutean por hub
+	if owner, err := s.repo.GuildForHub(ctx, hubID); err == nil && owner != guildID {
+		return "⚠️ Ese hub ya está configurado en otro servidor.", nil
+	} else if err != nil && err != storage.ErrNotFound {
+		return "", err
+	}
+
+	hub, err := s.fc.GetHub(ctx, hubID)
+	if err != nil {
+		return "⚠️ No encontré el hub `" + hubID + "` en FACEIT (" + err.Error() + ").", nil
+	}
+	if err := s.repo.AddHub(ctx, storage.GuildHub{GuildID: guildID, HubID: hub.ID, Name: hub.Name}); err != nil {
+		return "", err
+	}
+	return "✅ Hub **" + hub.Name + "** agregado.", nil
+}
+
+func (s *GuildSettingsService) RemoveHub(ctx context.Context, guildID, hubID string) (string, error) {
+	ok, err := s.repo.RemoveHub(ctx, guildID, strings.TrimSpace(hubID))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "ℹ️ Ese hub no estaba configurado.", nil
+	}
+	return "✅ Hub `" + hubID + "` quitado.", nil
+}
+
+func (s *GuildSettingsService) ListHubs(ctx context.Context, guildID string) (string, error) {
+	hubs, err := s.repo.ListHubs(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	return renderHubs(hubs, s.defaultHubFor(guildID)), nil
+}
+
+func (s *GuildSettingsService) SetVoice(ctx context.Context, guildID, categoryID, afkChannelID string) (string, error) {
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	g.VoiceCategoryID = categoryID
+	g.AFKChannelID = afkChannelID
+	if err := s.repo.Upsert(ctx, g); err != nil {
+		return "", err
+	}
+	return s.Show(ctx, guildID)
+}
+
+func (s *GuildSettingsService) Show(ctx context.Context, guildID string) (string, error) {
+	g, err := s.Get(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	hubs, err := s.repo.ListHubs(ctx, guildID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"**Setup de %s**\n• categoría de voz: %s\n• canal AFK: %s\n• colas a la vez por jugador: **%d**\n• jugadores por party: **%d**\n• envejecimiento de prioridad: %s\n• zona horaria: **%s**\n%s",
+		guildID, orNone(g.VoiceCategoryID, "<#%s>"), orNone(g.AFKChannelID, "<#%s>"), max(g.MaxQueues, 1), max(g.MaxPartySize, 1), agingLabel(g.PriorityAging), orDefault(g.Timezone, "UTC"),
+		renderHubs(hubs, s.defaultHubFor(guildID)),
+	), nil
+}
+
+func renderHubs(hubs []storage.GuildHub, defaultHubID string) string {
+	if len(hubs) == 0 {
+		if defaultHubID != "" {
+			return "• hubs FACEIT: `" + defaultHubID + "` *(default del bot)*"
+		}
+		return "• hubs FACEIT: *(sin configurar)*"
+	}
+	var b strings.Builder
+	b.WriteString("• hubs FACEIT:")
+	for _, h := range hubs {
+		fmt.Fprintf(&b, "\n  – %s `%s`", orNone(h.Name, "**%s**"), h.HubID)
+	}
+	return b.String()
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func orNone(v, format string) string {
+	if v == "" {
+		return "*(sin configurar)*"
+	}
+	return fmt.Sprintf(format, v)
+}
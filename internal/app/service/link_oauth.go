@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// stateTTL: cuánto vale el link de "Iniciar sesión con FACEIT"
+const stateTTL = 10 * time.Minute
+
+var (
+	ErrOAuthDisabled = errors.New("oauth de FACEIT no configurado")
+	ErrInvalidState  = errors.New("state inválido, vencido o ya usado")
+)
+
+// EnableOAuth activa el /link por "Sign in with FACEIT". signingKey firma los states (HMAC).
+func (s *LinkService) EnableOAuth(oauth FaceitOAuth, states OAuthStateRepo, signingKey string) {
+	s.oauth = oauth
+	s.states = states
+	s.stateKey = []byte(signingKey)
+}
+
+func (s *LinkService) OAuthEnabled() bool { return s.oauth != nil && s.states != nil }
+
+// StartOAuth: genera state + PKCE y devuelve la URL de login de FACEIT
+func (s *LinkService) StartOAuth(ctx context.Context, discordID, guildID string) (string, error) {
+	if !s.OAuthEnabled() {
+		return "", ErrOAuthDisabled
+	}
+	_, _ = s.states.DeleteExpired(ctx) // limpieza oportunista
+
+	nonce, err := randomToken(24)
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := newPKCE()
+	if err != nil {
+		return "", err
+	}
+	state := s.signState(nonce)
+	if err := s.states.Create(ctx, storage.OAuthState{
+		State:         state,
+		DiscordUserID: discordID,
+		GuildID:       guildID,
+		CodeVerifier:  verifier,
+		ExpiresAt:     time.Now().Add(stateTTL),
+	}); err != nil {
+		return "", err
+	}
+	return s.oauth.AuthCodeURL(state, challenge), nil
+}
+
+// CompleteOAuth: callback de FACEIT. Valida el state, cambia el code y vincula la cuenta logueada.
+func (s *LinkService) CompleteOAuth(ctx context.Context, state, code string) (string, error) {
+	if !s.OAuthEnabled() {
+		return "", ErrOAuthDisabled
+	}
+	// la firma se chequea antes de ir a la DB: states inventados ni llegan a consultar
+	if !s.verifyState(state) || code == "" {
+		return "", ErrInvalidState
+	}
+	st, err := s.states.Consume(ctx, state)
+	if err == storage.ErrNotFound {
+		return "", ErrInvalidState
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.oauth.Exchange(ctx, code, st.CodeVerifier)
+	if err != nil {
+		return "", fmt.Errorf("intercambio de code: %w", err)
+	}
+	who, err := s.oauth.UserInfo(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("userinfo: %w", err)
+	}
+
+	// elo/nivel vienen de la Data API; el ID tiene que coincidir con el de la sesión
+	p, err := s.fc.GetPlayerByNickname(ctx, who.Nickname, "cs2")
+	if err != nil || p.ID != who.ID {
+		p = who
+	}
+	return s.linkPlayer(ctx, p, st.DiscordUserID, st.GuildID, "oauth")
+}
+
+// state = nonce.firma (HMAC-SHA256, base64url)
+func (s *LinkService) signState(nonce string) string {
+	m := hmac.New(sha256.New, s.stateKey)
+	m.Write([]byte(nonce))
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (s *LinkService) verifyState(state string) bool {
+	nonce, _, ok := strings.Cut(state, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(state), []byte(s.signState(nonce)))
+}
+
+// newPKCE: code_verifier aleatorio y su code_challenge (S256)
+func newPKCE() (verifier, challenge string, err error) {
+	verifier, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -3,19 +3,27 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/jose-valero/faceit-queue-bot/internal/domain"
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/faceitevent"
 	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
 )
 
 type LinkService struct {
 	fc    FaceitAPI
 	users UserRepo
-	hubID string
+	hubs  GuildConfig
+
+	// OAuth (opcional): si oauth es nil, /link sigue siendo por nickname
+	oauth    FaceitOAuth
+	states   OAuthStateRepo
+	stateKey []byte
 }
 
-func NewLinkService(fc FaceitAPI, users UserRepo, hubID string) *LinkService {
-	return &LinkService{fc: fc, users: users, hubID: hubID}
+func NewLinkService(fc FaceitAPI, users UserRepo, hubs GuildConfig) *LinkService {
+	return &LinkService{fc: fc, users: users, hubs: hubs}
 }
 
 func (s *LinkService) DescribeByNick(ctx context.Context, nick string) (string, error) {
@@ -27,17 +35,35 @@ func (s *LinkService) DescribeByNick(ctx context.Context, nick string) (string,
 }
 
 func (s *LinkService) Link(ctx context.Context, nick, discordID, guildID string) (string, error) {
+	// con OAuth activo no confiamos en el nick tipeado (cualquiera puede escribir el de otro)
+	if s.OAuthEnabled() {
+		return "🔐 El vínculo ahora se hace iniciando sesión en FACEIT: usá el botón de `/link`.", nil
+	}
 	p, err := s.fc.GetPlayerByNickname(ctx, nick, "cs2")
 	if err != nil {
 		return "", err
 	}
+	// una cuenta ya verificada por otro no se la puede llevar alguien que sólo tipea el nick
+	if owner, err := s.users.GetByFaceitID(ctx, p.ID); err == nil && owner.DiscordUserID != discordID && owner.VerifiedAt != nil {
+		return "⛔ Esa cuenta de FACEIT ya está vinculada y **verificada** por otro usuario.", nil
+	}
+	return s.linkPlayer(ctx, p, discordID, guildID, "")
+}
 
-	// ¿ya está vinculado este discord en este guild?
-	existing, err := s.users.GetByDiscordID(ctx, discordID)
-	if err == nil && existing.GuildID == guildID {
+// linkPlayer: guarda el vínculo (o revalida si ya existía) para un jugador ya resuelto.
+// verifiedBy != "" cuando el flujo ya probó que la cuenta es suya (oauth).
+func (s *LinkService) linkPlayer(ctx context.Context, p *domain.Player, discordID, guildID, verifiedBy string) (string, error) {
+	var verifiedAt *time.Time
+	if verifiedBy != "" {
+		now := time.Now()
+		verifiedAt = &now
+	}
+	// ¿ya está vinculado este discord en este guild? (un vínculo de otro servidor no cuenta acá)
+	existing, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err == nil {
 		if existing.FaceitUserID == p.ID {
 			// revalida membresía + refresca snapshot
-			isMember, err := s.fc.IsMemberOfHub(ctx, p.ID, s.hubID)
+			isMember, err := memberOfAnyHub(ctx, s.fc, p.ID, s.hubs.HubIDs(ctx, guildID))
 			if err != nil {
 				return "", err
 			}
@@ -53,6 +79,9 @@ func (s *LinkService) Link(ctx context.Context, nick, discordID, guildID string)
 				GuildID:            guildID,
 				EloSnapshot:        &elo,
 				SkillLevelSnapshot: &skill,
+				SnapshotAt:         &now,
+				VerifiedAt:         verifiedAt,
+				VerificationMethod: verifiedBy,
 			})
 			if isMember {
 				return "✅ Ya estabas vinculado como **" + p.Nickname + "** y eres **miembro del Club**. ¡Todo listo!", nil
@@ -66,8 +95,15 @@ func (s *LinkService) Link(ctx context.Context, nick, discordID, guildID string)
 	if err != nil && err != storage.ErrNotFound {
 		return "", err
 	}
+	// una cuenta FACEIT tiene un solo vínculo activo: si es suyo en otro servidor, UpsertLink
+	// se lo llevaría para acá y allá quedaría desvinculado sin enterarse
+	if owner, err := s.users.GetByFaceitID(ctx, p.ID); err == nil && owner.DiscordUserID == discordID && owner.GuildID != guildID {
+		return "⚠️ Esa cuenta de FACEIT ya está vinculada en otro servidor. Usá `/unlink` allá primero: cada vínculo vale sólo en el servidor donde lo hiciste.", nil
+	} else if err != nil && err != storage.ErrNotFound {
+		return "", err
+	}
 
-	isMember, err := s.fc.IsMemberOfHub(ctx, p.ID, s.hubID)
+	isMember, err := memberOfAnyHub(ctx, s.fc, p.ID, s.hubs.HubIDs(ctx, guildID))
 	if err != nil {
 		return "", err
 	}
@@ -84,6 +120,9 @@ func (s *LinkService) Link(ctx context.Context, nick, discordID, guildID string)
 		GuildID:            guildID,
 		EloSnapshot:        &elo,
 		SkillLevelSnapshot: &skill,
+		SnapshotAt:         &now,
+		VerifiedAt:         verifiedAt,
+		VerificationMethod: verifiedBy,
 	}); err != nil {
 		return "", err
 	}
@@ -105,19 +144,23 @@ func (s *LinkService) Unlink(ctx context.Context, discordID, guildID string) (st
 	return "✅ Listo, desvinculado. Usa `/link` cuando quieras volver a vincular.", nil
 }
 
-func (s *LinkService) WhoAmI(ctx context.Context, discordID string) (string, error) {
-	ul, err := s.users.GetByDiscordID(ctx, discordID)
+func (s *LinkService) WhoAmI(ctx context.Context, guildID, discordID string) (string, error) {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
 	if err != nil {
 		return "", err
 	}
+	verified := "no (usa `/link` para verificar)"
+	if ul.VerifiedAt != nil {
+		verified = fmt.Sprintf("sí, por %s <t:%d:R>", ul.VerificationMethod, ul.VerifiedAt.Unix())
+	}
 	return fmt.Sprintf(
-		"**Discord:** <@%s>\n**FACEIT:** `%s` (%s)\n**Miembro del Club:** %v\n**Vinculado:** <t:%d:R>",
-		ul.DiscordUserID, ul.FaceitUserID, ul.Nickname, ul.IsMember, ul.LinkedAt.Unix(),
+		"**Discord:** <@%s>\n**FACEIT:** `%s` (%s)\n**Miembro del Club:** %v\n**Verificado:** %s\n**Vinculado:** <t:%d:R>",
+		ul.DiscordUserID, ul.FaceitUserID, ul.Nickname, ul.IsMember, verified, ul.LinkedAt.Unix(),
 	), nil
 }
 
-func (s *LinkService) EnsureSnapshot(ctx context.Context, discordID string) (*int, *int, string, error) {
-	ul, err := s.users.GetByDiscordID(ctx, discordID)
+func (s *LinkService) EnsureSnapshot(ctx context.Context, guildID, discordID string) (*int, *int, string, error) {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
 	if err != nil {
 		return nil, nil, "", err
 	}
@@ -142,6 +185,7 @@ func (s *LinkService) EnsureSnapshot(ctx context.Context, discordID string) (*in
 
 	elo := p.Elo
 	skill := p.Skill
+	now := time.Now()
 
 	// Persistimos snapshots (reutilizamos campos existentes del link)
 	_ = s.users.UpsertLink(ctx, storage.UserLink{
@@ -154,7 +198,43 @@ func (s *LinkService) EnsureSnapshot(ctx context.Context, discordID string) (*in
 		LinkedAt:           ul.LinkedAt,        // mantenemos
 		EloSnapshot:        &elo,
 		SkillLevelSnapshot: &skill,
+		SnapshotAt:         &now,
 	})
 
 	return &skill, &elo, p.Nickname, nil
 }
+
+// HandleMemberEvent: webhook hub_user_added/removed. Sólo cuentan los hubs que algún guild
+// tiene configurados; una baja no pisa is_member a ciegas (puede seguir en otro hub del
+// mismo guild, ej. salió de la academy pero sigue en el principal): se vuelve a consultar.
+func (s *LinkService) HandleMemberEvent(ctx context.Context, m faceitevent.MemberEvent) error {
+	guildID, ok := s.hubs.GuildForHub(ctx, m.HubID)
+	if !ok {
+		log.Printf("[link] %s hub=%s: ningún guild tiene ese hub, lo ignoro", m.UserID, m.HubID)
+		return nil
+	}
+	isMember := m.Added
+	if !m.Added {
+		var err error
+		if isMember, err = memberOfAnyHub(ctx, s.fc, m.UserID, s.hubs.HubIDs(ctx, guildID)); err != nil {
+			return fmt.Errorf("membresía de %s: %w", m.UserID, err)
+		}
+	}
+	return s.users.UpdateMembershipByFaceitID(ctx, m.UserID, isMember)
+}
+
+// memberOfAnyHub: alcanza con ser miembro de uno de los hubs del guild (ej: principal o academy)
+func memberOfAnyHub(ctx context.Context, fc FaceitAPI, playerID string, hubIDs []string) (bool, error) {
+	var lastErr error
+	for _, hubID := range hubIDs {
+		ok, err := fc.IsMemberOfHub(ctx, playerID, hubID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, lastErr
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// verifyCodeTTL: tiempo para poner el código en el perfil y tocar "Verificar"
+const verifyCodeTTL = 15 * time.Minute
+
+// sin 0/O/1/I para que no haya dudas al tipearlo
+const verifyAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var ErrAlreadyVerified = errors.New("el vínculo ya está verificado")
+
+// IssueVerifyCode: genera (o reusa si sigue vigente) el código que el jugador pone en su perfil FACEIT
+func (s *LinkService) IssueVerifyCode(ctx context.Context, guildID, discordID string) (string, time.Time, error) {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if ul.VerifiedAt != nil {
+		return "", time.Time{}, ErrAlreadyVerified
+	}
+	if ul.VerifyCode != "" && ul.VerifyCodeExpires != nil && time.Now().Before(*ul.VerifyCodeExpires) {
+		return ul.VerifyCode, *ul.VerifyCodeExpires, nil
+	}
+
+	code, err := newVerifyCode(6)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	exp := time.Now().Add(verifyCodeTTL)
+	if err := s.users.SetVerifyCode(ctx, ul.FaceitUserID, code, exp); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, exp, nil
+}
+
+// VerifyCode: vuelve a leer el perfil FACEIT y confirma si el código está en un campo visible
+func (s *LinkService) VerifyCode(ctx context.Context, guildID, discordID string) (string, error) {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err != nil {
+		return "❌ No estás vinculado. Usa `/link` primero.", nil
+	}
+	if ul.VerifiedAt != nil {
+		return "✅ Tu vínculo ya estaba verificado.", nil
+	}
+	if ul.VerifyCode == "" || ul.VerifyCodeExpires == nil || time.Now().After(*ul.VerifyCodeExpires) {
+		return "⌛ Tu código venció. Usa `/link` de nuevo para pedir otro.", nil
+	}
+
+	p, err := s.fc.GetPlayer(ctx, ul.FaceitUserID, "cs2")
+	if err != nil {
+		return "", err
+	}
+	if !containsCode(ul.VerifyCode, p.SteamNickname, p.GamePlayerName) {
+		return fmt.Sprintf("🔎 No encontré `%s` en tu perfil de FACEIT todavía (nombre de Steam o nombre en CS2). "+
+			"FACEIT puede tardar unos minutos en actualizarlo; probá de nuevo en un rato.", ul.VerifyCode), nil
+	}
+
+	if err := s.users.MarkVerified(ctx, ul.FaceitUserID, "code"); err != nil {
+		return "", err
+	}
+	return "✅ Cuenta **verificada**. Ya podés sacar el código de tu perfil.", nil
+}
+
+func containsCode(code string, fields ...string) bool {
+	for _, f := range fields {
+		if strings.Contains(strings.ToUpper(f), code) {
+			return true
+		}
+	}
+	return false
+}
+
+func newVerifyCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = verifyAlphabet[int(b[i])%len(verifyAlphabet)]
+	}
+	return "XCG-" + string(b), nil
+}
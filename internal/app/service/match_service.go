@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/teams"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// tamaño por defecto si la policy no trae uno válido (5v5)
+const defaultMatchSize = 10
+
+// maxDraftMatchSize: los capitanes eligen de un select de Discord (máximo 25 opciones);
+// más jugadores que eso (par) no entran en el draft y el match va por balance
+const maxDraftMatchSize = 26
+
+// draftPickTimeout: cuánto tiene cada capitán para elegir; después elige el bot por él
+const draftPickTimeout = 60 * time.Second
+
+type MatchService struct {
+	matches   MatchRepo
+	users     UserRepo
+	policy    PolicyRepo
+	penalties PenaltyRepo
+	strategy  teams.Strategy // cómo se reparten los equipos en modo balance
+}
+
+func NewMatchService(matches MatchRepo, users UserRepo, policy PolicyRepo, penalties PenaltyRepo) *MatchService {
+	return &MatchService{matches: matches, users: users, policy: policy, penalties: penalties, strategy: teams.Balance}
+}
+
+// UseStrategy: cambia cómo se arman los equipos en modo balance (por defecto teams.Balance)
+func (s *MatchService) UseStrategy(st teams.Strategy) {
+	if st != nil {
+		s.strategy = st
+	}
+}
+
+// ReadyResult: en qué quedó el match después de una respuesta del ready-check
+type ReadyResult struct {
+	Match     storage.PendingMatch
+	Confirmed bool // todos aceptaron (equipos ya armados)
+	Drafting  bool // todos aceptaron y arranca el draft de capitanes
+	Failed    bool // alguien declinó: match cancelado
+}
+
+// DraftResult: en qué quedó el draft después de un pick
+type DraftResult struct {
+	Match storage.PendingMatch
+	Done  bool // equipos completos: match confirmado
+}
+
+// TryForm: si hay suficientes jugadores en 'waiting' arma el match pendiente
+// (saca a los primeros N de la cola). Si la policy tiene ready-check el match queda
+// esperando confirmaciones; si no, se balancea y confirma de una.
+// Devuelve nil si todavía no alcanza; con error y match != nil, el match se armó sin equipos.
+func (s *MatchService) TryForm(ctx context.Context, guildID, queue string) (*storage.PendingMatch, error) {
+	pol, err := s.policy.Get(ctx, guildID, queue)
+	if err != nil {
+		return nil, err
+	}
+	size := pol.MatchSize
+	if size <= 0 {
+		size = defaultMatchSize
+	}
+
+	var deadline *time.Time
+	if pol.ReadyCheckSeconds > 0 {
+		d := time.Now().Add(time.Duration(pol.ReadyCheckSeconds) * time.Second)
+		deadline = &d
+	}
+
+	m, err := s.matches.Pop(ctx, guildID, queue, size, deadline)
+	if errors.Is(err, storage.ErrNotEnoughPlayers) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == "ready_check" {
+		return &m, nil
+	}
+
+	if _, err := s.settle(ctx, &m, "pending"); err != nil {
+		// el match ya existe (los jugadores salieron de la cola): lo devolvemos igual, sin
+		// equipos, y quien llama tiene que anunciarlo aunque venga con error
+		return &m, err
+	}
+	return &m, nil
+}
+
+// SetMessage: recuerda el mensaje publicado del match
+func (s *MatchService) SetMessage(ctx context.Context, matchID int64, channelID, messageID string) error {
+	return s.matches.SetMessage(ctx, matchID, channelID, messageID)
+}
+
+// SetDraftMessage: recuerda el mensaje del draft
+func (s *MatchService) SetDraftMessage(ctx context.Context, matchID int64, channelID, messageID string) error {
+	return s.matches.SetDraftMessage(ctx, matchID, channelID, messageID)
+}
+
+// Respond: aceptar/declinar el ready-check. El string es el mensaje para el jugador.
+func (s *MatchService) Respond(ctx context.Context, matchID int64, discordID string, accept bool) (ReadyResult, string, error) {
+	state := "declined"
+	if accept {
+		state = "accepted"
+	}
+	ok, err := s.matches.SetReadyState(ctx, matchID, discordID, state)
+	if err != nil {
+		return ReadyResult{}, "", err
+	}
+	m, err := s.matches.Get(ctx, matchID)
+	if err != nil {
+		return ReadyResult{}, "", err
+	}
+	if !ok {
+		if !inMatch(m, discordID) {
+			return ReadyResult{Match: m}, "ℹ️ No estás en este match.", nil
+		}
+		if m.Status != "ready_check" {
+			return ReadyResult{Match: m}, "ℹ️ Este ready-check ya terminó.", nil
+		}
+		return ReadyResult{Match: m}, "ℹ️ Ya respondiste.", nil
+	}
+
+	if !accept {
+		res, err := s.failReadyCheck(ctx, matchID)
+		return res, "❌ Declinaste el match.", err
+	}
+
+	for _, p := range m.Players {
+		if p.ReadyState != "accepted" {
+			return ReadyResult{Match: m}, "✅ Aceptaste. Esperando al resto…", nil
+		}
+	}
+	moved, err := s.settle(ctx, &m, "ready_check")
+	res := ReadyResult{Match: m}
+	if moved {
+		res.Confirmed = m.Status == "confirmed"
+		res.Drafting = m.Status == "drafting"
+	}
+	if err != nil {
+		// la respuesta sí quedó: si el match se confirmó sin equipos se anuncia igual; si ni
+		// eso, sigue en ready_check y al vencer vuelven todos a la cola
+		log.Printf("[match] settle match=%d: %v", m.ID, err)
+		if !moved {
+			return res, "✅ Aceptaste, pero no pude arrancar el match. Si no arranca, al vencer el ready-check vuelven todos a la cola.", nil
+		}
+	}
+	return res, "✅ Aceptaste. ¡Están todos!", nil
+}
+
+// ExpireReadyChecks: cierra los ready-checks vencidos (los que no respondieron quedan fuera)
+func (s *MatchService) ExpireReadyChecks(ctx context.Context) ([]storage.PendingMatch, error) {
+	ids, err := s.matches.ListExpiredReadyChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []storage.PendingMatch
+	for _, id := range ids {
+		res, err := s.failReadyCheck(ctx, id)
+		if err != nil {
+			log.Printf("[match] expire ready-check match=%d: %v", id, err)
+			continue
+		}
+		if res.Failed {
+			out = append(out, res.Match)
+		}
+	}
+	return out, nil
+}
+
+// failReadyCheck: cancela el match, devuelve a la cola a los que aceptaron y penaliza al resto
+func (s *MatchService) failReadyCheck(ctx context.Context, matchID int64) (ReadyResult, error) {
+	m, ok, err := s.matches.FailReadyCheck(ctx, matchID)
+	if err != nil || !ok {
+		return ReadyResult{Match: m}, err
+	}
+
+	// el match ya quedó cancelado: sin policy no hay penalización, pero el resultado vale igual
+	pol, err := s.policy.Get(ctx, m.GuildID, m.QueueName)
+	if err != nil {
+		log.Printf("[match] policy ready-check match=%d: %v (sin penalizaciones)", m.ID, err)
+	}
+	if s.penalties != nil && err == nil && pol.ReadyPenaltySeconds > 0 {
+		until := time.Now().Add(time.Duration(pol.ReadyPenaltySeconds) * time.Second)
+		for _, p := range m.Players {
+			if p.ReadyState != "declined" && p.ReadyState != "timeout" {
+				continue
+			}
+			if err := s.penalties.Add(ctx, storage.QueuePenalty{
+				GuildID:       m.GuildID,
+				DiscordUserID: p.DiscordUserID,
+				Kind:          "ready_check",
+				Reason:        fmt.Sprintf("ready-check %s (match #%d)", p.ReadyState, m.ID),
+				ExpiresAt:     until,
+			}); err != nil {
+				log.Printf("[match] penalty %s: %v", p.DiscordUserID, err)
+			}
+		}
+	}
+	return ReadyResult{Match: m, Failed: true}, nil
+}
+
+// settle: el match tiene a todos confirmados → arma equipos según la policy:
+// 'balance' lo deja 'confirmed' con equipos por elo, 'captains' lo pasa a 'drafting'.
+// El estado cambia en la misma transacción que guarda equipos o capitanes; si el draft no se
+// puede arrancar va por balance, y si tampoco se pueden guardar los equipos se confirma sin
+// equipos (moved=true con error): los jugadores ya salieron de la cola, hay que anunciarlo igual.
+// Devuelve false si otro request ya lo había resuelto.
+func (s *MatchService) settle(ctx context.Context, m *storage.PendingMatch, from string) (bool, error) {
+	mode := "balance"
+	if pol, err := s.policy.Get(ctx, m.GuildID, m.QueueName); err != nil {
+		log.Printf("[match] policy settle match=%d: %v (va por balance)", m.ID, err)
+	} else {
+		mode = pol.TeamMode
+	}
+
+	// con parties no hay draft (un capitán podría separarlas) ni con más jugadores de los que
+	// entran en el select (policies viejas): esos matches van por balance
+	if mode == "captains" && len(m.Players) >= 4 && len(m.Players) <= maxDraftMatchSize && !hasParty(*m) {
+		moved, err := s.startDraft(ctx, m, from)
+		if err == nil {
+			return moved, nil
+		}
+		log.Printf("[match] draft match=%d: %v (va por balance)", m.ID, err)
+	}
+
+	moved, err := s.balance(ctx, m, from)
+	if err == nil {
+		return moved, nil
+	}
+	ok, serr := s.matches.SetStatus(ctx, m.ID, from, "confirmed")
+	if serr != nil {
+		return false, fmt.Errorf("equipos: %v; confirmar sin equipos: %w", err, serr)
+	}
+	if !ok {
+		return false, s.reload(ctx, m)
+	}
+	m.Status = "confirmed"
+	for i := range m.Players {
+		m.Players[i].Team = 0
+	}
+	return true, fmt.Errorf("equipos: %w", err)
+}
+
+// reload: otro request ya movió el match; m pasa a reflejar lo que hay en la DB
+func (s *MatchService) reload(ctx context.Context, m *storage.PendingMatch) error {
+	cur, err := s.matches.Get(ctx, m.ID)
+	if err == nil {
+		*m = cur
+	}
+	return err
+}
+
+// startDraft: los dos de mayor elo son capitanes; el resto queda sin equipo hasta que los elijan
+func (s *MatchService) startDraft(ctx context.Context, m *storage.PendingMatch, from string) (bool, error) {
+	in := make([]teams.Player, 0, len(m.Players))
+	for _, p := range m.Players {
+		in = append(in, teams.Player{ID: p.DiscordUserID, Rating: s.ratingFor(ctx, m.GuildID, p.DiscordUserID)})
+	}
+	c1, c2, ok := teams.Captains(in)
+	if !ok {
+		return false, errors.New("no hay jugadores suficientes para capitanes")
+	}
+	players := append([]storage.PendingMatchPlayer(nil), m.Players...)
+	for i := range players {
+		r := in[i].Rating
+		players[i].Rating = &r
+		switch players[i].DiscordUserID {
+		case c1.ID:
+			players[i].Team = 1
+		case c2.ID:
+			players[i].Team = 2
+		}
+	}
+	deadline := time.Now().Add(draftPickTimeout)
+	moved, err := s.matches.StartDraft(ctx, m.ID, from, c1.ID, c2.ID, players, deadline)
+	if err != nil {
+		return false, err
+	}
+	if !moved {
+		return false, s.reload(ctx, m)
+	}
+	m.Players, m.Status, m.PickDeadline = players, "drafting", &deadline
+	m.Mode, m.Captain1ID, m.Captain2ID = "captains", c1.ID, c2.ID
+	return true, nil
+}
+
+// DraftTurn: a quién le toca elegir (capitán, equipo). ok=false si el draft terminó.
+func DraftTurn(m storage.PendingMatch) (captainID string, team int, ok bool) {
+	order := teams.SnakeOrder(len(m.Players) - 2)
+	idx := len(m.Picks)
+	if m.Status != "drafting" || idx >= len(order) {
+		return "", 0, false
+	}
+	team = order[idx]
+	if team == 1 {
+		return m.Captain1ID, 1, true
+	}
+	return m.Captain2ID, 2, true
+}
+
+// Pick: el capitán de turno elige a un jugador. Si queda uno solo, se asigna solo.
+func (s *MatchService) Pick(ctx context.Context, matchID int64, captainID, pickedID string) (DraftResult, string, error) {
+	m, err := s.matches.Get(ctx, matchID)
+	if err != nil {
+		return DraftResult{}, "", err
+	}
+	turnCaptain, team, ok := DraftTurn(m)
+	if !ok {
+		return DraftResult{Match: m}, "ℹ️ Este draft ya terminó.", nil
+	}
+	if captainID != turnCaptain {
+		return DraftResult{Match: m}, "⏳ No es tu turno de elegir.", nil
+	}
+	if !isFree(m, pickedID) {
+		return DraftResult{Match: m}, "⚠️ Ese jugador ya tiene equipo.", nil
+	}
+
+	added, err := s.matches.AddPick(ctx, storage.DraftPick{
+		MatchID: m.ID, PickNo: len(m.Picks), Team: team, CaptainID: captainID, DiscordUserID: pickedID,
+	}, time.Now().Add(draftPickTimeout))
+	if err != nil {
+		return DraftResult{Match: m}, "", err
+	}
+	if !added {
+		// doble click / carrera con otro pick
+		m, err = s.matches.Get(ctx, matchID)
+		return DraftResult{Match: m}, "ℹ️ Ese turno ya se jugó.", err
+	}
+
+	m, err = s.matches.Get(ctx, matchID)
+	if err != nil {
+		return DraftResult{}, "", err
+	}
+
+	// último jugador libre: no hay nada que elegir
+	if free := freePlayers(m); len(free) == 1 {
+		if c, t, ok := DraftTurn(m); ok {
+			if _, err := s.matches.AddPick(ctx, storage.DraftPick{
+				MatchID: m.ID, PickNo: len(m.Picks), Team: t, CaptainID: c, DiscordUserID: free[0].DiscordUserID,
+			}, time.Now().Add(draftPickTimeout)); err != nil {
+				return DraftResult{Match: m}, "", err
+			}
+			if m, err = s.matches.Get(ctx, matchID); err != nil {
+				return DraftResult{}, "", err
+			}
+		}
+	}
+
+	if len(freePlayers(m)) > 0 {
+		return DraftResult{Match: m}, "✅ Elegido.", nil
+	}
+	done, err := s.matches.SetStatus(ctx, m.ID, "drafting", "confirmed")
+	if err != nil {
+		return DraftResult{Match: m}, "", err
+	}
+	if done {
+		m.Status = "confirmed"
+	}
+	return DraftResult{Match: m, Done: done}, "✅ Elegido. ¡Equipos completos!", nil
+}
+
+// ExpireDraftPicks: en los drafts cuyo capitán de turno se pasó del plazo elige el bot por él
+// (el libre de más elo), así un capitán afk no deja el match colgado. Devuelve cómo quedó cada uno.
+func (s *MatchService) ExpireDraftPicks(ctx context.Context) ([]DraftResult, error) {
+	ids, err := s.matches.ListExpiredDrafts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []DraftResult
+	for _, id := range ids {
+		m, err := s.matches.Get(ctx, id)
+		if err != nil {
+			log.Printf("[draft] expire match=%d: %v", id, err)
+			continue
+		}
+		captain, _, ok := DraftTurn(m)
+		free := freePlayers(m)
+		if !ok || len(free) == 0 {
+			continue
+		}
+		best := free[0]
+		for _, p := range free[1:] {
+			if ratingOf(p) > ratingOf(best) {
+				best = p
+			}
+		}
+		res, _, err := s.Pick(ctx, id, captain, best.DiscordUserID)
+		if err != nil {
+			log.Printf("[draft] auto-pick match=%d: %v", id, err)
+			continue
+		}
+		log.Printf("[draft] auto-pick match=%d captain=%s picked=%s", id, captain, best.DiscordUserID)
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func ratingOf(p storage.PendingMatchPlayer) int {
+	if p.Rating == nil {
+		return 0
+	}
+	return *p.Rating
+}
+
+func freePlayers(m storage.PendingMatch) []storage.PendingMatchPlayer {
+	var out []storage.PendingMatchPlayer
+	for _, p := range m.Players {
+		if p.Team == 0 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func isFree(m storage.PendingMatch, discordID string) bool {
+	for _, p := range freePlayers(m) {
+		if p.DiscordUserID == discordID {
+			return true
+		}
+	}
+	return false
+}
+
+func hasParty(m storage.PendingMatch) bool {
+	for _, p := range m.Players {
+		if p.PartyID != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func inMatch(m storage.PendingMatch, discordID string) bool {
+	for _, p := range m.Players {
+		if p.DiscordUserID == discordID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MatchService) Get(ctx context.Context, id int64) (storage.PendingMatch, error) {
+	return s.matches.Get(ctx, id)
+}
+
+// TeamAverages: promedio de rating por equipo (para mostrar en el anuncio)
+func TeamAverages(m storage.PendingMatch) (float64, float64) {
+	var sum1, sum2, n1, n2 int
+	for _, p := range m.Players {
+		r := 0
+		if p.Rating != nil {
+			r = *p.Rating
+		}
+		switch p.Team {
+		case 1:
+			sum1, n1 = sum1+r, n1+1
+		case 2:
+			sum2, n2 = sum2+r, n2+1
+		}
+	}
+	var a1, a2 float64
+	if n1 > 0 {
+		a1 = float64(sum1) / float64(n1)
+	}
+	if n2 > 0 {
+		a2 = float64(sum2) / float64(n2)
+	}
+	return a1, a2
+}
+
+// balance: reparte por elo (snapshot de user_links, o nivel si no hay elo) y confirma el match
+// con esos equipos. Cada party queda entera en un equipo.
+func (s *MatchService) balance(ctx context.Context, m *storage.PendingMatch, from string) (bool, error) {
+	in := make([]teams.Player, 0, len(m.Players))
+	for _, p := range m.Players {
+		tp := teams.Player{ID: p.DiscordUserID, Rating: s.ratingFor(ctx, m.GuildID, p.DiscordUserID)}
+		if p.PartyID != 0 {
+			tp.Group = strconv.FormatInt(p.PartyID, 10)
+		}
+		in = append(in, tp)
+	}
+	split := s.strategy(in)
+
+	byID := make(map[string]teams.Player, len(in))
+	team := make(map[string]int, len(in))
+	for _, tp := range split.Team1 {
+		byID[tp.ID], team[tp.ID] = tp, 1
+	}
+	for _, tp := range split.Team2 {
+		byID[tp.ID], team[tp.ID] = tp, 2
+	}
+	players := append([]storage.PendingMatchPlayer(nil), m.Players...)
+	for i := range players {
+		id := players[i].DiscordUserID
+		rating := byID[id].Rating
+		players[i].Team = team[id]
+		players[i].Rating = &rating
+	}
+	moved, err := s.matches.Confirm(ctx, m.ID, from, players)
+	if err != nil {
+		return false, err
+	}
+	if !moved {
+		return false, s.reload(ctx, m)
+	}
+	m.Players, m.Status, m.Mode = players, "confirmed", "balance"
+	return true, nil
+}
+
+func (s *MatchService) ratingFor(ctx context.Context, guildID, discordID string) int {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err != nil {
+		return 0
+	}
+	if ul.EloSnapshot != nil && *ul.EloSnapshot > 0 {
+		return *ul.EloSnapshot
+	}
+	if ul.SkillLevelSnapshot != nil {
+		return teams.RatingFromLevel(*ul.SkillLevelSnapshot)
+	}
+	return 0
+}
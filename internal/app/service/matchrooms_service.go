@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log"
@@ -17,6 +18,12 @@ import (
 // Dependencias mínimas para este servicio
 type RoomsFaceit interface {
 	GetMatchStats(ctx context.Context, matchID string) (*domain.MatchStats, error)
+	GetMatchHubID(ctx context.Context, matchID string) (string, error)
+}
+
+// Implementado por GuildSettingsService: a qué guild pertenece un hub
+type GuildResolver interface {
+	GuildForHub(ctx context.Context, hubID string) (string, bool)
 }
 
 type RoomsUserRepo interface {
@@ -28,26 +35,45 @@ type RoomsRepo interface {
 	Upsert(ctx context.Context, m storage.MatchVoiceRoom) error
 	UpdateStatus(ctx context.Context, matchID string, status string) error
 	Delete(ctx context.Context, matchID string) error
+	ListExpired(ctx context.Context) ([]storage.MatchVoiceRoom, error)
 }
 
+// localRoomsTTL: cuánto duran las salas de un match armado por el bot (no hay webhook que avise el final)
+const localRoomsTTL = 90 * time.Minute
+
 type MatchRoomsService struct {
 	s              *discordgo.Session
 	fc             RoomsFaceit
 	users          RoomsUserRepo
 	repo           RoomsRepo
-	guildID        string
+	guilds         GuildResolver
 	categoryPrefix string // ej: "XCG Match"
 }
 
-func NewMatchRoomsService(s *discordgo.Session, fc RoomsFaceit, users RoomsUserRepo, repo RoomsRepo, guildID, categoryPrefix string) *MatchRoomsService {
+func NewMatchRoomsService(s *discordgo.Session, fc RoomsFaceit, users RoomsUserRepo, repo RoomsRepo, guilds GuildResolver, categoryPrefix string) *MatchRoomsService {
 	if categoryPrefix == "" {
 		categoryPrefix = "XCG Faceit Match"
 	}
-	return &MatchRoomsService{s: s, fc: fc, users: users, repo: repo, guildID: guildID, categoryPrefix: categoryPrefix}
+	return &MatchRoomsService{s: s, fc: fc, users: users, repo: repo, guilds: guilds, categoryPrefix: categoryPrefix}
 }
 
-// HandleMatchEvent: llamalo con webhooks "match_status_*"
-func (m *MatchRoomsService) HandleMatchEvent(ctx context.Context, matchID, status string) {
+// guildForMatch: el guild que tiene configurado el hub del match
+func (m *MatchRoomsService) guildForMatch(ctx context.Context, matchID string) (string, error) {
+	hubID, err := m.fc.GetMatchHubID(ctx, matchID)
+	if err != nil {
+		return "", fmt.Errorf("hub del match %s: %w", matchID, err)
+	}
+	guildID, ok := m.guilds.GuildForHub(ctx, hubID)
+	if !ok {
+		return "", fmt.Errorf("ningún guild tiene configurado el hub %s", hubID)
+	}
+	return guildID, nil
+}
+
+// HandleMatchEvent: llamalo con webhooks "match_status_*". El error es para reintentar
+// (el worker de webhooks lo manda al dead letter): si las salas no se pudieron crear o
+// borrar, el registro queda como estaba para que el reintento las encuentre.
+func (m *MatchRoomsService) HandleMatchEvent(ctx context.Context, matchID, status string) error {
 	status = strings.ToLower(status)
 	log.Printf("[rooms] evt match=%s status=%s", matchID, status)
 
@@ -56,15 +82,16 @@ func (m *MatchRoomsService) HandleMatchEvent(ctx context.Context, matchID, statu
 		// Asegura salas y lanza un polling corto para detectar teams y mover
 		if err := m.ensureRooms(ctx, matchID); err != nil {
 			log.Printf("[rooms] ensureRooms: %v", err)
-			return
+			return fmt.Errorf("salas del match %s: %w", matchID, err)
 		}
 		_ = m.repo.UpdateStatus(ctx, matchID, status)
 		go m.pollAndMove(ctx, matchID)
 
-	case strings.Contains(status, "finished") || strings.Contains(status, "cancelled"):
+	case strings.Contains(status, "finished") || strings.Contains(status, "cancelled") || strings.Contains(status, "aborted"):
 		// Limpia
 		if err := m.cleanup(ctx, matchID); err != nil {
 			log.Printf("[rooms] cleanup: %v", err)
+			return fmt.Errorf("limpiar salas del match %s: %w", matchID, err)
 		}
 		_ = m.repo.Delete(ctx, matchID)
 
@@ -72,6 +99,7 @@ func (m *MatchRoomsService) HandleMatchEvent(ctx context.Context, matchID, statu
 		// otros estados: no hacemos nada
 		_ = m.repo.UpdateStatus(ctx, matchID, status)
 	}
+	return nil
 }
 
 // ---------- internos ----------
@@ -81,27 +109,51 @@ func (m *MatchRoomsService) ensureRooms(ctx context.Context, matchID string) err
 	if _, err := m.repo.Get(ctx, matchID); err == nil {
 		return nil
 	}
+	guildID, err := m.guildForMatch(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	return m.createRooms(ctx, guildID, matchID, fmt.Sprintf("%s %s", m.categoryPrefix, shortID(matchID)))
+}
+
+func (m *MatchRoomsService) createRooms(ctx context.Context, guildID, matchID, categoryName string) (err error) {
+	// si algo falla antes de guardar, borramos lo que ya se creó: sin registro nadie lo
+	// limpia después y cada reintento dejaría otra categoría huérfana
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(created) - 1; i >= 0; i-- {
+			if _, derr := m.s.ChannelDelete(created[i]); derr != nil && !isUnknownChannel(derr) {
+				log.Printf("[rooms] limpiar canal %s de match=%s: %v", created[i], matchID, derr)
+			}
+		}
+	}()
 
 	// Crea categoría y 2 voice channels
-	cat, err := m.s.GuildChannelCreate(m.guildID, fmt.Sprintf("%s %s", m.categoryPrefix, shortID(matchID)), discordgo.ChannelTypeGuildCategory)
+	cat, err := m.s.GuildChannelCreate(guildID, categoryName, discordgo.ChannelTypeGuildCategory)
 	if err != nil {
 		return err
 	}
-	t1, err := m.s.GuildChannelCreate(m.guildID, "Team A", discordgo.ChannelTypeGuildVoice)
+	created = append(created, cat.ID)
+	t1, err := m.s.GuildChannelCreate(guildID, "Team A", discordgo.ChannelTypeGuildVoice)
 	if err != nil {
 		return err
 	}
-	t2, err := m.s.GuildChannelCreate(m.guildID, "Team B", discordgo.ChannelTypeGuildVoice)
+	created = append(created, t1.ID)
+	t2, err := m.s.GuildChannelCreate(guildID, "Team B", discordgo.ChannelTypeGuildVoice)
 	if err != nil {
 		return err
 	}
+	created = append(created, t2.ID)
 	// moverlos bajo la categoría
 	_, _ = m.s.ChannelEdit(t1.ID, &discordgo.ChannelEdit{ParentID: cat.ID})
 	_, _ = m.s.ChannelEdit(t2.ID, &discordgo.ChannelEdit{ParentID: cat.ID})
 
 	mv := storage.MatchVoiceRoom{
 		MatchID:        matchID,
-		GuildID:        m.guildID,
+		GuildID:        guildID,
 		CategoryID:     cat.ID,
 		Team1ChannelID: t1.ID,
 		Team2ChannelID: t2.ID,
@@ -192,7 +244,7 @@ func (m *MatchRoomsService) moveTeams(ctx context.Context, matchID string, team1
 
 	// mover a cada jugador (si está en el guild y en voz en cualquier canal)
 	moveOne := func(discordID, channelID string) {
-		if err := m.s.GuildMemberMove(m.guildID, discordID, &channelID); err != nil {
+		if err := m.s.GuildMemberMove(mv.GuildID, discordID, &channelID); err != nil {
 			log.Printf("[rooms] move %s -> %s: %v", discordID, channelID, err)
 		}
 	}
@@ -208,16 +260,29 @@ func (m *MatchRoomsService) moveTeams(ctx context.Context, matchID string, team1
 
 func (m *MatchRoomsService) cleanup(ctx context.Context, matchID string) error {
 	mv, err := m.repo.Get(ctx, matchID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // el match no tenía salas (o ya se limpiaron)
+	}
 	if err != nil {
 		return err
 	}
-	// borrar canales y categoría
-	_, _ = m.s.ChannelDelete(mv.Team1ChannelID)
-	_, _ = m.s.ChannelDelete(mv.Team2ChannelID)
-	_, _ = m.s.ChannelDelete(mv.CategoryID)
+	// borrar canales y categoría; si ya no existen (404) está bien
+	for _, id := range []string{mv.Team1ChannelID, mv.Team2ChannelID, mv.CategoryID} {
+		if id == "" {
+			continue
+		}
+		if _, err := m.s.ChannelDelete(id); err != nil && !isUnknownChannel(err) {
+			return fmt.Errorf("borrar canal %s: %w", id, err)
+		}
+	}
 	return nil
 }
 
+func isUnknownChannel(err error) bool {
+	var re *discordgo.RESTError
+	return errors.As(err, &re) && re.Response != nil && re.Response.StatusCode == 404
+}
+
 func shortID(s string) string {
 	if len(s) <= 6 {
 		return s
@@ -232,9 +297,65 @@ func firstNonEmpty(a, b string) string {
 	return b
 }
 
-// DebugEnsureRooms: fuerza crear salas para un matchID (si no existen)
-func (m *MatchRoomsService) DebugEnsureRooms(ctx context.Context, matchID string) error {
-	return m.ensureRooms(ctx, matchID)
+// DebugEnsureRooms: fuerza crear salas para un matchID (si no existen) en el guild indicado
+func (m *MatchRoomsService) DebugEnsureRooms(ctx context.Context, guildID, matchID string) error {
+	if _, err := m.repo.Get(ctx, matchID); err == nil {
+		return nil
+	}
+	return m.createRooms(ctx, guildID, matchID, fmt.Sprintf("%s %s", m.categoryPrefix, shortID(matchID)))
+}
+
+// OpenForLocalMatch: salas para un match armado por el bot desde la cola (no de FACEIT).
+// Crea Team A/B y mueve a cada jugador a la de su equipo; como el balance no separa
+// parties, cada party termina en el mismo canal. Las salas vencen solas (CleanupExpired).
+func (m *MatchRoomsService) OpenForLocalMatch(ctx context.Context, pm storage.PendingMatch) error {
+	key := fmt.Sprintf("local-%d", pm.ID)
+	if _, err := m.repo.Get(ctx, key); err != nil {
+		if err := m.createRooms(ctx, pm.GuildID, key, fmt.Sprintf("%s #%d", m.categoryPrefix, pm.ID)); err != nil {
+			return err
+		}
+	}
+	mv, err := m.repo.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	exp := time.Now().Add(localRoomsTTL)
+	mv.ExpiresAt = &exp
+	if err := m.repo.Upsert(ctx, mv); err != nil {
+		return err
+	}
+
+	for _, p := range pm.Players {
+		channelID := mv.Team1ChannelID
+		switch p.Team {
+		case 1:
+		case 2:
+			channelID = mv.Team2ChannelID
+		default:
+			continue
+		}
+		// sólo se puede mover a quien está conectado a voz; el resto entra solo
+		if err := m.s.GuildMemberMove(mv.GuildID, p.DiscordUserID, &channelID); err != nil {
+			log.Printf("[rooms] move %s -> %s: %v", p.DiscordUserID, channelID, err)
+		}
+	}
+	return nil
+}
+
+// CleanupExpired: borra las salas vencidas (llamalo desde un ticker)
+func (m *MatchRoomsService) CleanupExpired(ctx context.Context) {
+	rooms, err := m.repo.ListExpired(ctx)
+	if err != nil {
+		log.Printf("[rooms] expired: %v", err)
+		return
+	}
+	for _, mv := range rooms {
+		if err := m.cleanup(ctx, mv.MatchID); err != nil {
+			log.Printf("[rooms] cleanup %s: %v", mv.MatchID, err)
+			continue // queda el registro: se reintenta en la próxima vuelta
+		}
+		_ = m.repo.Delete(ctx, mv.MatchID)
+	}
 }
 
 // DebugMoveDiscord: mueve directamente por Discord IDs (sin Faceit)
@@ -263,7 +384,7 @@ func (m *MatchRoomsService) DebugMoveDiscord(ctx context.Context, matchID string
 	})
 
 	moveOne := func(discordID, channelID string) {
-		if err := m.s.GuildMemberMove(m.guildID, discordID, &channelID); err != nil {
+		if err := m.s.GuildMemberMove(mv.GuildID, discordID, &channelID); err != nil {
 			log.Printf("[rooms] move %s -> %s: %v", discordID, channelID, err)
 		}
 	}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// PartyService: premades. El líder invita, los invitados aceptan o declinan, y la party
+// entra/sale de la cola como una unidad (ver QueueService.Join/Leave y el pop).
+// Mientras la party está en una cola no se puede cambiar quién está adentro.
+type PartyService struct {
+	parties  PartyRepo
+	users    UserRepo
+	queue    QueueRepo
+	guilds   GuildConfig
+	notifier Notifier
+}
+
+func NewPartyService(parties PartyRepo, users UserRepo, queue QueueRepo, guilds GuildConfig, notifier Notifier) *PartyService {
+	return &PartyService{parties: parties, users: users, queue: queue, guilds: guilds, notifier: notifier}
+}
+
+// Invite: el líder (o alguien sin party, que pasa a serlo) invita a otro jugador.
+// Devuelve el ID de la party para armar los botones de aceptar/declinar (0 si no se invitó).
+func (s *PartyService) Invite(ctx context.Context, guildID, leaderID, targetID string) (int64, string, error) {
+	if leaderID == targetID {
+		return 0, "ℹ️ No podés invitarte a vos mismo.", nil
+	}
+	if _, err := s.users.GetByDiscordIDInGuild(ctx, guildID, targetID); err != nil {
+		return 0, fmt.Sprintf("⚠️ <@%s> no está vinculado a FACEIT (tiene que usar `/link` primero).", targetID), nil
+	}
+	if _, err := s.parties.Of(ctx, guildID, targetID); err == nil {
+		return 0, fmt.Sprintf("⚠️ <@%s> ya está en otra party.", targetID), nil
+	}
+
+	p, err := s.parties.Of(ctx, guildID, leaderID)
+	isNew := err == storage.ErrNotFound
+	size := len(p.Members)
+	switch {
+	case isNew:
+		// primera invitación: los mismos chequeos que al aceptar, antes de crear nada
+		if q, err := s.soloQueue(ctx, guildID, leaderID); err != nil {
+			return 0, "", err
+		} else if q != "" {
+			return 0, "⚠️ Estás anotado solo en " + queueLabel(q) + ". Salí de la cola para armar una party.", nil
+		}
+		size = 1 // el que invita
+	case err != nil:
+		return 0, "", err
+	case p.LeaderID != leaderID:
+		return 0, fmt.Sprintf("⛔ Sólo el líder de la party (<@%s>) puede invitar.", p.LeaderID), nil
+	default:
+		if q, err := s.queuedIn(ctx, guildID, p); err != nil {
+			return 0, "", err
+		} else if q != "" {
+			return 0, "⛔ Tu party está en " + queueLabel(q) + ". Salí de la cola para cambiar la party.", nil
+		}
+	}
+	if max := s.guilds.MaxPartySize(ctx, guildID); size >= max {
+		if max <= 1 {
+			return 0, "⛔ En este servidor las parties están desactivadas (máximo **1** jugador).", nil
+		}
+		return 0, fmt.Sprintf("⛔ La party está llena (máximo **%d** jugadores, contando invitaciones pendientes).", max), nil
+	}
+	if isNew {
+		// la party nace con el que invita como líder
+		if p, err = s.parties.Create(ctx, guildID, leaderID); err != nil {
+			return 0, "", err
+		}
+	}
+
+	ok, err := s.parties.Invite(ctx, p.ID, guildID, targetID, leaderID)
+	if err != nil {
+		return 0, "", err
+	}
+	if !ok {
+		return 0, fmt.Sprintf("ℹ️ <@%s> ya estaba invitado.", targetID), nil
+	}
+	return p.ID, fmt.Sprintf("📨 Invitaste a <@%s> a tu party.", targetID), nil
+}
+
+// Respond: el invitado acepta o declina. done=true si la invitación quedó resuelta
+// (para sacarle los botones al mensaje).
+func (s *PartyService) Respond(ctx context.Context, guildID string, partyID int64, discordID string, accept bool) (bool, string, error) {
+	p, err := s.parties.Get(ctx, partyID)
+	if err == storage.ErrNotFound || (err == nil && p.GuildID != guildID) {
+		return true, "ℹ️ Esa party ya no existe.", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if !hasMember(p, discordID, "invited") {
+		if hasMember(p, discordID, "accepted") {
+			return false, "ℹ️ Ya estás en esta party.", nil
+		}
+		return false, "ℹ️ Esta invitación no es para vos (o ya no está pendiente).", nil
+	}
+
+	if !accept {
+		if _, err := s.parties.RemoveMember(ctx, p.ID, discordID); err != nil {
+			return false, "", err
+		}
+		s.notify(guildID, p.LeaderID, fmt.Sprintf("❌ <@%s> declinó tu invitación a la party.", discordID))
+		return true, "❌ Declinaste la invitación.", nil
+	}
+
+	if cur, err := s.parties.Of(ctx, guildID, discordID); err == nil {
+		return false, fmt.Sprintf("⚠️ Ya estás en la party de <@%s>. Usá `/party leave` primero.", cur.LeaderID), nil
+	}
+	if q, err := s.queuedIn(ctx, guildID, p); err != nil {
+		return false, "", err
+	} else if q != "" {
+		return false, "⏳ La party está en " + queueLabel(q) + " ahora. Probá cuando salga.", nil
+	}
+	if q, err := s.soloQueue(ctx, guildID, discordID); err != nil {
+		return false, "", err
+	} else if q != "" {
+		return false, "⚠️ Estás anotado solo en " + queueLabel(q) + ". Salí de la cola para sumarte a una party.", nil
+	}
+
+	ok, err := s.parties.Accept(ctx, p.ID, discordID)
+	if err != nil {
+		return false, "", err
+	}
+	if !ok {
+		return true, "ℹ️ Esta invitación ya no está pendiente.", nil
+	}
+	s.notify(guildID, p.LeaderID, fmt.Sprintf("✅ <@%s> se sumó a tu party.", discordID))
+	return true, fmt.Sprintf("✅ Te sumaste a la party de <@%s>. Cuando uno entre a la cola, entran todos.", p.LeaderID), nil
+}
+
+// Leave: salir de la party; si sale el líder lo reemplaza el más antiguo, si queda uno solo se disuelve
+func (s *PartyService) Leave(ctx context.Context, guildID, discordID string) (string, error) {
+	p, err := s.parties.Of(ctx, guildID, discordID)
+	if err == storage.ErrNotFound {
+		return "ℹ️ No estás en ninguna party.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if q, err := s.queuedIn(ctx, guildID, p); err != nil {
+		return "", err
+	} else if q != "" {
+		return "⛔ Tu party está en " + queueLabel(q) + ". Salí de la cola primero.", nil
+	}
+	if _, err := s.parties.RemoveMember(ctx, p.ID, discordID); err != nil {
+		return "", err
+	}
+	return "👋 Saliste de la party." + s.afterRemoval(ctx, p, discordID), nil
+}
+
+// Kick: el líder saca a alguien (miembro o invitación pendiente)
+func (s *PartyService) Kick(ctx context.Context, guildID, leaderID, targetID string) (string, error) {
+	p, err := s.parties.Of(ctx, guildID, leaderID)
+	if err == storage.ErrNotFound {
+		return "ℹ️ No estás en ninguna party.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if p.LeaderID != leaderID {
+		return "⛔ Sólo el líder de la party puede sacar jugadores.", nil
+	}
+	if targetID == leaderID {
+		return "ℹ️ Para irte usá `/party leave`.", nil
+	}
+	if q, err := s.queuedIn(ctx, guildID, p); err != nil {
+		return "", err
+	} else if q != "" {
+		return "⛔ Tu party está en " + queueLabel(q) + ". Salí de la cola primero.", nil
+	}
+	ok, err := s.parties.RemoveMember(ctx, p.ID, targetID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("ℹ️ <@%s> no está en tu party.", targetID), nil
+	}
+	s.notify(guildID, targetID, fmt.Sprintf("👋 <@%s> te sacó de su party.", leaderID))
+	msg := fmt.Sprintf("✅ Sacaste a <@%s> de la party.", targetID)
+	if hasMember(p, targetID, "accepted") {
+		msg += s.afterRemoval(ctx, p, targetID) // una invitación pendiente no cambia la party
+	}
+	return msg, nil
+}
+
+// afterRemoval: reasigna líder o disuelve la party si quedó uno solo
+func (s *PartyService) afterRemoval(ctx context.Context, p storage.Party, removedID string) string {
+	var left []string
+	for _, id := range p.Accepted() {
+		if id != removedID {
+			left = append(left, id)
+		}
+	}
+	if len(left) <= 1 {
+		if err := s.parties.Disband(ctx, p.ID); err != nil {
+			return ""
+		}
+		for _, id := range left {
+			s.notify(p.GuildID, id, "ℹ️ Tu party se disolvió (quedaste solo).")
+		}
+		return " La party se disolvió."
+	}
+	if p.LeaderID == removedID {
+		if err := s.parties.SetLeader(ctx, p.ID, left[0]); err != nil {
+			return ""
+		}
+		s.notify(p.GuildID, left[0], "👑 Ahora sos el líder de la party.")
+		return fmt.Sprintf(" <@%s> es el nuevo líder.", left[0])
+	}
+	return ""
+}
+
+// Show: quién está en la party del jugador
+func (s *PartyService) Show(ctx context.Context, guildID, discordID string) (string, error) {
+	p, err := s.parties.Of(ctx, guildID, discordID)
+	if err == storage.ErrNotFound {
+		return "ℹ️ No estás en ninguna party. Invitá a alguien con `/party invite`.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "👥 **Party de <@%s>** (máx %d)\n", p.LeaderID, s.guilds.MaxPartySize(ctx, guildID))
+	for _, m := range p.Members {
+		switch {
+		case m.DiscordUserID == p.LeaderID:
+			fmt.Fprintf(&b, "👑 <@%s>\n", m.DiscordUserID)
+		case m.State == "accepted":
+			fmt.Fprintf(&b, "✅ <@%s>\n", m.DiscordUserID)
+		default:
+			fmt.Fprintf(&b, "⏳ <@%s> *(invitado)*\n", m.DiscordUserID)
+		}
+	}
+	if q, err := s.queuedIn(ctx, guildID, p); err == nil && q != "" {
+		b.WriteString("\nEn " + queueLabel(q) + ".")
+	}
+	return b.String(), nil
+}
+
+// Members: con quién entra a la cola el jugador (él solo si no tiene party)
+func (s *PartyService) Members(ctx context.Context, guildID, discordID string) []string {
+	p, err := s.parties.Of(ctx, guildID, discordID)
+	if err != nil {
+		return []string{discordID}
+	}
+	return p.Accepted()
+}
+
+// queuedIn: en qué cola está la party ("" si en ninguna)
+func (s *PartyService) queuedIn(ctx context.Context, guildID string, p storage.Party) (string, error) {
+	entries, err := s.queue.QueuesOf(ctx, guildID, p.LeaderID)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.PartyID == p.ID {
+			return e.QueueName, nil
+		}
+	}
+	return "", nil
+}
+
+// soloQueue: en qué cola está anotado el jugador por su cuenta ("" si en ninguna)
+func (s *PartyService) soloQueue(ctx context.Context, guildID, discordID string) (string, error) {
+	entries, err := s.queue.QueuesOf(ctx, guildID, discordID)
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].QueueName, nil
+}
+
+func (s *PartyService) notify(guildID, userID, msg string) {
+	if s.notifier != nil {
+		s.notifier.Notify(guildID, userID, msg)
+	}
+}
+
+func hasMember(p storage.Party, discordID, state string) bool {
+	for _, m := range p.Members {
+		if m.DiscordUserID == discordID && m.State == state {
+			return true
+		}
+	}
+	return false
+}
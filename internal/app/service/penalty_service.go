@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+const (
+	penaltyKindReliability = "reliability"
+
+	// reliabilityChurn: salir antes de esto después de entrar cuenta como "entrar y salir"
+	reliabilityChurn = 2 * time.Minute
+	// reliabilityEscalation: las penalizaciones de esta ventana duplican la siguiente
+	reliabilityEscalation = 7 * 24 * time.Hour
+	reliabilityMaxPenalty = 24 * time.Hour
+)
+
+// PenaltyService: penalizaciones automáticas por confiabilidad. Mira las transiciones de la
+// cola (podas por afk/left y entrar-salir rápido) y, pasado el límite de la policy, aplica un
+// cooldown que se duplica con cada reincidencia. El Join ya rechaza con cualquier penalización activa.
+type PenaltyService struct {
+	penalties PenaltyRepo
+	queue     QueueRepo
+	policy    PolicyRepo
+	notifier  Notifier
+}
+
+func NewPenaltyService(penalties PenaltyRepo, queue QueueRepo, policy PolicyRepo, notifier Notifier) *PenaltyService {
+	return &PenaltyService{penalties: penalties, queue: queue, policy: policy, notifier: notifier}
+}
+
+// Evaluate: después de una salida, cuenta las faltas del jugador en la ventana de la policy
+// y lo penaliza si llegó al límite. Las faltas anteriores a la última penalización no cuentan
+// (ya las pagó). Devuelve la penalización aplicada, si hubo.
+func (s *PenaltyService) Evaluate(ctx context.Context, guildID, queue, discordID string) (storage.QueuePenalty, bool) {
+	pol, err := s.policy.Get(ctx, guildID, queue)
+	if err != nil || pol.LeaveLimit <= 0 || pol.LeavePenaltySeconds <= 0 {
+		return storage.QueuePenalty{}, false
+	}
+	since := time.Now().Add(-time.Duration(pol.LeaveWindowMinutes) * time.Minute)
+	if last, ok, err := s.penalties.LastOf(ctx, guildID, discordID, penaltyKindReliability); err != nil {
+		log.Printf("[penalty] last %s: %v", discordID, err)
+		return storage.QueuePenalty{}, false
+	} else if ok && last.CreatedAt.After(since) {
+		since = last.CreatedAt
+	}
+
+	n, err := s.queue.CountOffenses(ctx, guildID, queue, discordID, since, reliabilityChurn)
+	if err != nil {
+		log.Printf("[penalty] offenses %s: %v", discordID, err)
+		return storage.QueuePenalty{}, false
+	}
+	if n < pol.LeaveLimit {
+		return storage.QueuePenalty{}, false
+	}
+
+	prev, err := s.penalties.CountSince(ctx, guildID, discordID, penaltyKindReliability, time.Now().Add(-reliabilityEscalation))
+	if err != nil {
+		log.Printf("[penalty] escalation %s: %v", discordID, err)
+		return storage.QueuePenalty{}, false
+	}
+	d := escalate(time.Duration(pol.LeavePenaltySeconds)*time.Second, prev)
+
+	p := storage.QueuePenalty{
+		GuildID:       guildID,
+		DiscordUserID: discordID,
+		Kind:          penaltyKindReliability,
+		Reason:        fmt.Sprintf("%d salidas de %s en %d min", n, queueLabel(queue), pol.LeaveWindowMinutes),
+		ExpiresAt:     time.Now().Add(d),
+	}
+	if prev > 0 {
+		p.Reason += fmt.Sprintf(", reincidencia #%d", prev+1)
+	}
+	if err := s.penalties.Add(ctx, p); err != nil {
+		log.Printf("[penalty] add %s: %v", discordID, err)
+		return storage.QueuePenalty{}, false
+	}
+	log.Printf("[penalty] reliability guild=%s queue=%s user=%s offenses=%d dur=%s", guildID, queue, discordID, n, d)
+	if s.notifier != nil {
+		s.notifier.Notify(guildID, discordID, fmt.Sprintf("⛔ Cooldown automático (%s). Podés volver <t:%d:R>.", p.Reason, p.ExpiresAt.Unix()))
+	}
+	return p, true
+}
+
+// escalate: base * 2^prev, con tope
+func escalate(base time.Duration, prev int) time.Duration {
+	d := base
+	for i := 0; i < prev && d < reliabilityMaxPenalty; i++ {
+		d *= 2
+	}
+	return min(d, reliabilityMaxPenalty)
+}
+
+// Describe: penalización vigente + historial, para /whoami
+func (s *PenaltyService) Describe(ctx context.Context, guildID, discordID string) (string, error) {
+	var b strings.Builder
+	if p, ok, err := s.penalties.Active(ctx, guildID, discordID); err != nil {
+		return "", err
+	} else if ok {
+		fmt.Fprintf(&b, "**Penalización:** ⛔ %s — vence <t:%d:R>\n", p.Reason, p.ExpiresAt.Unix())
+	} else {
+		b.WriteString("**Penalización:** ninguna ✅\n")
+	}
+
+	hist, err := s.penalties.History(ctx, guildID, discordID, 5)
+	if err != nil {
+		return "", err
+	}
+	if len(hist) == 0 {
+		return b.String(), nil
+	}
+	b.WriteString("**Historial:**\n")
+	for _, p := range hist {
+		fmt.Fprintf(&b, "• <t:%d:d> %s (%s)\n", p.CreatedAt.Unix(), p.Reason, fmtPenaltyDuration(p.ExpiresAt.Sub(p.CreatedAt)))
+	}
+	return b.String(), nil
+}
+
+func fmtPenaltyDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%.0fh", d.Hours())
+	case d >= time.Minute:
+		return fmt.Sprintf("%.0f min", d.Minutes())
+	}
+	return fmt.Sprintf("%.0fs", d.Seconds())
+}
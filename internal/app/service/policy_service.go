@@ -19,26 +19,42 @@ type PolicyPatch struct {
 	DropIfLeftSeconds        *int
 	VoiceRequired            *bool
 	CooldownAfterLossSeconds *int
+	MatchSize                *int
+	ReadyCheckSeconds        *int
+	ReadyPenaltySeconds      *int
+	TeamMode                 *string
+	JoinEnforcement          *string
+	RequireVerified          *bool
+	MinSkillLevel            *int // 0 = sin límite
+	MaxSkillLevel            *int
+	MinElo                   *int
+	MaxElo                   *int
+	LeaveLimit               *int // 0 = sin penalización automática
+	LeaveWindowMinutes       *int
+	LeavePenaltySeconds      *int
+	FlushOnClose             *bool
 }
 
-func (s *PolicyService) GetPolicy(ctx context.Context, guildID string) (storage.GuildPolicy, error) {
-	return s.repo.Get(ctx, guildID)
+func (s *PolicyService) GetPolicy(ctx context.Context, guildID, queue string) (storage.GuildPolicy, error) {
+	return s.repo.Get(ctx, guildID, queue)
 }
 
-func (s *PolicyService) Show(ctx context.Context, guildID string) (string, error) {
-	p, err := s.repo.Get(ctx, guildID)
+func (s *PolicyService) Show(ctx context.Context, guildID, queue string) (string, error) {
+	p, err := s.repo.Get(ctx, guildID, queue)
 	if err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf(
-		"**Policies de %s**\n• require_member: **%v**\n• voice_required: **%v**\n• afk_timeout_seconds: **%d**\n• drop_if_left_minutes: **%d**\n• cooldown_after_loss_seconds: **%d**",
-		guildID, p.RequireMember, p.VoiceRequired, p.AFKTimeoutSeconds, p.DropIfLeftSeconds, p.CooldownAfterLossSeconds,
+		"**Policies de %s · cola %s**\n• require_member: **%v**\n• voice_required: **%v**\n• afk_timeout_seconds: **%d**\n• drop_if_left_minutes: **%d**\n• cooldown_after_loss_seconds: **%d**\n• match_size: **%d**\n• ready_check_seconds: **%d**\n• ready_penalty_seconds: **%d**\n• team_mode: **%s**\n• join_enforcement: **%s**\n• require_verified: **%v**\n• nivel: **%s**\n• elo: **%s**\n• salidas: %s\n• flush_on_close: **%v**",
+		guildID, queue, p.RequireMember, p.VoiceRequired, p.AFKTimeoutSeconds, p.DropIfLeftSeconds, p.CooldownAfterLossSeconds, p.MatchSize,
+		p.ReadyCheckSeconds, p.ReadyPenaltySeconds, p.TeamMode, p.JoinEnforcement, p.RequireVerified,
+		RangeLabel(p.MinSkillLevel, p.MaxSkillLevel), RangeLabel(p.MinElo, p.MaxElo), leaveLabel(p), p.FlushOnClose,
 	), nil
 }
 
-func (s *PolicyService) Update(ctx context.Context, guildID string, patch PolicyPatch) (string, error) {
-	cur, err := s.repo.Get(ctx, guildID)
+func (s *PolicyService) Update(ctx context.Context, guildID, queue string, patch PolicyPatch) (string, error) {
+	cur, err := s.repo.Get(ctx, guildID, queue)
 	if err != nil {
 		return "", err
 	}
@@ -58,9 +74,128 @@ func (s *PolicyService) Update(ctx context.Context, guildID string, patch Policy
 	if patch.CooldownAfterLossSeconds != nil {
 		cur.CooldownAfterLossSeconds = *patch.CooldownAfterLossSeconds
 	}
+	if patch.MatchSize != nil {
+		// dos equipos iguales: tiene que ser par
+		if *patch.MatchSize < 2 || *patch.MatchSize%2 != 0 {
+			return "", fmt.Errorf("match_size debe ser par y >= 2 (recibí %d)", *patch.MatchSize)
+		}
+		cur.MatchSize = *patch.MatchSize
+	}
+	if patch.ReadyCheckSeconds != nil {
+		if *patch.ReadyCheckSeconds < 0 {
+			return "", fmt.Errorf("ready_check_seconds debe ser >= 0 (0 = sin ready-check; recibí %d)", *patch.ReadyCheckSeconds)
+		}
+		cur.ReadyCheckSeconds = *patch.ReadyCheckSeconds
+	}
+	if patch.ReadyPenaltySeconds != nil {
+		if *patch.ReadyPenaltySeconds < 0 {
+			return "", fmt.Errorf("ready_penalty_seconds debe ser >= 0 (recibí %d)", *patch.ReadyPenaltySeconds)
+		}
+		cur.ReadyPenaltySeconds = *patch.ReadyPenaltySeconds
+	}
+	if patch.TeamMode != nil {
+		if *patch.TeamMode != "balance" && *patch.TeamMode != "captains" {
+			return "", fmt.Errorf("team_mode inválido: %q (balance | captains)", *patch.TeamMode)
+		}
+		cur.TeamMode = *patch.TeamMode
+	}
+	if patch.JoinEnforcement != nil {
+		switch *patch.JoinEnforcement {
+		case "warn", "block", "remove":
+		default:
+			return "", fmt.Errorf("join_enforcement inválido: %q (warn | block | remove)", *patch.JoinEnforcement)
+		}
+		cur.JoinEnforcement = *patch.JoinEnforcement
+	}
+	if patch.RequireVerified != nil {
+		cur.RequireVerified = *patch.RequireVerified
+	}
+	if patch.MinSkillLevel != nil {
+		cur.MinSkillLevel = *patch.MinSkillLevel
+	}
+	if patch.MaxSkillLevel != nil {
+		cur.MaxSkillLevel = *patch.MaxSkillLevel
+	}
+	if patch.MinElo != nil {
+		cur.MinElo = *patch.MinElo
+	}
+	if patch.MaxElo != nil {
+		cur.MaxElo = *patch.MaxElo
+	}
+	if patch.LeaveLimit != nil {
+		cur.LeaveLimit = *patch.LeaveLimit
+	}
+	if patch.LeaveWindowMinutes != nil {
+		cur.LeaveWindowMinutes = *patch.LeaveWindowMinutes
+	}
+	if patch.LeavePenaltySeconds != nil {
+		cur.LeavePenaltySeconds = *patch.LeavePenaltySeconds
+	}
+	if patch.FlushOnClose != nil {
+		cur.FlushOnClose = *patch.FlushOnClose
+	}
+	// el draft elige de un select, y Discord no acepta más de 25 opciones (sin contar capitanes)
+	if cur.TeamMode == "captains" && cur.MatchSize > maxDraftMatchSize {
+		return "", fmt.Errorf("con team_mode captains match_size puede ser hasta %d (recibí %d)", maxDraftMatchSize, cur.MatchSize)
+	}
+	if cur.LeaveLimit < 0 || cur.LeaveWindowMinutes < 1 || cur.LeavePenaltySeconds < 0 {
+		return "", fmt.Errorf("leave_limit >= 0, leave_window_minutes >= 1 y leave_penalty_seconds >= 0")
+	}
+	// se valida el rango final (puede venir sólo uno de los dos extremos)
+	for _, lvl := range []int{cur.MinSkillLevel, cur.MaxSkillLevel} {
+		if lvl < 0 || lvl > 10 {
+			return "", fmt.Errorf("nivel inválido: %d (1-10, 0 = sin límite)", lvl)
+		}
+	}
+	if cur.MinElo < 0 || cur.MaxElo < 0 {
+		return "", fmt.Errorf("elo inválido (>= 0, 0 = sin límite)")
+	}
+	if cur.MinSkillLevel > 0 && cur.MaxSkillLevel > 0 && cur.MinSkillLevel > cur.MaxSkillLevel {
+		return "", fmt.Errorf("min_level (%d) es mayor que max_level (%d)", cur.MinSkillLevel, cur.MaxSkillLevel)
+	}
+	if cur.MinElo > 0 && cur.MaxElo > 0 && cur.MinElo > cur.MaxElo {
+		return "", fmt.Errorf("min_elo (%d) es mayor que max_elo (%d)", cur.MinElo, cur.MaxElo)
+	}
 
 	if err := s.repo.Upsert(ctx, cur); err != nil {
 		return "", err
 	}
-	return s.Show(ctx, guildID)
+	return s.Show(ctx, guildID, queue)
+}
+
+// RangeLabel: "1–5", "≥ 5", "≤ 1800" o "sin límite" (0 = extremo abierto)
+func RangeLabel(lo, hi int) string {
+	switch {
+	case lo > 0 && hi > 0:
+		return fmt.Sprintf("%d–%d", lo, hi)
+	case lo > 0:
+		return fmt.Sprintf("≥ %d", lo)
+	case hi > 0:
+		return fmt.Sprintf("≤ %d", hi)
+	}
+	return "sin límite"
+}
+
+// leaveLabel: resumen de la penalización automática por salidas
+func leaveLabel(p storage.GuildPolicy) string {
+	if p.LeaveLimit <= 0 || p.LeavePenaltySeconds <= 0 {
+		return "**sin penalización**"
+	}
+	return fmt.Sprintf("**%d** en **%d min** → cooldown **%ds** (se duplica al reincidir)", p.LeaveLimit, p.LeaveWindowMinutes, p.LeavePenaltySeconds)
+}
+
+// HasSkillGate: la cola filtra por nivel o elo
+func HasSkillGate(p storage.GuildPolicy) bool {
+	return p.MinSkillLevel > 0 || p.MaxSkillLevel > 0 || p.MinElo > 0 || p.MaxElo > 0
+}
+
+// CheckSkillGate: motivo de rechazo si el nivel/elo no entra en el rango de la cola ("" = pasa)
+func CheckSkillGate(p storage.GuildPolicy, level, elo int) string {
+	if (p.MinSkillLevel > 0 && level < p.MinSkillLevel) || (p.MaxSkillLevel > 0 && level > p.MaxSkillLevel) {
+		return fmt.Sprintf("esta cola es para nivel **%s** y tenés nivel **%d**", RangeLabel(p.MinSkillLevel, p.MaxSkillLevel), level)
+	}
+	if (p.MinElo > 0 && elo < p.MinElo) || (p.MaxElo > 0 && elo > p.MaxElo) {
+		return fmt.Sprintf("esta cola es para elo **%s** y tenés **%d** elo", RangeLabel(p.MinElo, p.MaxElo), elo)
+	}
+	return ""
 }
@@ -5,12 +5,14 @@ import (
 	"time"
 
 	"github.com/jose-valero/faceit-queue-bot/internal/domain"
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/faceitevent"
 	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
 )
 
 // Implementado por internal/adapters/faceit.Client
 type FaceitAPI interface {
 	GetPlayerByNickname(ctx context.Context, nick, game string) (*domain.Player, error)
+	GetPlayer(ctx context.Context, playerID, game string) (*domain.Player, error)
 	IsMemberOfHub(ctx context.Context, playerID, hubID string) (bool, error)
 
 	PlayerInOngoingHub(ctx context.Context, playerID, hubID string) (bool, error)
@@ -19,32 +21,185 @@ type FaceitAPI interface {
 
 // Implementado por internal/infra/storage.UserRepo
 type UserRepo interface {
-	GetByDiscordID(ctx context.Context, discordID string) (storage.UserLink, error)
+	GetByDiscordIDInGuild(ctx context.Context, guildID, discordID string) (storage.UserLink, error)
+	GetByFaceitID(ctx context.Context, faceitUserID string) (storage.UserLink, error)
 	UpsertLink(ctx context.Context, ul storage.UserLink) error
 	SoftDeleteByDiscordID(ctx context.Context, discordID, guildID string) (bool, error)
 	FindDiscordByFaceitIDs(ctx context.Context, ids []string) (map[string]string, error)
+	SetVerifyCode(ctx context.Context, faceitUserID, code string, expiresAt time.Time) error
+	MarkVerified(ctx context.Context, faceitUserID, method string) error
+	UpdateSnapshots(ctx context.Context, faceitUserID string, elo, skill int) error
+	UpdateMembershipByFaceitID(ctx context.Context, faceitUserID string, isMember bool) error
+}
+
+// Implementado por internal/adapters/faceit.OAuthClient
+type FaceitOAuth interface {
+	AuthCodeURL(state, codeChallenge string) string
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	UserInfo(ctx context.Context, accessToken string) (*domain.Player, error)
+}
+
+// Implementado por internal/infra/storage.OAuthStateRepo
+type OAuthStateRepo interface {
+	Create(ctx context.Context, st storage.OAuthState) error
+	Consume(ctx context.Context, state string) (storage.OAuthState, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Implementado por internal/infra/storage.GuildSettingsRepo
+type GuildSettingsRepo interface {
+	Get(ctx context.Context, guildID string) (storage.GuildSettings, error)
+	Upsert(ctx context.Context, g storage.GuildSettings) error
+	ListHubs(ctx context.Context, guildID string) ([]storage.GuildHub, error)
+	AddHub(ctx context.Context, h storage.GuildHub) error
+	RemoveHub(ctx context.Context, guildID, hubID string) (bool, error)
+	GuildForHub(ctx context.Context, hubID string) (string, error)
+	ListPriorityRoles(ctx context.Context, guildID string) ([]storage.PriorityRole, error)
+	SetPriorityRole(ctx context.Context, pr storage.PriorityRole) error
+	RemovePriorityRole(ctx context.Context, guildID, roleID string) (bool, error)
+}
+
+// Implementado por internal/adapters/faceit.Client
+type HubLookup interface {
+	GetHub(ctx context.Context, hubID string) (*domain.Hub, error)
+}
+
+// Implementado por GuildSettingsService: config por guild que usan los services
+type GuildConfig interface {
+	HubIDs(ctx context.Context, guildID string) []string
+	GuildForHub(ctx context.Context, hubID string) (string, bool)
+	MaxQueues(ctx context.Context, guildID string) int
+	MaxPartySize(ctx context.Context, guildID string) int
+	Priority(ctx context.Context, guildID string, roles []string) int
+	Location(ctx context.Context, guildID string) *time.Location
+}
+
+// Implementado por internal/infra/storage.ScheduleRepo
+type ScheduleRepo interface {
+	List(ctx context.Context, guildID, queue string) ([]storage.QueueSchedule, error)
+	Add(ctx context.Context, guildID, queue string, days []time.Weekday, open, close int) error
+	Clear(ctx context.Context, guildID, queue string) (int64, error)
+}
+
+// Implementado por internal/adapters/discord.Members
+type MemberRoles interface {
+	Roles(guildID, discordUserID string) []string
+}
+
+// Implementado por internal/infra/storage.PartyRepo
+type PartyRepo interface {
+	Create(ctx context.Context, guildID, leaderID string) (storage.Party, error)
+	Get(ctx context.Context, partyID int64) (storage.Party, error)
+	Of(ctx context.Context, guildID, discordID string) (storage.Party, error)
+	Invite(ctx context.Context, partyID int64, guildID, discordID, invitedBy string) (bool, error)
+	Accept(ctx context.Context, partyID int64, discordID string) (bool, error)
+	RemoveMember(ctx context.Context, partyID int64, discordID string) (bool, error)
+	SetLeader(ctx context.Context, partyID int64, discordID string) error
+	Disband(ctx context.Context, partyID int64) error
 }
 
 // Implementado por internal/infra/storage.QueueRepo
 type QueueRepo interface {
-	Join(ctx context.Context, e storage.QueueEntry) error
-	Leave(ctx context.Context, guildID, discordID string) (bool, error)
-	List(ctx context.Context, guildID string, limit int) ([]storage.QueueEntry, error)
+	Join(ctx context.Context, e storage.QueueEntry) (time.Time, error)
+	Add(ctx context.Context, e storage.QueueEntry, actorID string) error
+	Leave(ctx context.Context, guildID, queue, discordID string) (bool, error)
+	Kick(ctx context.Context, guildID, queue, discordID, actorID, reason string) (bool, error)
+	LeaveParty(ctx context.Context, guildID, queue string, partyID int64, actorID string) (int, error)
+	Clear(ctx context.Context, guildID, queue, actorID, reason string) ([]string, error)
+	Move(ctx context.Context, guildID, queue, discordID string, priority int, joinedAt time.Time, actorID, reason string) (bool, error)
+	Swap(ctx context.Context, guildID, queue, a, b, actorID string) (bool, error)
+	Lock(ctx context.Context, guildID, queue, actorID, reason string) (bool, error)
+	Unlock(ctx context.Context, guildID, queue, actorID string) (bool, error)
+	Locked(ctx context.Context, guildID, queue string) (storage.QueueLock, bool, error)
+	List(ctx context.Context, guildID, queue string, limit int) ([]storage.QueueEntry, error)
+	QueuesOf(ctx context.Context, guildID, discordID string) ([]storage.QueueEntry, error)
 
-	TouchValid(ctx context.Context, guildID, discordID string) error
-	MarkLeft(ctx context.Context, guildID, discordID string) error
-	MarkAFK(ctx context.Context, guildID, discordID string) error
+	TouchValid(ctx context.Context, guildID, queue, discordID string) error
+	MarkLeft(ctx context.Context, guildID, queue, discordID, reason string) error
+	MarkAFK(ctx context.Context, guildID, queue, discordID, reason string) error
+	Block(ctx context.Context, guildID, queue, discordID, reason string) error
+	Validated(ctx context.Context, guildID, queue, discordID string, until time.Time) error
 
-	Exists(ctx context.Context, guildID, discordID string) (bool, error)
+	Exists(ctx context.Context, guildID, queue, discordID string) (bool, error)
+	Position(ctx context.Context, guildID, queue, discordID string) (storage.QueueEntry, int, int, bool, error)
+	CountEvents(ctx context.Context, guildID, queue, event string, within time.Duration) (int, error)
+	ListEvents(ctx context.Context, guildID, discordID string, limit int) ([]storage.QueueEvent, error)
 
-	// Prune con “tiempos de gracia” para AFK/LEFT
-	Prune(ctx context.Context, guildID string, afkTimeout, leftTimeout time.Duration) (int64, int64, error)
+	// Prune con “tiempos de gracia” para AFK/LEFT; devuelve a quiénes sacó por afk y por left
+	Prune(ctx context.Context, guildID, queue string, afkTimeout, leftTimeout time.Duration) ([]string, []string, error)
+	CountOffenses(ctx context.Context, guildID, queue, discordID string, since time.Time, churn time.Duration) (int, error)
 	// LEFT/AFK con tiempos de gracia
-	ListWithGrace(ctx context.Context, guildID string, limit int, graceAFK, graceLeft time.Duration) ([]storage.QueueEntry, error)
+	ListWithGrace(ctx context.Context, guildID, queue string, limit int, graceAFK, graceLeft time.Duration) ([]storage.QueueEntry, error)
 }
 
-// Implementado por internal/infra/storage.PolicyRepo
+// Implementado por internal/infra/storage.PolicyRepo (una policy por cola)
 type PolicyRepo interface {
-	Get(ctx context.Context, guildID string) (storage.GuildPolicy, error)
+	Get(ctx context.Context, guildID, queue string) (storage.GuildPolicy, error)
 	Upsert(ctx context.Context, p storage.GuildPolicy) error
 }
+
+// Implementado por internal/infra/storage.PendingMatchRepo
+type MatchRepo interface {
+	Pop(ctx context.Context, guildID, queue string, size int, readyDeadline *time.Time) (storage.PendingMatch, error)
+	Get(ctx context.Context, id int64) (storage.PendingMatch, error)
+	Confirm(ctx context.Context, matchID int64, from string, players []storage.PendingMatchPlayer) (bool, error)
+	SetMessage(ctx context.Context, matchID int64, channelID, messageID string) error
+	SetStatus(ctx context.Context, matchID int64, from, to string) (bool, error)
+
+	// ready-check
+	SetReadyState(ctx context.Context, matchID int64, discordID, state string) (bool, error)
+	ListExpiredReadyChecks(ctx context.Context) ([]int64, error)
+	FailReadyCheck(ctx context.Context, matchID int64) (storage.PendingMatch, bool, error)
+
+	// draft de capitanes
+	StartDraft(ctx context.Context, matchID int64, from, captain1, captain2 string, players []storage.PendingMatchPlayer, pickDeadline time.Time) (bool, error)
+	AddPick(ctx context.Context, pk storage.DraftPick, nextDeadline time.Time) (bool, error)
+	ListExpiredDrafts(ctx context.Context) ([]int64, error)
+	SetDraftMessage(ctx context.Context, matchID int64, channelID, messageID string) error
+}
+
+// Implementado por internal/infra/storage.PenaltyRepo
+type PenaltyRepo interface {
+	Add(ctx context.Context, p storage.QueuePenalty) error
+	Active(ctx context.Context, guildID, discordID string) (storage.QueuePenalty, bool, error)
+	LastOf(ctx context.Context, guildID, discordID, kind string) (storage.QueuePenalty, bool, error)
+	CountSince(ctx context.Context, guildID, discordID, kind string, since time.Time) (int, error)
+	History(ctx context.Context, guildID, discordID string, limit int) ([]storage.QueuePenalty, error)
+}
+
+// Implementado por internal/infra/storage.BanRepo
+type BanRepo interface {
+	Add(ctx context.Context, b storage.QueueBan) (storage.QueueBan, error)
+	Active(ctx context.Context, guildID, discordID string) (storage.QueueBan, bool, error)
+	ListActive(ctx context.Context, guildID string) ([]storage.QueueBan, error)
+	Revoke(ctx context.Context, guildID, discordID, revokedBy string) (int64, error)
+	CountSince(ctx context.Context, guildID, discordID string, since time.Time) (int, error)
+}
+
+// Implementado por internal/infra/storage.WebhookEventRepo
+type WebhookEventRepo interface {
+	Get(ctx context.Context, id int64) (storage.WebhookEvent, error)
+	ListPending(ctx context.Context, limit int) ([]storage.WebhookEvent, error)
+	MarkProcessed(ctx context.Context, id int64) error
+	HasNewerMatchStatus(ctx context.Context, id int64, matchID string) (bool, error)
+	UpsertMatchStatus(ctx context.Context, matchID, hubID, status, demoURL string, at time.Time) error
+}
+
+// Implementado por LinkService
+type MemberEventHandler interface {
+	HandleMemberEvent(ctx context.Context, m faceitevent.MemberEvent) error
+}
+
+// Implementado por MatchRoomsService
+type MatchEventHandler interface {
+	HandleMatchEvent(ctx context.Context, matchID, status string) error
+}
+
+// Implementado por internal/infra/storage.WebhookFailureRepo
+type WebhookFailureRepo interface {
+	Get(ctx context.Context, eventID int64, source string) (storage.WebhookFailure, error)
+	Record(ctx context.Context, f storage.WebhookFailure) error
+	Due(ctx context.Context, source string, limit int) ([]storage.WebhookFailure, error)
+	ListOpen(ctx context.Context, hubIDs []string, limit int) ([]storage.WebhookFailure, error)
+	Resolve(ctx context.Context, eventID int64, source string) error
+}
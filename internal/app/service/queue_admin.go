@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// Operaciones de admin sobre la cola (/queue admin ...). Todas dejan su evento en
+// queue_events con el admin como actor, así /queue history muestra quién hizo qué.
+
+// AdminAdd: mete a un jugador sin pasar por los chequeos de Join (ban, cooldown, rango,
+// lock); sólo hace falta que tenga la cuenta vinculada. Entra solo, sin su party.
+func (s *QueueService) AdminAdd(ctx context.Context, guildID, queue, discordID, actorID string) (string, error) {
+	ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err != nil {
+		return fmt.Sprintf("❌ <@%s> no tiene la cuenta de FACEIT vinculada.", discordID), nil
+	}
+	if in, err := s.queue.Exists(ctx, guildID, queue, discordID); err != nil {
+		return "", err
+	} else if in {
+		return fmt.Sprintf("ℹ️ <@%s> ya está en %s.", discordID, queueLabel(queue)), nil
+	}
+	if err := s.queue.Add(ctx, storage.QueueEntry{
+		GuildID:       guildID,
+		QueueName:     queue,
+		DiscordUserID: discordID,
+		FaceitUserID:  ul.FaceitUserID,
+		Nickname:      ul.Nickname,
+		Status:        "waiting",
+		Priority:      s.priorityOf(ctx, guildID, discordID),
+	}, actorID); err != nil {
+		return "", err
+	}
+	log.Printf("[queue.admin] add guild=%s queue=%s user=%s by=%s", guildID, queue, discordID, actorID)
+	s.notify(guildID, discordID, "➕ Un admin te agregó a "+queueLabel(queue)+".")
+	return fmt.Sprintf("✅ <@%s> agregado a %s.", discordID, queueLabel(queue)), nil
+}
+
+// Move: pone al jugador en el puesto `pos` (1-based, entre los que esperan). Toma la
+// prioridad de quien ocupa ese puesto y un joined_at pegado al suyo, así el orden de la
+// cola (prioridad + aging, joined_at) lo deja justo ahí.
+func (s *QueueService) Move(ctx context.Context, guildID, queue, discordID string, pos int, actorID string) (string, error) {
+	if pos < 1 {
+		return "", fmt.Errorf("la posición arranca en 1")
+	}
+	items, err := s.queue.List(ctx, guildID, queue, 500)
+	if err != nil {
+		return "", err
+	}
+	others := make([]storage.QueueEntry, 0, len(items))
+	found := false
+	for _, e := range items {
+		if e.DiscordUserID == discordID {
+			found = true
+			continue
+		}
+		others = append(others, e)
+	}
+	if !found {
+		return fmt.Sprintf("ℹ️ <@%s> no está esperando en %s (afk/left no tienen puesto).", discordID, queueLabel(queue)), nil
+	}
+	if len(others) == 0 {
+		return fmt.Sprintf("ℹ️ <@%s> es el único en %s.", discordID, queueLabel(queue)), nil
+	}
+
+	var priority int
+	var joinedAt time.Time
+	if pos <= len(others) {
+		t := others[pos-1] // queda justo antes del que hoy ocupa el puesto
+		priority, joinedAt = t.Priority, t.JoinedAt.Add(-time.Millisecond)
+	} else {
+		pos = len(others) + 1 // al final
+		t := others[len(others)-1]
+		priority, joinedAt = t.Priority, t.JoinedAt.Add(time.Millisecond)
+	}
+	ok, err := s.queue.Move(ctx, guildID, queue, discordID, priority, joinedAt, actorID, fmt.Sprintf("al puesto #%d", pos))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("ℹ️ <@%s> ya no está en %s.", discordID, queueLabel(queue)), nil
+	}
+	log.Printf("[queue.admin] move guild=%s queue=%s user=%s pos=%d by=%s", guildID, queue, discordID, pos, actorID)
+	return fmt.Sprintf("↕️ <@%s> movido al puesto **#%d** de %s.", discordID, pos, queueLabel(queue)), nil
+}
+
+// Swap: intercambia los lugares de dos jugadores
+func (s *QueueService) Swap(ctx context.Context, guildID, queue, a, b, actorID string) (string, error) {
+	if a == b {
+		return "", fmt.Errorf("elegí dos jugadores distintos")
+	}
+	ok, err := s.queue.Swap(ctx, guildID, queue, a, b, actorID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("ℹ️ <@%s> y <@%s> tienen que estar los dos en %s.", a, b, queueLabel(queue)), nil
+	}
+	log.Printf("[queue.admin] swap guild=%s queue=%s a=%s b=%s by=%s", guildID, queue, a, b, actorID)
+	return fmt.Sprintf("🔀 <@%s> y <@%s> intercambiaron lugares en %s.", a, b, queueLabel(queue)), nil
+}
+
+// Clear: vacía la cola entera (avisa a cada uno por DM)
+func (s *QueueService) Clear(ctx context.Context, guildID, queue, actorID string) (string, error) {
+	ids, err := s.queue.Clear(ctx, guildID, queue, actorID, "vaciada por un admin")
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "ℹ️ No había nadie en " + queueLabel(queue) + ".", nil
+	}
+	log.Printf("[queue.admin] clear guild=%s queue=%s n=%d by=%s", guildID, queue, len(ids), actorID)
+	for _, id := range ids {
+		s.notify(guildID, id, "🧹 Un admin vació "+queueLabel(queue)+".")
+	}
+	return fmt.Sprintf("🧹 Vacié %s (%d jugador(es)).", queueLabel(queue), len(ids)), nil
+}
+
+// Lock: nadie nuevo puede unirse (los que están siguen y el pop sigue andando)
+func (s *QueueService) Lock(ctx context.Context, guildID, queue, actorID, reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	ok, err := s.queue.Lock(ctx, guildID, queue, actorID, reason)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "ℹ️ Nada que hacer: " + queueLabel(queue) + " ya estaba cerrada.", nil
+	}
+	log.Printf("[queue.admin] lock guild=%s queue=%s by=%s reason=%q", guildID, queue, actorID, reason)
+	return "🔒 Cerré " + queueLabel(queue) + ": nadie nuevo puede unirse hasta `/queue admin unlock`.", nil
+}
+
+func (s *QueueService) Unlock(ctx context.Context, guildID, queue, actorID string) (string, error) {
+	ok, err := s.queue.Unlock(ctx, guildID, queue, actorID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "ℹ️ Nada que hacer: " + queueLabel(queue) + " no estaba cerrada.", nil
+	}
+	log.Printf("[queue.admin] unlock guild=%s queue=%s by=%s", guildID, queue, actorID)
+	return "🔓 Abrí " + queueLabel(queue) + ".", nil
+}
+
+// Locked: lock vigente de la cola (para el Join y el embed); si falla la DB, abierta
+func (s *QueueService) Locked(ctx context.Context, guildID, queue string) (storage.QueueLock, bool) {
+	l, ok, err := s.queue.Locked(ctx, guildID, queue)
+	if err != nil {
+		log.Printf("[queue] locked guild=%s queue=%s: %v", guildID, queue, err)
+		return storage.QueueLock{}, false
+	}
+	return l, ok
+}
+
+// lockedMessage: motivo para el rechazo del join
+func lockedMessage(queue string, l storage.QueueLock) string {
+	msg := "🔒 Un admin cerró " + queueLabel(queue) + "."
+	if l.Reason != "" {
+		msg += " Motivo: " + l.Reason + "."
+	}
+	return msg
+}
@@ -3,7 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
-	"sort"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
@@ -15,27 +16,59 @@ type QueueItemRich struct {
 	FaceitUserID  string
 	Nickname      string
 	Status        string
-	SkillLevel    *int // snapshot; puede ser nil
+	StatusReason  string // motivo si está blocked
+	SkillLevel    *int   // snapshot; puede ser nil
+	PartyID       int64  // 0 = solo
+	Priority      int    // > 0 = prioridad por rol (⭐ en el embed)
 	JoinedAt      time.Time
 	LastSeenAt    time.Time
 }
 
 type Notifier interface {
-	// Opcional: si lo seteas, podés mandar un DM o mensaje en canal
+	// Implementado por internal/adapters/discord.Notifier (DM con fallback a canal)
 	Notify(guildID, discordUserID, msg string)
+	NotifyThrottled(guildID, discordUserID, msg string) // acuses de join: se descartan si vienen muy seguidos
 }
 
 type QueueService struct {
-	users    UserRepo
-	queue    QueueRepo
-	policy   PolicyRepo
-	fc       FaceitAPI
-	hubID    string
-	notifier Notifier
+	users     UserRepo
+	queue     QueueRepo
+	policy    PolicyRepo
+	penalties PenaltyRepo
+	bans      BanRepo
+	parties   PartyRepo
+	fc        FaceitAPI
+	guilds    GuildConfig
+	notifier  Notifier
+	onChange  func(guildID, queue string) // la validación async terminó o tocó la cola: repintar y probar el pop
+
+	reliability *PenaltyService  // penalización automática por salidas; nil = apagada
+	roles       MemberRoles      // para la prioridad por rol; nil = todos iguales
+	schedules   *ScheduleService // horarios de apertura; nil = siempre abierta
+}
+
+// EnableSchedules: fuera de horario Join rechaza
+func (s *QueueService) EnableSchedules(sch *ScheduleService) { s.schedules = sch }
+
+// EnablePriority: la prioridad de cada entrada sale de sus roles al momento de entrar
+func (s *QueueService) EnablePriority(roles MemberRoles) { s.roles = roles }
+
+// priorityOf: peso del jugador según sus roles (0 sin prioridad)
+func (s *QueueService) priorityOf(ctx context.Context, guildID, discordID string) int {
+	if s.roles == nil {
+		return 0
+	}
+	return s.guilds.Priority(ctx, guildID, s.roles.Roles(guildID, discordID))
 }
 
-func (s *QueueService) ListRich(ctx context.Context, guildID string, limit int) ([]QueueItemRich, error) {
-	base, err := s.queue.List(ctx, guildID, limit)
+// EnableReliability: evalúa la penalización por salidas después de cada leave/prune
+func (s *QueueService) EnableReliability(p *PenaltyService) { s.reliability = p }
+
+// OnChange: callback para cuando la cola cambia fuera de una interacción (p.ej. enforcement async)
+func (s *QueueService) OnChange(fn func(guildID, queue string)) { s.onChange = fn }
+
+func (s *QueueService) ListRich(ctx context.Context, guildID, queue string, limit int) ([]QueueItemRich, error) {
+	base, err := s.queue.List(ctx, guildID, queue, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -46,10 +79,13 @@ func (s *QueueService) ListRich(ctx context.Context, guildID string, limit int)
 			FaceitUserID:  it.FaceitUserID,
 			Nickname:      it.Nickname,
 			Status:        it.Status,
+			StatusReason:  it.StatusReason,
+			PartyID:       it.PartyID,
+			Priority:      it.Priority,
 			JoinedAt:      it.JoinedAt,
 			LastSeenAt:    it.LastSeenAt,
 		}
-		if ul, err := s.users.GetByDiscordID(ctx, it.DiscordUserID); err == nil {
+		if ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, it.DiscordUserID); err == nil {
 			qi.SkillLevel = ul.SkillLevelSnapshot
 			if ul.Nickname != "" {
 				qi.Nickname = ul.Nickname
@@ -57,89 +93,287 @@ func (s *QueueService) ListRich(ctx context.Context, guildID string, limit int)
 		}
 		out = append(out, qi)
 	}
-	// por si acaso
-	sort.SliceStable(out, func(i, j int) bool { return out[i].JoinedAt.Before(out[j].JoinedAt) })
-	return out, nil
+	return GroupByParty(out), nil
+}
+
+// GroupByParty: respeta el orden de la DB (prioridad, joined_at) pero deja juntos a los de
+// una misma party, en el lugar del primero de ellos
+func GroupByParty(items []QueueItemRich) []QueueItemRich {
+	out := make([]QueueItemRich, 0, len(items))
+	placed := make(map[int64]bool)
+	for i, it := range items {
+		if it.PartyID == 0 {
+			out = append(out, it)
+			continue
+		}
+		if placed[it.PartyID] {
+			continue
+		}
+		placed[it.PartyID] = true
+		for _, other := range items[i:] {
+			if other.PartyID == it.PartyID {
+				out = append(out, other)
+			}
+		}
+	}
+	return out
 }
 
-func NewQueueService(fc FaceitAPI, users UserRepo, queue QueueRepo, policy PolicyRepo, hubID string) *QueueService {
-	return &QueueService{fc: fc, users: users, queue: queue, policy: policy, hubID: hubID}
+func NewQueueService(fc FaceitAPI, users UserRepo, queue QueueRepo, policy PolicyRepo, penalties PenaltyRepo, bans BanRepo, parties PartyRepo, notifier Notifier, guilds GuildConfig) *QueueService {
+	return &QueueService{fc: fc, users: users, queue: queue, policy: policy, penalties: penalties, bans: bans, parties: parties, notifier: notifier, guilds: guilds}
 }
 
-func (s *QueueService) Join(ctx context.Context, guildID, discordID string) (string, error) {
-	// 1) Link debe existir (DB local, rápido)
-	ul, err := s.users.GetByDiscordID(ctx, discordID)
-	if err != nil {
-		return "❌ No estás vinculado. Usa `/link nick:<tu_nick_FACEIT>`", nil
+// NormalizeQueueName: nombre canónico de una cola ("" = la principal).
+// Va en los custom IDs de los botones, así que lo mantenemos corto y sin ':'.
+func NormalizeQueueName(raw string) (string, error) {
+	name := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if name == "" {
+		return storage.DefaultQueue, nil
+	}
+	if len(name) > 32 || strings.Contains(name, ":") {
+		return "", fmt.Errorf("nombre de cola inválido %q (máx 32 caracteres, sin ':')", raw)
+	}
+	return name, nil
+}
+
+// queueLabel: "la cola" para la principal, "la cola **x**" para las demás (para los mensajes)
+func queueLabel(queue string) string {
+	if queue == "" || queue == storage.DefaultQueue {
+		return "la cola"
+	}
+	return "la cola **" + queue + "**"
+}
+
+// Join: anota al jugador (y a su party, si tiene) en la cola. Los chequeos rápidos van acá;
+// los que dependen de FACEIT (hub, derrota reciente, membresía) van async en validateJoinAsync.
+func (s *QueueService) Join(ctx context.Context, guildID, queue, discordID string) (string, error) {
+	if s.schedules != nil {
+		if st := s.schedules.State(ctx, guildID, queue); !st.Open {
+			return st.ClosedMessage(queue), nil
+		}
+	}
+	if l, locked := s.Locked(ctx, guildID, queue); locked {
+		return lockedMessage(queue, l), nil
+	}
+	pol, polErr := s.policy.Get(ctx, guildID, queue)
+
+	// la party entra entera o no entra
+	members, partyID := []string{discordID}, int64(0)
+	if s.parties != nil {
+		if p, err := s.parties.Of(ctx, guildID, discordID); err == nil && len(p.Accepted()) > 1 {
+			members, partyID = p.Accepted(), p.ID
+		}
+	}
+	if partyID != 0 {
+		size := pol.MatchSize
+		if size <= 0 {
+			size = defaultMatchSize
+		}
+		if len(members) > size/2 {
+			return fmt.Sprintf("⛔ Tu party (%d) no entra en un equipo de %s (máximo %d por equipo).", len(members), queueLabel(queue), size/2), nil
+		}
+	}
+
+	links := make([]storage.UserLink, 0, len(members))
+	already := false
+	for _, id := range members {
+		ul, in, reject, err := s.admit(ctx, guildID, queue, pol, polErr == nil, id)
+		if err != nil {
+			return "", err
+		}
+		if reject != "" {
+			if id != discordID {
+				return fmt.Sprintf("⛔ Tu party no puede unirse por <@%s>:\n%s", id, reject), nil
+			}
+			return reject, nil
+		}
+		if id == discordID {
+			already = in
+		}
+		links = append(links, ul)
 	}
 
 	// 2) Escribir en cola YA (no bloqueamos por redes externas)
-	already, _ := s.queue.Exists(ctx, guildID, discordID)
-	if err := s.queue.Join(ctx, storage.QueueEntry{
-		GuildID:       guildID,
-		DiscordUserID: discordID,
-		FaceitUserID:  ul.FaceitUserID,
-		Nickname:      ul.Nickname,
-		Status:        "waiting",
-	}); err != nil {
-		return "", err
+	windows := make([]time.Time, len(links)) // la ventana de validación de cada join
+	for i, ul := range links {
+		until, err := s.queue.Join(ctx, storage.QueueEntry{
+			GuildID:       guildID,
+			QueueName:     queue,
+			DiscordUserID: ul.DiscordUserID,
+			FaceitUserID:  ul.FaceitUserID,
+			Nickname:      ul.Nickname,
+			Status:        "waiting",
+			PartyID:       partyID,
+			Priority:      s.priorityOf(ctx, guildID, ul.DiscordUserID),
+		})
+		if err != nil {
+			return "", err
+		}
+		windows[i] = until
 	}
 
 	// 3) Disparar validación en background (no bloquea UX)
-	go s.validateJoinAsync(guildID, ul)
+	for i, ul := range links {
+		go s.validateJoinAsync(guildID, queue, ul, windows[i])
+	}
+	for _, id := range members {
+		if id != discordID {
+			s.notifyJoin(guildID, id, fmt.Sprintf("👥 <@%s> anotó a tu party en %s.", discordID, queueLabel(queue)))
+		}
+	}
 
 	// 4) Responder rápido
+	if partyID != 0 {
+		return fmt.Sprintf("✅ Tu party (%d) se unió a %s. (validando requisitos…)", len(members), queueLabel(queue)), nil
+	}
 	if already {
-		return fmt.Sprintf("🟡 Ya estabas en la cola, actualicé tu estado: **%s**.", ul.Nickname), nil
+		return fmt.Sprintf("🟡 Ya estabas en %s, actualicé tu estado: **%s**.", queueLabel(queue), links[0].Nickname), nil
 	}
-	return fmt.Sprintf("✅ %s te uniste a la cola. (validando requisitos…)", ul.Nickname), nil
+	return fmt.Sprintf("✅ %s te uniste a %s. (validando requisitos…)", links[0].Nickname, queueLabel(queue)), nil
+}
+
+// admit: chequeos sincrónicos para un jugador. reject != "" es el motivo para mostrarle;
+// already dice si ya estaba en esta cola.
+func (s *QueueService) admit(ctx context.Context, guildID, queue string, pol storage.GuildPolicy, polOK bool, discordID string) (ul storage.UserLink, already bool, reject string, err error) {
+	// 1) Link debe existir (DB local, rápido)
+	ul, err = s.users.GetByDiscordIDInGuild(ctx, guildID, discordID)
+	if err != nil {
+		return ul, false, "❌ No estás vinculado. Usa `/link nick:<tu_nick_FACEIT>`", nil
+	}
+
+	// 1.2) cuenta verificada (oauth o código) si la policy lo exige
+	if polOK && pol.RequireVerified && ul.VerifiedAt == nil {
+		return ul, false, "🔐 Este servidor exige una cuenta FACEIT **verificada**. Usa `/link` y seguí los pasos para verificarla.", nil
+	}
+
+	// 1.3) rango de nivel/elo de la cola: va sincrónico para que el jugador vea el motivo en la respuesta
+	if polOK && HasSkillGate(pol) {
+		level, elo, ok := s.skillSnapshot(ctx, ul)
+		if !ok {
+			return ul, false, "⚠️ No pude leer tu nivel de FACEIT para validar el rango de " + queueLabel(queue) + ". Probá de nuevo en un rato.", nil
+		}
+		if why := CheckSkillGate(pol, level, elo); why != "" {
+			return ul, false, "⛔ No podés unirte: " + why + ".", nil
+		}
+	}
+
+	// 1.4) ban puesto por un admin
+	if s.bans != nil {
+		if b, ok, err := s.bans.Active(ctx, guildID, discordID); err != nil {
+			return ul, false, "", err
+		} else if ok {
+			return ul, false, "⛔ Estás baneado de las colas (" + b.Reason + "). " + banExpiry(b) + ".", nil
+		}
+	}
+
+	// 1.5) penalización vigente (ready-check declinado, etc.)
+	if s.penalties != nil {
+		if p, ok, err := s.penalties.Active(ctx, guildID, discordID); err == nil && ok {
+			return ul, false, fmt.Sprintf("⛔ Tenés una penalización activa (%s). Podés volver <t:%d:R>.", p.Reason, p.ExpiresAt.Unix()), nil
+		}
+	}
+
+	// 1.7) tope de colas simultáneas (configurable por guild; default 1)
+	entries, err := s.queue.QueuesOf(ctx, guildID, discordID)
+	if err != nil {
+		return ul, false, "", err
+	}
+	others := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.QueueName == queue {
+			already = true
+			continue
+		}
+		others = append(others, "**"+e.QueueName+"**")
+	}
+	if max := s.guilds.MaxQueues(ctx, guildID); !already && len(entries) >= max {
+		return ul, false, fmt.Sprintf("⛔ Ya estás en %d cola(s) (%s) y el máximo es **%d**. Salí de alguna para unirte a esta.",
+			len(entries), strings.Join(others, ", "), max), nil
+	}
+	return ul, already, "", nil
+}
+
+// snapshotMaxAge: pasado esto, el elo/nivel guardado se refresca contra FACEIT antes de usarlo en un filtro
+const snapshotMaxAge = time.Hour
+
+// skillSnapshot: nivel y elo del jugador; refresca el snapshot si está viejo.
+// Si FACEIT no responde usamos el snapshot que haya (viejo es mejor que nada).
+func (s *QueueService) skillSnapshot(ctx context.Context, ul storage.UserLink) (level, elo int, ok bool) {
+	fresh := ul.SnapshotAt != nil && time.Since(*ul.SnapshotAt) < snapshotMaxAge &&
+		ul.SkillLevelSnapshot != nil && ul.EloSnapshot != nil
+	if !fresh && ul.Nickname != "" {
+		fctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		p, err := s.fc.GetPlayerByNickname(fctx, ul.Nickname, "cs2")
+		cancel()
+		if err == nil {
+			if err := s.users.UpdateSnapshots(ctx, ul.FaceitUserID, p.Elo, p.Skill); err != nil {
+				log.Printf("[queue] snapshot user=%s: %v", ul.DiscordUserID, err)
+			}
+			return p.Skill, p.Elo, true
+		}
+		log.Printf("[queue] refresh snapshot user=%s: %v", ul.DiscordUserID, err)
+	}
+	if ul.SkillLevelSnapshot == nil || ul.EloSnapshot == nil {
+		return 0, 0, false
+	}
+	return *ul.SkillLevelSnapshot, *ul.EloSnapshot, true
 }
 
 // --- validación asíncrona post-join ---
-func (s *QueueService) validateJoinAsync(guildID string, ul storage.UserLink) {
+func (s *QueueService) validateJoinAsync(guildID, queue string, ul storage.UserLink, until time.Time) {
+	// al terminar (pase o no) el pop ya puede tomarlo, o ya no está: el enforce corre antes
+	defer s.validated(guildID, queue, ul.DiscordUserID, until)
+
 	// límites agresivos: no queremos bloquear nada largo en background
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
 	// policy (si falla, usa defaults)
-	pol, _ := s.policy.Get(ctx, guildID)
+	pol, _ := s.policy.Get(ctx, guildID, queue)
+	hubIDs := s.guilds.HubIDs(ctx, guildID)
 	cd := time.Duration(pol.CooldownAfterLossSeconds) * time.Second
 	if cd <= 0 {
 		cd = 2 * time.Minute
 	}
 
 	// 1) match en curso en el hub → fuera
-	if ok, err := s.fc.PlayerInOngoingHub(ctx, ul.FaceitUserID, s.hubID); err == nil && ok {
-		// _ = s.queue.Leave(context.Background(), guildID, ul.DiscordUserID)
-		s.notify(guildID, ul.DiscordUserID, "⛔ No puedes unirte: estás en una **partida activa del hub**.")
-		return
+	if len(hubIDs) == 0 {
+		// guild sin hub configurado: no hay contra qué validar partida en curso ni membresía
+		log.Printf("[queue] guild=%s sin hub FACEIT; salteo validaciones de hub", guildID)
+	}
+	for _, hubID := range hubIDs {
+		if ok, err := s.fc.PlayerInOngoingHub(ctx, ul.FaceitUserID, hubID); err == nil && ok {
+			s.enforce(guildID, queue, ul.DiscordUserID, pol.JoinEnforcement, "partida activa en el hub",
+				"⛔ No puedes unirte: estás en una **partida activa del hub**.")
+			return
+		}
 	}
 
 	// 2) cooldown por última derrota → fuera si no cumplió
 	if lost, endedAt, err := s.fc.LastMatchLossWithin(ctx, ul.FaceitUserID, "cs2", cd); err == nil && lost {
 		wait := time.Until(endedAt.Add(cd))
 		if wait > 0 {
-			// _ = s.queue.Leave(context.Background(), guildID, ul.DiscordUserID)
-			s.notify(guildID, ul.DiscordUserID,
+			s.enforce(guildID, queue, ul.DiscordUserID, pol.JoinEnforcement, fmt.Sprintf("cooldown por derrota hasta <t:%d:t>", endedAt.Add(cd).Unix()),
 				fmt.Sprintf("⌛ Acabas de **perder** una partida. Debes esperar **%d s** para unirte.", int(wait.Seconds())))
 			return
 		}
 	}
 
 	// 3) membresía si la policy lo exige (refresca snapshots si está “stale”)
-	if pol.RequireMember {
+	if pol.RequireMember && len(hubIDs) > 0 {
 		stale := ul.MemberCheckedAt == nil || time.Since(*ul.MemberCheckedAt) > 10*time.Minute
 		if stale {
-			if ok, err := s.fc.IsMemberOfHub(ctx, ul.FaceitUserID, s.hubID); err == nil {
+			if ok, err := memberOfAnyHub(ctx, s.fc, ul.FaceitUserID, hubIDs); err == nil {
 				now := time.Now()
-				var eloPtr, skillPtr *int
+				// sin refresh se conservan los snapshots que había (UpsertLink pisa todo)
+				eloPtr, skillPtr, snapAt := ul.EloSnapshot, ul.SkillLevelSnapshot, ul.SnapshotAt
 				// snapshots si están nulos o vencidos (>24h)
 				snapStale := ul.EloSnapshot == nil || ul.SkillLevelSnapshot == nil ||
 					(ul.MemberCheckedAt != nil && time.Since(*ul.MemberCheckedAt) > 24*time.Hour)
 				if snapStale {
 					if p, e2 := s.fc.GetPlayerByNickname(ctx, ul.Nickname, "cs2"); e2 == nil {
 						elo, skill := p.Elo, p.Skill
-						eloPtr, skillPtr = &elo, &skill
+						eloPtr, skillPtr, snapAt = &elo, &skill, &now
 					}
 				}
 				_ = s.users.UpsertLink(ctx, storage.UserLink{
@@ -148,23 +382,19 @@ func (s *QueueService) validateJoinAsync(guildID string, ul storage.UserLink) {
 					Nickname:           ul.Nickname,
 					IsMember:           ok,
 					MemberCheckedAt:    &now,
-					GuildID:            guildID,
+					GuildID:            ul.GuildID, // el guild del vínculo no cambia por joinear en otro
 					EloSnapshot:        eloPtr,
 					SkillLevelSnapshot: skillPtr,
+					SnapshotAt:         snapAt,
 				})
 				ul.IsMember = ok
 				ul.MemberCheckedAt = &now
-				if eloPtr != nil {
-					ul.EloSnapshot = eloPtr
-				}
-				if skillPtr != nil {
-					ul.SkillLevelSnapshot = skillPtr
-				}
+				ul.EloSnapshot, ul.SkillLevelSnapshot, ul.SnapshotAt = eloPtr, skillPtr, snapAt
 			}
 		}
 		if !ul.IsMember {
-			// _ = s.queue.Leave(context.Background(), guildID, ul.DiscordUserID)
-			s.notify(guildID, ul.DiscordUserID, "❌ Debes ser **miembro del Club** en FACEIT para unirte a la cola.")
+			s.enforce(guildID, queue, ul.DiscordUserID, pol.JoinEnforcement, "no es miembro del Club",
+				"❌ Debes ser **miembro del Club** en FACEIT para unirte a la cola.")
 			return
 		}
 	}
@@ -172,38 +402,196 @@ func (s *QueueService) validateJoinAsync(guildID string, ul storage.UserLink) {
 	// si llegó hasta acá, mantiene su lugar en la cola
 }
 
+// validated: libera al jugador para el pop (si no volvió a joinear mientras tanto) y avisa
+// (el match que esperaba por él puede salir ahora)
+func (s *QueueService) validated(guildID, queue, discordID string, until time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.queue.Validated(ctx, guildID, queue, discordID, until); err != nil {
+		log.Printf("[queue] validated guild=%s user=%s: %v", guildID, discordID, err)
+	}
+	if s.onChange != nil {
+		s.onChange(guildID, queue)
+	}
+}
+
+// enforce aplica la policy cuando falla una validación post-join:
+// warn = sólo aviso, block = queda en la cola como blocked (con motivo), remove = fuera de la cola.
+func (s *QueueService) enforce(guildID, queue, discordID, mode, reason, msg string) {
+	// el ctx de la validación puede estar por vencer; esto tiene el suyo
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	switch mode {
+	case "block":
+		if err := s.queue.Block(ctx, guildID, queue, discordID, reason); err != nil {
+			log.Printf("[queue] block guild=%s user=%s: %v", guildID, discordID, err)
+			break
+		}
+		msg += "\nQuedaste **bloqueado** en " + queueLabel(queue) + " hasta que vuelvas a unirte cumpliendo los requisitos."
+	case "remove":
+		if _, err := s.queue.Kick(ctx, guildID, queue, discordID, "system", reason); err != nil {
+			log.Printf("[queue] remove guild=%s user=%s: %v", guildID, discordID, err)
+			break
+		}
+		msg += "\nTe sacamos de " + queueLabel(queue) + "."
+	}
+	if mode == "warn" {
+		s.notifyJoin(guildID, discordID, msg) // se repite en cada join: que no spamee
+	} else {
+		s.notify(guildID, discordID, msg)
+	}
+	if mode != "warn" && s.onChange != nil {
+		s.onChange(guildID, queue)
+	}
+}
+
 func (s *QueueService) notify(guildID, userID, msg string) {
 	if s.notifier != nil {
 		s.notifier.Notify(guildID, userID, msg)
 	}
 }
 
-func (s *QueueService) Leave(ctx context.Context, guildID, discordID string) (string, error) {
-	ok, err := s.queue.Leave(ctx, guildID, discordID)
+func (s *QueueService) notifyJoin(guildID, userID, msg string) {
+	if s.notifier != nil {
+		s.notifier.NotifyThrottled(guildID, userID, msg)
+	}
+}
+
+// Leave: sale el jugador; si entró con su party, sale la party entera
+func (s *QueueService) Leave(ctx context.Context, guildID, queue, discordID string) (string, error) {
+	entries, err := s.queue.QueuesOf(ctx, guildID, discordID)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.QueueName != queue || e.PartyID == 0 {
+			continue
+		}
+		n, err := s.queue.LeaveParty(ctx, guildID, queue, e.PartyID, discordID)
+		if err != nil {
+			return "", err
+		}
+		if s.reliability != nil {
+			s.reliability.Evaluate(ctx, guildID, queue, discordID)
+		}
+		return fmt.Sprintf("✅ Saliste de %s con tu party (%d).", queueLabel(queue), n), nil
+	}
+
+	ok, err := s.queue.Leave(ctx, guildID, queue, discordID)
 	if err != nil {
 		return "", err
 	}
 	if !ok {
-		return "ℹ️ No estabas en la cola.", nil
+		return "ℹ️ No estabas en " + queueLabel(queue) + ".", nil
+	}
+	msg := "✅ Saliste de " + queueLabel(queue) + "."
+	if s.reliability != nil {
+		if p, ok := s.reliability.Evaluate(ctx, guildID, queue, discordID); ok {
+			msg += fmt.Sprintf("\n⛔ Saliste muchas veces seguidas: no podés volver hasta <t:%d:R>.", p.ExpiresAt.Unix())
+		}
+	}
+	return msg, nil
+}
+
+// Kick: un admin saca a alguien de la cola (queda registrado quién y por qué)
+func (s *QueueService) Kick(ctx context.Context, guildID, queue, discordID, actorID, reason string) (bool, error) {
+	return s.queue.Kick(ctx, guildID, queue, discordID, actorID, reason)
+}
+
+// QueuesOf: en qué colas del guild está el jugador
+func (s *QueueService) QueuesOf(ctx context.Context, guildID, discordID string) ([]storage.QueueEntry, error) {
+	return s.queue.QueuesOf(ctx, guildID, discordID)
+}
+
+// History: últimos movimientos de un jugador en la cola, para responder "¿por qué me sacaron?"
+func (s *QueueService) History(ctx context.Context, guildID, discordID string, limit int) (string, error) {
+	evs, err := s.queue.ListEvents(ctx, guildID, discordID, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(evs) == 0 {
+		return fmt.Sprintf("ℹ️ <@%s> no tiene movimientos en la cola.", discordID), nil
+	}
+
+	out := fmt.Sprintf("🧾 **Historial de <@%s>** (últimos %d)\n", discordID, len(evs))
+	for _, ev := range evs {
+		line := fmt.Sprintf("<t:%d:f> · **%s**", ev.CreatedAt.Unix(), eventLabel(ev.Event))
+		if ev.QueueName != storage.DefaultQueue {
+			line += " [" + ev.QueueName + "]"
+		}
+		switch ev.Actor {
+		case "system", discordID:
+		default:
+			line += fmt.Sprintf(" por <@%s>", ev.Actor)
+		}
+		if ev.Reason != "" {
+			line += " — " + ev.Reason
+		}
+		out += line + "\n"
+	}
+	return out, nil
+}
+
+func eventLabel(ev string) string {
+	switch ev {
+	case "join":
+		return "entró"
+	case "rejoin":
+		return "volvió a entrar"
+	case "added":
+		return "agregado por un admin"
+	case "leave":
+		return "salió"
+	case "kick":
+		return "kickeado"
+	case "left":
+		return "fuera de voz"
+	case "afk":
+		return "afk"
+	case "blocked":
+		return "bloqueado"
+	case "back":
+		return "volvió a voz"
+	case "pruned":
+		return "removido por inactividad"
+	case "popped":
+		return "entró a un match"
+	case "requeued":
+		return "devuelto a la cola"
+	case "cleared":
+		return "cola vaciada"
+	case "moved":
+		return "movido de lugar"
+	case "swapped":
+		return "cambió de lugar"
+	case "locked":
+		return "cerró la cola"
+	case "unlocked":
+		return "abrió la cola"
+	default:
+		return ev
 	}
-	return "✅ Saliste de la cola.", nil
 }
 
-func (s *QueueService) Status(ctx context.Context, guildID string) (string, error) {
+func (s *QueueService) Status(ctx context.Context, guildID, queue string) (string, error) {
 	// lee policy para calcular ventanas de gracia mostradas
-	pol, _ := s.policy.Get(ctx, guildID)
+	pol, _ := s.policy.Get(ctx, guildID, queue)
 	afkGrace := time.Duration(pol.AFKTimeoutSeconds) * time.Second
 	leftGrace := time.Duration(pol.DropIfLeftSeconds) * time.Minute
 
-	items, err := s.queue.ListWithGrace(ctx, guildID, 50, afkGrace, leftGrace)
+	items, err := s.queue.ListWithGrace(ctx, guildID, queue, 50, afkGrace, leftGrace)
 	if err != nil {
 		return "", err
 	}
 	if len(items) == 0 {
-		return "ℹ️ La cola está vacía.", nil
+		return "ℹ️ No hay nadie en " + queueLabel(queue) + ".", nil
 	}
 
 	out := "📋 **Cola actual**\n"
+	if queue != storage.DefaultQueue {
+		out = "📋 **Cola " + queue + "**\n"
+	}
 	for i, it := range items {
 		suf := ""
 		switch it.Status {
@@ -213,34 +601,109 @@ func (s *QueueService) Status(ctx context.Context, guildID string) (string, erro
 		case "afk":
 			// nose si mostrar los afk o no(afkGrace > 0)
 			suf = " · 😴 *(afk)*"
+		case "blocked":
+			suf = " · ⛔ " + it.StatusReason
+		}
+		if it.PartyID != 0 {
+			suf += " · 👥"
+		}
+		if it.Priority > 0 {
+			suf += " · ⭐"
 		}
 		out += fmt.Sprintf("%d) <@%s> — **%s** (%s)%s\n", i+1, it.DiscordUserID, it.Nickname, it.Status, suf)
 	}
 	return out, nil
 }
 
-func (s *QueueService) TouchValid(ctx context.Context, guildID, discordID string) error {
-	return s.queue.TouchValid(ctx, guildID, discordID)
+// Position: lugar en la cola, tiempo esperando y ETA estimada según el ritmo de joins
+func (s *QueueService) Position(ctx context.Context, guildID, queue, discordID string) (string, error) {
+	e, pos, total, found, err := s.queue.Position(ctx, guildID, queue, discordID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "ℹ️ No estás en " + queueLabel(queue) + ".", nil
+	}
+	waited := time.Since(e.JoinedAt)
+	out := fmt.Sprintf("⏱️ Esperando hace **%s** (desde <t:%d:t>).", fmtWait(waited), e.JoinedAt.Unix())
+	if pos == 0 && e.Status == "blocked" {
+		return fmt.Sprintf("⛔ Estás **bloqueado** en la cola: %s.\nVolvé a unirte cuando cumplas los requisitos.", e.StatusReason), nil
+	}
+	if pos == 0 {
+		// afk/left: no cuenta para el pop hasta que vuelva
+		return fmt.Sprintf("🟠 Estás en la cola pero como **%s**, no contás para armar match hasta que vuelvas.\n%s", e.Status, out), nil
+	}
+	out = fmt.Sprintf("📍 Estás **#%d** de %d en %s.\n%s", pos, total, queueLabel(queue), out)
+
+	pol, _ := s.policy.Get(ctx, guildID, queue)
+	size := pol.MatchSize
+	if size <= 0 {
+		size = defaultMatchSize
+	}
+	// para que me toque hace falta llenar el bloque de `size` en el que estoy
+	needed := ((pos+size-1)/size)*size - total
+	if needed <= 0 {
+		return out + "\n🔥 Ya hay gente suficiente: el match se arma en breve.", nil
+	}
+
+	eta, ok := s.estimateWait(ctx, guildID, queue, needed)
+	if !ok {
+		return out + fmt.Sprintf("\n🔮 Faltan **%d** jugadores; todavía no hay historial para estimar cuánto.", needed), nil
+	}
+	return out + fmt.Sprintf("\n🔮 Faltan **%d** jugadores · ETA ~**%s**.", needed, fmtWait(eta)), nil
 }
 
-func (s *QueueService) MarkLeft(ctx context.Context, guildID, discordID string) error {
-	return s.queue.MarkLeft(ctx, guildID, discordID)
+// estimateWait: tiempo para `needed` joins nuevos según el ritmo reciente (2h) o, si no hay, la última semana
+func (s *QueueService) estimateWait(ctx context.Context, guildID, queue string, needed int) (time.Duration, bool) {
+	for _, win := range []time.Duration{2 * time.Hour, 7 * 24 * time.Hour} {
+		n, err := s.queue.CountEvents(ctx, guildID, queue, "join", win)
+		if err != nil || n == 0 {
+			continue
+		}
+		perJoin := win / time.Duration(n)
+		return perJoin * time.Duration(needed), true
+	}
+	return 0, false
 }
 
-func (s *QueueService) MarkAFK(ctx context.Context, guildID, discordID string) error {
-	return s.queue.MarkAFK(ctx, guildID, discordID)
+func fmtWait(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
 }
 
-func (s *QueueService) Prune(ctx context.Context, guildID string, afk, left time.Duration) (int64, int64, error) {
-	return s.queue.Prune(ctx, guildID, afk, left)
+func (s *QueueService) TouchValid(ctx context.Context, guildID, queue, discordID string) error {
+	return s.queue.TouchValid(ctx, guildID, queue, discordID)
 }
 
-func (s *QueueService) List(ctx context.Context, guildID string, limit int) ([]storage.QueueEntry, error) {
-	return s.queue.List(ctx, guildID, limit)
+func (s *QueueService) MarkLeft(ctx context.Context, guildID, queue, discordID, reason string) error {
+	return s.queue.MarkLeft(ctx, guildID, queue, discordID, reason)
 }
 
-func (s *QueueService) ListRichWithGrace(ctx context.Context, guildID string, limit int, graceAFK, graceLeft time.Duration) ([]QueueItemRich, error) {
-	base, err := s.queue.ListWithGrace(ctx, guildID, limit, graceAFK, graceLeft)
+func (s *QueueService) MarkAFK(ctx context.Context, guildID, queue, discordID, reason string) error {
+	return s.queue.MarkAFK(ctx, guildID, queue, discordID, reason)
+}
+
+func (s *QueueService) Prune(ctx context.Context, guildID, queue string, afk, left time.Duration) (int64, int64, error) {
+	afkIDs, leftIDs, err := s.queue.Prune(ctx, guildID, queue, afk, left)
+	if s.reliability != nil {
+		for _, id := range append(afkIDs, leftIDs...) {
+			s.reliability.Evaluate(ctx, guildID, queue, id)
+		}
+	}
+	return int64(len(afkIDs)), int64(len(leftIDs)), err
+}
+
+func (s *QueueService) List(ctx context.Context, guildID, queue string, limit int) ([]storage.QueueEntry, error) {
+	return s.queue.List(ctx, guildID, queue, limit)
+}
+
+func (s *QueueService) ListRichWithGrace(ctx context.Context, guildID, queue string, limit int, graceAFK, graceLeft time.Duration) ([]QueueItemRich, error) {
+	base, err := s.queue.ListWithGrace(ctx, guildID, queue, limit, graceAFK, graceLeft)
 	if err != nil {
 		return nil, err
 	}
@@ -251,10 +714,13 @@ func (s *QueueService) ListRichWithGrace(ctx context.Context, guildID string, li
 			FaceitUserID:  it.FaceitUserID,
 			Nickname:      it.Nickname,
 			Status:        it.Status,
+			StatusReason:  it.StatusReason,
+			PartyID:       it.PartyID,
+			Priority:      it.Priority,
 			JoinedAt:      it.JoinedAt,
 			LastSeenAt:    it.LastSeenAt,
 		}
-		if ul, err := s.users.GetByDiscordID(ctx, it.DiscordUserID); err == nil {
+		if ul, err := s.users.GetByDiscordIDInGuild(ctx, guildID, it.DiscordUserID); err == nil {
 			qi.SkillLevel = ul.SkillLevelSnapshot
 			if ul.Nickname != "" {
 				qi.Nickname = ul.Nickname
@@ -262,6 +728,5 @@ func (s *QueueService) ListRichWithGrace(ctx context.Context, guildID string, li
 		}
 		out = append(out, qi)
 	}
-	sort.SliceStable(out, func(i, j int) bool { return out[i].JoinedAt.Before(out[j].JoinedAt) })
-	return out, nil
+	return GroupByParty(out), nil
 }
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/schedule"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+// ScheduleState: si la cola está abierta ahora y cuándo cambia
+type ScheduleState struct {
+	Scheduled bool // la cola tiene horarios (sin horarios está siempre abierta)
+	Open      bool
+	Next      time.Time // próxima apertura si está cerrada, cierre si está abierta
+}
+
+// ScheduleService: horarios de apertura por cola, en la zona horaria del guild.
+// Fuera de horario QueueService.Join rechaza y la UI deshabilita el botón.
+type ScheduleService struct {
+	repo   ScheduleRepo
+	guilds GuildConfig
+	policy PolicyRepo
+	queue  QueueRepo
+}
+
+func NewScheduleService(repo ScheduleRepo, guilds GuildConfig, policy PolicyRepo, queue QueueRepo) *ScheduleService {
+	return &ScheduleService{repo: repo, guilds: guilds, policy: policy, queue: queue}
+}
+
+func (s *ScheduleService) windows(ctx context.Context, guildID, queue string) ([]schedule.Window, error) {
+	rows, err := s.repo.List(ctx, guildID, queue)
+	if err != nil {
+		return nil, err
+	}
+	ws := make([]schedule.Window, 0, len(rows))
+	for _, r := range rows {
+		ws = append(ws, schedule.Window{Weekday: r.Weekday, Open: r.OpenMinute, Close: r.CloseMinute})
+	}
+	return ws, nil
+}
+
+// State: si falla la DB la damos por abierta (mejor dejar entrar que trabar la cola)
+func (s *ScheduleService) State(ctx context.Context, guildID, queue string) ScheduleState {
+	ws, err := s.windows(ctx, guildID, queue)
+	if err != nil || len(ws) == 0 {
+		return ScheduleState{Open: true}
+	}
+	now := time.Now().In(s.guilds.Location(ctx, guildID))
+	st := ScheduleState{Scheduled: true, Open: schedule.IsOpen(ws, now)}
+	if st.Open {
+		st.Next, _ = schedule.NextClose(ws, now)
+	} else {
+		st.Next, _ = schedule.NextOpen(ws, now)
+	}
+	return st
+}
+
+// ClosedMessage: motivo para el rechazo del join
+func (st ScheduleState) ClosedMessage(queue string) string {
+	msg := "🔒 Fuera de horario: " + queueLabel(queue) + " está cerrada."
+	if !st.Next.IsZero() {
+		msg += fmt.Sprintf(" Abre <t:%d:F> (<t:%d:R>).", st.Next.Unix(), st.Next.Unix())
+	}
+	return msg
+}
+
+// Add: "lun-vie" de 20:00 a 02:00 (cruza la medianoche si to <= from)
+func (s *ScheduleService) Add(ctx context.Context, guildID, queue, days, from, to string) (string, error) {
+	ds, err := schedule.ParseDays(days)
+	if err != nil {
+		return "", err
+	}
+	open, err := schedule.ParseClock(from)
+	if err != nil {
+		return "", err
+	}
+	shut, err := schedule.ParseClock(to)
+	if err != nil {
+		return "", err
+	}
+	if open == shut {
+		return "", fmt.Errorf("la apertura y el cierre no pueden ser la misma hora")
+	}
+	if err := s.repo.Add(ctx, guildID, queue, ds, open, shut); err != nil {
+		return "", err
+	}
+	return s.Show(ctx, guildID, queue)
+}
+
+func (s *ScheduleService) Clear(ctx context.Context, guildID, queue string) (string, error) {
+	n, err := s.repo.Clear(ctx, guildID, queue)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "ℹ️ " + queueLabel(queue) + " no tenía horarios.", nil
+	}
+	return "✅ Horarios borrados: " + queueLabel(queue) + " queda siempre abierta.", nil
+}
+
+func (s *ScheduleService) Show(ctx context.Context, guildID, queue string) (string, error) {
+	ws, err := s.windows(ctx, guildID, queue)
+	if err != nil {
+		return "", err
+	}
+	loc := s.guilds.Location(ctx, guildID)
+	var b strings.Builder
+	fmt.Fprintf(&b, "🕒 **Horarios de %s** (%s)\n", queueLabel(queue), loc)
+	if len(ws) == 0 {
+		b.WriteString("• siempre abierta\n")
+		return b.String(), nil
+	}
+	for _, w := range ws {
+		b.WriteString("• " + w.String() + "\n")
+	}
+	pol, _ := s.policy.Get(ctx, guildID, queue)
+	if pol.FlushOnClose {
+		b.WriteString("• al cerrar se vacía la cola\n")
+	}
+	st := s.State(ctx, guildID, queue)
+	if st.Open {
+		fmt.Fprintf(&b, "\nAhora: **abierta**, cierra <t:%d:R>.", st.Next.Unix())
+	} else if !st.Next.IsZero() {
+		fmt.Fprintf(&b, "\nAhora: **cerrada**, abre <t:%d:R>.", st.Next.Unix())
+	}
+	return b.String(), nil
+}
+
+// FlushIfClosed: si la cola está fuera de horario y la policy lo pide, la vacía.
+// Devuelve a quiénes sacó (vacío si no hizo nada) y el estado, para el aviso.
+func (s *ScheduleService) FlushIfClosed(ctx context.Context, guildID, queue string) ([]string, ScheduleState, error) {
+	st := s.State(ctx, guildID, queue)
+	if !st.Scheduled || st.Open {
+		return nil, st, nil
+	}
+	pol, err := s.policy.Get(ctx, guildID, queue)
+	if err != nil || !pol.FlushOnClose {
+		return nil, st, err
+	}
+	ids, err := s.queue.Clear(ctx, guildID, queue, "system", "cola cerrada por horario")
+	return ids, st, err
+}
+
+// compile-time: el repo de storage cumple el port
+var _ ScheduleRepo = (*storage.ScheduleRepo)(nil)
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/faceitevent"
+	"github.com/jose-valero/faceit-queue-bot/internal/infra/storage"
+)
+
+const (
+	webhookBatch          = 50        // cuántos eventos pendientes se leen por vuelta
+	webhookSourceBot      = "bot"     // source de las fallas del worker en webhook_failures
+	webhookSourceReceiver = "webhook" // las del receptor (cmd/webhook): faceit_match_status
+)
+
+// webhookBackoff: espera antes de cada reintento automático. Agotada la lista la falla
+// queda en el dead letter (sin próximo intento) hasta que un admin haga /webhook replay.
+var webhookBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// WebhookService: procesa los eventos de FACEIT que el receptor guarda en webhook_events.
+// Un solo worker (Run) los toma en orden de llegada y los marca al terminar: si el bot se
+// cae a mitad de uno, al volver lo procesa de nuevo (al menos una vez, nunca en paralelo).
+// Si el handler falla, el evento pasa a webhook_failures y se reintenta con backoff sin
+// frenar a los que vienen atrás. Las fallas del receptor (no pudo actualizar
+// faceit_match_status) también se reintentan acá, rehaciendo ese upsert.
+type WebhookService struct {
+	events   WebhookEventRepo
+	failures WebhookFailureRepo
+	rooms    MatchEventHandler
+	members  MemberEventHandler
+
+	mu sync.Mutex // worker y /webhook replay no despachan a la vez
+}
+
+func NewWebhookService(events WebhookEventRepo, failures WebhookFailureRepo, rooms MatchEventHandler, members MemberEventHandler) *WebhookService {
+	return &WebhookService{events: events, failures: failures, rooms: rooms, members: members}
+}
+
+// Run: drena los pendientes al arrancar, cada vez que llega algo por wake (NOTIFY o
+// reconexión del LISTEN) y cada minuto por las dudas; en cada vuelta también reintenta
+// las fallas que ya cumplieron su espera. Bloquea hasta que se cancela ctx.
+func (s *WebhookService) Run(ctx context.Context, wake <-chan struct{}) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		s.mu.Lock()
+		s.drain(ctx)
+		s.retryDue(ctx)
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-t.C:
+		}
+	}
+}
+
+func (s *WebhookService) drain(ctx context.Context) {
+	for {
+		lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		evs, err := s.events.ListPending(lctx, webhookBatch)
+		cancel()
+		if err != nil {
+			log.Printf("[webhook] pendientes: %v", err)
+			return
+		}
+		for _, ev := range evs {
+			if err := s.Dispatch(ctx, ev); err != nil {
+				log.Printf("[webhook] id=%d type=%s: %v", ev.ID, ev.Type, err)
+				if ferr := s.fail(ctx, ev, webhookSourceBot, err); ferr != nil {
+					// sin dead letter no lo soltamos: queda pendiente para la próxima vuelta
+					log.Printf("[webhook] registrar falla id=%d: %v", ev.ID, ferr)
+					return
+				}
+			}
+			mctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err := s.events.MarkProcessed(mctx, ev.ID)
+			cancel()
+			if err != nil {
+				log.Printf("[webhook] marcar id=%d: %v", ev.ID, err)
+				return
+			}
+		}
+		if len(evs) < webhookBatch {
+			return
+		}
+	}
+}
+
+// retryDue: reintenta las fallas del worker y del receptor a las que ya les toca
+func (s *WebhookService) retryDue(ctx context.Context) {
+	s.retryBot(ctx)
+	s.retryReceiver(ctx)
+}
+
+func (s *WebhookService) retryBot(ctx context.Context) {
+	lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	fs, err := s.failures.Due(lctx, webhookSourceBot, webhookBatch)
+	cancel()
+	if err != nil {
+		log.Printf("[webhook] reintentos: %v", err)
+		return
+	}
+	for _, f := range fs {
+		ev, err := s.events.Get(ctx, f.EventID)
+		if err != nil {
+			log.Printf("[webhook] reintento id=%d: %v", f.EventID, err)
+			continue
+		}
+		if newer, err := s.superseded(ctx, ev); err != nil {
+			log.Printf("[webhook] reintento id=%d: %v", ev.ID, err)
+			continue
+		} else if newer {
+			log.Printf("[webhook] reintento id=%d descartado: ya se procesó un estado más nuevo del match", ev.ID)
+			s.resolve(ctx, ev.ID, webhookSourceBot)
+			continue
+		}
+		if err := s.Dispatch(ctx, ev); err != nil {
+			log.Printf("[webhook] reintento id=%d type=%s (intento %d): %v", ev.ID, ev.Type, f.Attempts+1, err)
+			if ferr := s.fail(ctx, ev, webhookSourceBot, err); ferr != nil {
+				log.Printf("[webhook] registrar falla id=%d: %v", ev.ID, ferr)
+			}
+			continue
+		}
+		log.Printf("[webhook] reintento id=%d ok (intento %d)", ev.ID, f.Attempts+1)
+		s.resolve(ctx, ev.ID, webhookSourceBot)
+	}
+}
+
+// retryReceiver: rehace el upsert de faceit_match_status que le falló al receptor
+func (s *WebhookService) retryReceiver(ctx context.Context) {
+	lctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	fs, err := s.failures.Due(lctx, webhookSourceReceiver, webhookBatch)
+	cancel()
+	if err != nil {
+		log.Printf("[webhook] reintentos del receptor: %v", err)
+		return
+	}
+	for _, f := range fs {
+		ev, err := s.events.Get(ctx, f.EventID)
+		if err != nil {
+			log.Printf("[webhook] reintento receptor id=%d: %v", f.EventID, err)
+			continue
+		}
+		if err := s.upsertMatchStatus(ctx, ev); err != nil {
+			log.Printf("[webhook] reintento receptor id=%d (intento %d): %v", ev.ID, f.Attempts+1, err)
+			if ferr := s.fail(ctx, ev, webhookSourceReceiver, err); ferr != nil {
+				log.Printf("[webhook] registrar falla id=%d: %v", ev.ID, ferr)
+			}
+			continue
+		}
+		log.Printf("[webhook] reintento receptor id=%d ok (intento %d)", ev.ID, f.Attempts+1)
+		s.resolve(ctx, ev.ID, webhookSourceReceiver)
+	}
+}
+
+// upsertMatchStatus: lo que hace el receptor con el evento (si no es de match no hay nada que hacer)
+func (s *WebhookService) upsertMatchStatus(ctx context.Context, ev storage.WebhookEvent) error {
+	e, err := faceitevent.Decode([]byte(ev.Payload))
+	if err != nil || e.Match == nil || e.Match.MatchID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	m := e.Match
+	return s.events.UpsertMatchStatus(ctx, m.MatchID, m.HubID, m.Status, m.DemoURL, ev.ReceivedAt)
+}
+
+// fail: suma el intento fallido en webhook_failures y agenda el próximo según el backoff
+func (s *WebhookService) fail(ctx context.Context, ev storage.WebhookEvent, source string, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	prev, err := s.failures.Get(ctx, ev.ID, source)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	f := storage.WebhookFailure{
+		EventID:   ev.ID,
+		Source:    source,
+		HubID:     hubOf(ev),
+		Attempts:  prev.Attempts + 1,
+		LastError: cause.Error(),
+	}
+	if n := f.Attempts - 1; n < len(webhookBackoff) {
+		next := time.Now().Add(webhookBackoff[n])
+		f.NextAttemptAt = &next
+	} else {
+		log.Printf("[webhook] id=%d type=%s (%s) al dead letter tras %d intentos", ev.ID, ev.Type, source, f.Attempts)
+	}
+	return s.failures.Record(ctx, f)
+}
+
+func (s *WebhookService) resolve(ctx context.Context, id int64, source string) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.failures.Resolve(ctx, id, source); err != nil {
+		log.Printf("[webhook] resolver falla id=%d: %v", id, err)
+	}
+}
+
+// superseded: un match_status_* viejo que ya quedó atrás de otro del mismo match
+func (s *WebhookService) superseded(ctx context.Context, ev storage.WebhookEvent) (bool, error) {
+	e, err := faceitevent.Decode([]byte(ev.Payload))
+	if err != nil || e.Match == nil || e.Match.MatchID == "" || e.Type.MatchStatus() == "" || e.Type == faceitevent.MatchDemoReady {
+		return false, nil
+	}
+	return s.events.HasNewerMatchStatus(ctx, ev.ID, e.Match.MatchID)
+}
+
+// Dispatch: manda el evento a quien corresponda. Sólo devuelve error si vale la pena
+// reintentar; un payload roto se loguea y se da por procesado.
+func (s *WebhookService) Dispatch(ctx context.Context, ev storage.WebhookEvent) error {
+	e, err := faceitevent.Decode([]byte(ev.Payload))
+	if err != nil {
+		log.Printf("[webhook] id=%d payload inválido: %v", ev.ID, err)
+		return nil
+	}
+	log.Printf("[webhook] id=%d type=%s", ev.ID, e.Type)
+
+	switch {
+	case e.Match != nil:
+		if e.Type == faceitevent.MatchDemoReady {
+			return nil // las salas ya se limpiaron con el finished
+		}
+		if e.Match.MatchID == "" {
+			log.Printf("[webhook] id=%d %s sin match_id", ev.ID, e.Type)
+			return nil
+		}
+		return s.rooms.HandleMatchEvent(ctx, e.Match.MatchID, e.Match.Status)
+
+	case e.Member != nil:
+		if e.Member.UserID == "" {
+			log.Printf("[webhook] id=%d %s sin user_id", ev.ID, e.Type)
+			return nil
+		}
+		if err := s.members.HandleMemberEvent(ctx, *e.Member); err != nil {
+			return fmt.Errorf("membresía %s: %w", e.Member.UserID, err)
+		}
+
+	default:
+		// cambios de rol y tipos que no modelamos: por ahora sólo quedan guardados
+	}
+	return nil
+}
+
+// ---------- admin (/webhook) ----------
+
+// Replay: vuelve a despachar un evento guardado, esté o no procesado (sólo si es de un
+// hub del guild o no trae hub). Si el receptor tenía una falla abierta también rehace su
+// upsert. Cada parte que sale bien cierra su falla; la que no, suma un intento.
+func (s *WebhookService) Replay(ctx context.Context, id int64, hubIDs []string) (string, error) {
+	ev, err := s.events.Get(ctx, id)
+	if err == storage.ErrNotFound {
+		return fmt.Sprintf("ℹ️ No existe el evento `#%d`.", id), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if hub := hubOf(ev); hub != "" && !slices.Contains(hubIDs, hub) {
+		return fmt.Sprintf("⛔ El evento `#%d` es de un hub que no está configurado en este servidor.", id), nil
+	}
+	if _, err := faceitevent.Decode([]byte(ev.Payload)); err != nil {
+		return fmt.Sprintf("ℹ️ El evento `#%d` tiene un payload inválido (%v): no hay nada que re-despachar.", id, err), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.failures.Get(ctx, ev.ID, webhookSourceReceiver); err == nil {
+		if err := s.upsertMatchStatus(ctx, ev); err != nil {
+			log.Printf("[webhook] replay receptor id=%d: %v", ev.ID, err)
+			if ferr := s.fail(ctx, ev, webhookSourceReceiver, err); ferr != nil {
+				return "", ferr
+			}
+			return fmt.Sprintf("❌ El evento `#%d` (%s) volvió a fallar al guardar el estado del match: %v", id, ev.Type, err), nil
+		}
+		s.resolve(ctx, ev.ID, webhookSourceReceiver)
+	} else if err != storage.ErrNotFound {
+		return "", err
+	}
+
+	// sin el cancel del comando: el handler de salas deja un polling corriendo con este ctx
+	if err := s.Dispatch(context.WithoutCancel(ctx), ev); err != nil {
+		log.Printf("[webhook] replay id=%d type=%s: %v", ev.ID, ev.Type, err)
+		if ferr := s.fail(ctx, ev, webhookSourceBot, err); ferr != nil {
+			return "", ferr
+		}
+		return fmt.Sprintf("❌ El evento `#%d` (%s) volvió a fallar: %v", id, ev.Type, err), nil
+	}
+	s.resolve(ctx, ev.ID, webhookSourceBot)
+	if ev.ProcessedAt == nil {
+		if err := s.events.MarkProcessed(ctx, ev.ID); err != nil {
+			log.Printf("[webhook] marcar id=%d: %v", ev.ID, err)
+		}
+	}
+	log.Printf("[webhook] replay id=%d type=%s ok", ev.ID, ev.Type)
+	return fmt.Sprintf("✅ Evento `#%d` (%s) re-despachado.", id, ev.Type), nil
+}
+
+// Failures: las fallas sin resolver de los hubs del guild (y las que no traen hub)
+func (s *WebhookService) Failures(ctx context.Context, hubIDs []string) (string, error) {
+	fs, err := s.failures.ListOpen(ctx, hubIDs, 15)
+	if err != nil {
+		return "", err
+	}
+	if len(fs) == 0 {
+		return "✅ No hay webhooks con error.", nil
+	}
+	var b strings.Builder
+	b.WriteString("📮 **Webhooks con error**\n")
+	for _, f := range fs {
+		fmt.Fprintf(&b, "• `#%d` %s · %s · %d intento(s) · ", f.EventID, f.Type, f.Source, f.Attempts)
+		if f.NextAttemptAt != nil {
+			fmt.Fprintf(&b, "reintenta <t:%d:R>\n", f.NextAttemptAt.Unix())
+		} else {
+			b.WriteString("**sin reintentos automáticos**\n")
+		}
+		fmt.Fprintf(&b, "  └ %s (<t:%d:R>)\n", truncate(f.LastError, 140), f.LastFailedAt.Unix())
+	}
+	b.WriteString("\nUsá `/webhook replay id:<n>` para re-despacharlo.")
+	return b.String(), nil
+}
+
+// hubOf: el hub del evento, si el payload lo trae
+func hubOf(ev storage.WebhookEvent) string {
+	e, err := faceitevent.Decode([]byte(ev.Payload))
+	switch {
+	case err != nil:
+		return ""
+	case e.Match != nil:
+		return e.Match.HubID
+	case e.Member != nil:
+		return e.Member.HubID
+	case e.Role != nil:
+		return e.Role.HubID
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
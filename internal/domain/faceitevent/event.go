@@ -0,0 +1,208 @@
+// Package faceitevent decodifica los webhooks de FACEIT en eventos tipados.
+// Lo usan los tres lugares que reciben webhooks (httpfaceit, cmd/webhook y el worker del bot)
+// para no tener cada uno su parser. Puro como teams: sin DB ni Discord.
+package faceitevent
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Type string
+
+const (
+	MatchObjectCreated     Type = "match_object_created"
+	MatchStatusConfiguring Type = "match_status_configuring"
+	MatchStatusReady       Type = "match_status_ready"
+	MatchStatusFinished    Type = "match_status_finished"
+	MatchStatusCancelled   Type = "match_status_cancelled"
+	MatchStatusAborted     Type = "match_status_aborted"
+	MatchDemoReady         Type = "match_demo_ready"
+	HubUserAdded           Type = "hub_user_added"
+	HubUserRemoved         Type = "hub_user_removed"
+	HubUserRoleAdded       Type = "hub_user_role_added"
+	HubUserRoleRemoved     Type = "hub_user_role_removed"
+)
+
+// IsMatch: eventos de un match (traen Event.Match)
+func (t Type) IsMatch() bool {
+	return t == MatchObjectCreated || t == MatchDemoReady || strings.HasPrefix(string(t), "match_status_")
+}
+
+// MatchStatus: el estado del match que implica el evento ("" si no es de match).
+// demo_ready cuenta como finished (llega después del final, con la demo).
+func (t Type) MatchStatus() string {
+	switch {
+	case t == MatchObjectCreated:
+		return "created"
+	case t == MatchDemoReady:
+		return "finished"
+	case strings.HasPrefix(string(t), "match_status_"):
+		return strings.TrimPrefix(string(t), "match_status_")
+	}
+	return ""
+}
+
+// Event: un webhook decodificado. Según el tipo viene Match, Member o Role (o ninguno,
+// si es un tipo que no modelamos; igual se puede guardar y reprocesar).
+type Event struct {
+	ID        string    // event_id de FACEIT (o transaction_id); "" si no vino
+	Type      Type      // en minúsculas
+	Timestamp time.Time // zero si no vino
+	Match     *MatchEvent
+	Member    *MemberEvent
+	Role      *RoleEvent
+}
+
+type MatchEvent struct {
+	MatchID string
+	HubID   string // organizer_id / entity.id
+	Status  string // el del payload si vino, si no el que implica el tipo
+	DemoURL string // sólo en match_demo_ready (la primera si vienen varias)
+}
+
+// MemberEvent: alguien entró o salió del hub
+type MemberEvent struct {
+	HubID    string
+	UserID   string // player_id de FACEIT
+	Nickname string
+	Added    bool
+}
+
+// RoleEvent: le dieron o le sacaron un rol del hub
+type RoleEvent struct {
+	HubID  string
+	UserID string
+	RoleID string
+	Added  bool
+}
+
+var (
+	ErrEmpty  = errors.New("webhook vacío")
+	ErrNoType = errors.New("webhook sin tipo (event/type)")
+)
+
+// Decode: acepta el formato de FACEIT (event + payload) y el viejo (type + data)
+func Decode(body []byte) (Event, error) {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return Event{}, ErrEmpty
+	}
+	var env struct {
+		Event         string          `json:"event"`
+		Type          string          `json:"type"`
+		EventID       string          `json:"event_id"`
+		TransactionID string          `json:"transaction_id"`
+		Timestamp     json.RawMessage `json:"timestamp"`
+		Payload       fields          `json:"payload"`
+		Data          fields          `json:"data"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return Event{}, err
+	}
+	ev := Event{
+		ID:        first(env.EventID, env.TransactionID),
+		Type:      Type(strings.ToLower(strings.TrimSpace(first(env.Event, env.Type)))),
+		Timestamp: parseTime(env.Timestamp),
+	}
+	if ev.Type == "" {
+		return Event{}, ErrNoType
+	}
+	p := env.Payload
+	if len(p) == 0 {
+		p = env.Data
+	}
+	hubID := first(p.str("hub_id", "hubId", "organizer_id"), p.obj("entity").str("id"))
+	userID := p.str("user_id", "player_id", "userId")
+
+	switch {
+	case ev.Type.IsMatch():
+		ev.Match = &MatchEvent{
+			MatchID: p.str("match_id", "matchId", "id"),
+			HubID:   hubID,
+			Status:  first(strings.ToLower(p.str("status")), ev.Type.MatchStatus()),
+			DemoURL: p.firstURL("demo_url", "demoUrl"),
+		}
+	case ev.Type == HubUserAdded || ev.Type == HubUserRemoved:
+		ev.Member = &MemberEvent{HubID: hubID, UserID: userID, Nickname: p.str("nickname"), Added: ev.Type == HubUserAdded}
+	case ev.Type == HubUserRoleAdded || ev.Type == HubUserRoleRemoved:
+		ev.Role = &RoleEvent{HubID: hubID, UserID: userID, RoleID: p.str("role_id", "roleId", "role"), Added: ev.Type == HubUserRoleAdded}
+	}
+	return ev, nil
+}
+
+// fields: objeto JSON sin esquema fijo (FACEIT cambió nombres de campos entre versiones)
+type fields map[string]any
+
+func (f fields) str(keys ...string) string {
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64: // ids numéricos
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func (f fields) obj(key string) fields {
+	if m, ok := f[key].(map[string]any); ok {
+		return m
+	}
+	return nil
+}
+
+// firstURL: la demo viene como string o como lista de strings
+func (f fields) firstURL(keys ...string) string {
+	for _, k := range keys {
+		switch v := f[k].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case []any:
+			for _, x := range v {
+				if s, ok := x.(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// parseTime: RFC3339 o epoch (segundos o milisegundos)
+func parseTime(raw json.RawMessage) time.Time {
+	if len(raw) == 0 {
+		return time.Time{}
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+		return time.Time{}
+	}
+	var n float64
+	if json.Unmarshal(raw, &n) == nil && n > 0 {
+		if n > 1e12 {
+			return time.UnixMilli(int64(n))
+		}
+		return time.Unix(int64(n), 0)
+	}
+	return time.Time{}
+}
+
+func first(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
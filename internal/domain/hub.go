@@ -0,0 +1,7 @@
+package domain
+
+type Hub struct {
+	ID     string
+	Name   string
+	GameID string
+}
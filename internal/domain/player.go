@@ -5,4 +5,8 @@ type Player struct {
 	Nickname string
 	Elo      int
 	Skill    int
+
+	// campos visibles del perfil que el jugador puede editar (verificación por código)
+	SteamNickname  string
+	GamePlayerName string
 }
@@ -0,0 +1,169 @@
+// Package schedule resuelve los horarios de apertura de una cola: ventanas por día de la
+// semana (con hora de apertura y cierre) en la zona horaria del guild.
+// Puro como teams: sin DB ni Discord.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window: la cola abre el día Weekday a Open y cierra a Close (minutos desde las 00:00).
+// Close <= Open cruza la medianoche (ej: 20:00–02:00 cierra al día siguiente).
+type Window struct {
+	Weekday time.Weekday
+	Open    int
+	Close   int
+}
+
+func (w Window) length() time.Duration {
+	d := w.Close - w.Open
+	if d <= 0 {
+		d += 24 * 60
+	}
+	return time.Duration(d) * time.Minute
+}
+
+// start: cuándo abre la ventana el día de `day` (en la zona de day); DST lo resuelve time.Date
+func (w Window) start(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), w.Open/60, w.Open%60, 0, 0, day.Location())
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%s %s–%s", dayNames[w.Weekday], FormatClock(w.Open), FormatClock(w.Close))
+}
+
+// IsOpen: t cae dentro de alguna ventana. Sin ventanas la cola está siempre abierta.
+func IsOpen(ws []Window, t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		// la ventana de hoy, o la de ayer si cruza la medianoche
+		for _, back := range []int{0, -1} {
+			day := t.AddDate(0, 0, back)
+			if day.Weekday() != w.Weekday {
+				continue
+			}
+			s := w.start(day)
+			if !t.Before(s) && t.Before(s.Add(w.length())) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NextOpen: próxima apertura estrictamente después de t (false si no hay ventanas)
+func NextOpen(ws []Window, t time.Time) (time.Time, bool) {
+	var best time.Time
+	for _, w := range ws {
+		for i := 0; i <= 7; i++ {
+			day := t.AddDate(0, 0, i)
+			if day.Weekday() != w.Weekday {
+				continue
+			}
+			if s := w.start(day); s.After(t) && (best.IsZero() || s.Before(best)) {
+				best = s
+			}
+		}
+	}
+	return best, !best.IsZero()
+}
+
+// NextClose: cuándo cierra la ventana en la que está t (false si está cerrada)
+func NextClose(ws []Window, t time.Time) (time.Time, bool) {
+	var best time.Time
+	for _, w := range ws {
+		for _, back := range []int{0, -1} {
+			day := t.AddDate(0, 0, back)
+			if day.Weekday() != w.Weekday {
+				continue
+			}
+			s := w.start(day)
+			e := s.Add(w.length())
+			if !t.Before(s) && t.Before(e) && e.After(best) {
+				best = e // ventanas pegadas: la que más tarde cierra
+			}
+		}
+	}
+	return best, !best.IsZero()
+}
+
+// ParseClock: "20:00" → minutos desde las 00:00
+func ParseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("hora inválida %q (usá HH:MM)", s)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("hora inválida %q (usá HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+func FormatClock(min int) string {
+	return fmt.Sprintf("%02d:%02d", min/60, min%60)
+}
+
+var dayNames = map[time.Weekday]string{
+	time.Sunday: "dom", time.Monday: "lun", time.Tuesday: "mar", time.Wednesday: "mié",
+	time.Thursday: "jue", time.Friday: "vie", time.Saturday: "sáb",
+}
+
+var dayAliases = map[string]time.Weekday{
+	"dom": time.Sunday, "sun": time.Sunday,
+	"lun": time.Monday, "mon": time.Monday,
+	"mar": time.Tuesday, "tue": time.Tuesday,
+	"mie": time.Wednesday, "mié": time.Wednesday, "wed": time.Wednesday,
+	"jue": time.Thursday, "thu": time.Thursday,
+	"vie": time.Friday, "fri": time.Friday,
+	"sab": time.Saturday, "sáb": time.Saturday, "sat": time.Saturday,
+}
+
+// ParseDays: "lun-vie", "sab,dom", "todos" (también en inglés: "mon-fri", "all")
+func ParseDays(s string) ([]time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "todos" || s == "all" || s == "*" {
+		return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}, nil
+	}
+	seen := map[time.Weekday]bool{}
+	var out []time.Weekday
+	add := func(d time.Weekday) {
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		a, ok := dayAliases[strings.TrimSpace(from)]
+		if !ok {
+			return nil, fmt.Errorf("día inválido %q (lun, mar, mié, jue, vie, sáb, dom)", from)
+		}
+		if !isRange {
+			add(a)
+			continue
+		}
+		b, ok := dayAliases[strings.TrimSpace(to)]
+		if !ok {
+			return nil, fmt.Errorf("día inválido %q (lun, mar, mié, jue, vie, sáb, dom)", to)
+		}
+		// rango circular: "vie-lun" = vie, sáb, dom, lun
+		for d := a; ; d = (d + 1) % 7 {
+			add(d)
+			if d == b {
+				break
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("sin días")
+	}
+	return out, nil
+}
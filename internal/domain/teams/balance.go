@@ -0,0 +1,306 @@
+// Package teams arma los equipos de un match armado desde la cola.
+// Todo acá es puro (sin DB ni Discord) para poder razonarlo y probarlo aislado.
+package teams
+
+import "sort"
+
+// ExhaustiveMax: hasta este tamaño probamos todas las combinaciones (10 → 126 splits)
+const ExhaustiveMax = 12
+
+type Player struct {
+	ID     string // discord_user_id
+	Rating int    // elo (o aproximación por nivel); 0 = desconocido
+	Group  string // party: los del mismo grupo van al mismo equipo ("" = solo)
+}
+
+type Split struct {
+	Team1 []Player
+	Team2 []Player
+	Avg1  float64
+	Avg2  float64
+}
+
+// Diff: diferencia absoluta de promedios entre equipos
+func (s Split) Diff() float64 {
+	d := s.Avg1 - s.Avg2
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Strategy reparte jugadores en dos equipos (team1 con n/2, team2 con el resto).
+// Balance, Exhaustive y Greedy lo son; quien arma el match elige cuál usar.
+type Strategy func(players []Player) Split
+
+// StrategyByName: la estrategia por su nombre de configuración (auto | exhaustive | greedy)
+func StrategyByName(name string) (Strategy, bool) {
+	switch name {
+	case "auto":
+		return Balance, true
+	case "exhaustive":
+		return Exhaustive, true
+	case "greedy":
+		return Greedy, true
+	}
+	return nil, false
+}
+
+// Balance (la estrategia por defecto) elige según el tamaño: exhaustiva para matches chicos, greedy para grandes.
+// Los ratings desconocidos (0) se reemplazan por el promedio de los conocidos.
+// Los grupos (parties) nunca se separan, salvo que no exista ningún reparto posible.
+func Balance(players []Player) Split {
+	if len(players) <= ExhaustiveMax {
+		return Exhaustive(players)
+	}
+	return Greedy(players)
+}
+
+// Exhaustive prueba todas las combinaciones y se queda con la de menor diferencia de promedios.
+// Fija al jugador 0 en team1 para no evaluar cada split dos veces.
+func Exhaustive(players []Player) Split {
+	if sp, ok := exhaustive(players, true); ok {
+		return sp
+	}
+	sp, _ := exhaustive(players, false)
+	return sp
+}
+
+// exhaustive: con keepGroups descarta los splits que separan un grupo
+func exhaustive(players []Player, keepGroups bool) (Split, bool) {
+	ps := fillUnknown(players)
+	n := len(ps)
+	if n < 2 {
+		return newSplit(ps, nil), true
+	}
+	k := n / 2
+
+	best := Split{}
+	bestSet := false
+	idx := make([]int, 0, k)
+
+	var rec func(start int)
+	rec = func(start int) {
+		if len(idx) == k {
+			in := make(map[int]bool, k)
+			for _, i := range idx {
+				in[i] = true
+			}
+			var t1, t2 []Player
+			side := make(map[string]bool)
+			for i, p := range ps {
+				if keepGroups && p.Group != "" {
+					if s, seen := side[p.Group]; seen && s != in[i] {
+						return // el grupo quedó partido
+					}
+					side[p.Group] = in[i]
+				}
+				if in[i] {
+					t1 = append(t1, p)
+				} else {
+					t2 = append(t2, p)
+				}
+			}
+			sp := newSplit(t1, t2)
+			if !bestSet || sp.Diff() < best.Diff() {
+				best, bestSet = sp, true
+			}
+			return
+		}
+		for i := start; i < n; i++ {
+			if len(idx) == 0 && i > 0 {
+				return // el jugador 0 siempre va en team1
+			}
+			idx = append(idx, i)
+			rec(i + 1)
+			idx = idx[:len(idx)-1]
+		}
+	}
+	rec(0)
+	return best, bestSet
+}
+
+// Greedy ordena por rating desc y manda cada jugador al equipo con menor suma (respetando cupos).
+// Los grupos se reparten como una unidad (primero los más grandes, que son los difíciles de ubicar);
+// si un grupo no entra entero en ningún equipo se reparte suelto.
+func Greedy(players []Player) Split {
+	ps := fillUnknown(players)
+
+	var units [][]Player
+	byGroup := make(map[string]int)
+	for _, p := range ps {
+		if p.Group == "" {
+			units = append(units, []Player{p})
+			continue
+		}
+		if i, ok := byGroup[p.Group]; ok {
+			units[i] = append(units[i], p)
+			continue
+		}
+		byGroup[p.Group] = len(units)
+		units = append(units, []Player{p})
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		if len(units[i]) != len(units[j]) {
+			return len(units[i]) > len(units[j])
+		}
+		return sumRating(units[i]) > sumRating(units[j])
+	})
+
+	cap1 := len(ps) / 2
+	cap2 := len(ps) - cap1
+	var t1, t2 []Player
+	var sum1, sum2 int
+	for _, u := range units {
+		fit1 := len(t1)+len(u) <= cap1
+		fit2 := len(t2)+len(u) <= cap2
+		switch {
+		case fit1 && (!fit2 || sum1 <= sum2):
+			t1, sum1 = append(t1, u...), sum1+sumRating(u)
+		case fit2:
+			t2, sum2 = append(t2, u...), sum2+sumRating(u)
+		default:
+			for _, p := range u {
+				if len(t1) < cap1 && (sum1 <= sum2 || len(t2) >= cap2) {
+					t1, sum1 = append(t1, p), sum1+p.Rating
+				} else {
+					t2, sum2 = append(t2, p), sum2+p.Rating
+				}
+			}
+		}
+	}
+	return newSplit(t1, t2)
+}
+
+// CanSplit: si los grupos (por tamaño) se pueden repartir en dos equipos de exactamente `half`
+func CanSplit(sizes []int, half int) bool {
+	reach := make([]bool, half+1)
+	reach[0] = true
+	total := 0
+	for _, s := range sizes {
+		total += s
+		for v := half; v >= s; v-- {
+			if reach[v-s] {
+				reach[v] = true
+			}
+		}
+	}
+	return total == 2*half && reach[half]
+}
+
+// pickBudget: tope de nodos del backtracking de PickGroups (la cola es chica, es sólo un seguro)
+const pickBudget = 20000
+
+// PickGroups elige qué grupos de la cola (en orden de llegada, por tamaño) forman un match de `size`:
+// respeta el orden todo lo posible, no separa grupos y exige que se puedan armar dos equipos iguales.
+// Devuelve los índices elegidos (en orden) o ok=false si con esta cola no se puede.
+func PickGroups(sizes []int, size int) ([]int, bool) {
+	if size <= 0 || size%2 != 0 {
+		return nil, false
+	}
+	half := size / 2
+	chosen := make([]int, 0, size)
+	budget := pickBudget
+
+	var rec func(i, total int) bool
+	rec = func(i, total int) bool {
+		if total == size {
+			picked := make([]int, 0, len(chosen))
+			for _, c := range chosen {
+				picked = append(picked, sizes[c])
+			}
+			return CanSplit(picked, half)
+		}
+		if i >= len(sizes) || budget <= 0 {
+			return false
+		}
+		budget--
+		// primero intentamos incluirlo (respeta el orden de llegada), después saltearlo
+		if s := sizes[i]; s > 0 && s <= half && total+s <= size {
+			chosen = append(chosen, i)
+			if rec(i+1, total+s) {
+				return true
+			}
+			chosen = chosen[:len(chosen)-1]
+		}
+		return rec(i+1, total)
+	}
+	if !rec(0, 0) {
+		return nil, false
+	}
+	return chosen, true
+}
+
+// RatingFromLevel: elo aproximado (mitad del rango) para cuando sólo tenemos el nivel FACEIT
+func RatingFromLevel(level int) int {
+	switch level {
+	case 1:
+		return 300
+	case 2:
+		return 625
+	case 3:
+		return 825
+	case 4:
+		return 975
+	case 5:
+		return 1125
+	case 6:
+		return 1275
+	case 7:
+		return 1440
+	case 8:
+		return 1640
+	case 9:
+		return 1875
+	case 10:
+		return 2150
+	default:
+		return 0
+	}
+}
+
+func newSplit(t1, t2 []Player) Split {
+	return Split{Team1: t1, Team2: t2, Avg1: avg(t1), Avg2: avg(t2)}
+}
+
+func sumRating(ps []Player) int {
+	sum := 0
+	for _, p := range ps {
+		sum += p.Rating
+	}
+	return sum
+}
+
+func avg(ps []Player) float64 {
+	if len(ps) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, p := range ps {
+		sum += p.Rating
+	}
+	return float64(sum) / float64(len(ps))
+}
+
+// fillUnknown: copia los jugadores reemplazando rating 0 por el promedio de los conocidos
+func fillUnknown(players []Player) []Player {
+	out := make([]Player, len(players))
+	copy(out, players)
+	sum, n := 0, 0
+	for _, p := range out {
+		if p.Rating > 0 {
+			sum += p.Rating
+			n++
+		}
+	}
+	if n == 0 || n == len(out) {
+		return out
+	}
+	mean := sum / n
+	for i := range out {
+		if out[i].Rating <= 0 {
+			out[i].Rating = mean
+		}
+	}
+	return out
+}
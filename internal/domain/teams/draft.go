@@ -0,0 +1,33 @@
+package teams
+
+import "sort"
+
+// SnakeOrder: a qué equipo le toca cada pick del draft (1-2-2-2-1 para 8 picks).
+// El primer pick es del team 1 y después se alternan de a dos.
+func SnakeOrder(picks int) []int {
+	out := make([]int, 0, picks)
+	for i := 0; i < picks; i++ {
+		if i == 0 {
+			out = append(out, 1)
+			continue
+		}
+		// i=1,2 → team 2; i=3,4 → team 1; ...
+		if ((i-1)/2)%2 == 0 {
+			out = append(out, 2)
+		} else {
+			out = append(out, 1)
+		}
+	}
+	return out
+}
+
+// Captains: los dos jugadores de mayor rating. El de menor rating de los dos es el
+// captain1 (team 1) y elige primero, para compensar.
+func Captains(players []Player) (c1, c2 Player, ok bool) {
+	if len(players) < 2 {
+		return Player{}, Player{}, false
+	}
+	ps := fillUnknown(players)
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].Rating > ps[j].Rating })
+	return ps[1], ps[0], true
+}
@@ -3,20 +3,35 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"strings"
 )
 
 type Config struct {
 	DatabaseURL     string
 	DiscordToken    string
-	DiscordGuild    string
+	DiscordGuild    string // guild "home" (opcional): defaults del .env y registro inmediato de comandos
 	FaceitAPIKey    string
-	FaceitHubID     string
+	FaceitHubID     string // default para guilds sin hubs propios (/setup hub add)
 	WebhookSecret   string
 	HTTPAddr        string
 	VoiceCategoryID string
 	AFKChannelID    string
+	NotifyChannelID string   // fallback si el usuario tiene los DMs cerrados, sólo en el guild home (vacío = canal de la cola)
 	AdminRoleIDs    []string `env:"ADMIN_ROLE_IDS"`
+	CommandsGlobal  bool     // DISCORD_COMMANDS_GLOBAL=true: slash commands globales en vez de por guild
+	TeamStrategy    string   // TEAM_STRATEGY: auto (default) | exhaustive | greedy, para el modo balance
+
+	// OAuth FACEIT para /link (si falta el client id, /link sigue siendo por nickname)
+	FaceitOAuthClientID     string
+	FaceitOAuthClientSecret string
+	FaceitOAuthRedirectURL  string // https://<host>/faceit/oauth/callback
+	OAuthStateSecret        string // firma HMAC del state (default: client secret)
+
+	// rotación y firma de webhooks (ver internal/infra/webhookauth)
+	WebhookSecretPrevious   string
+	WebhookSigningSecrets   []string // actual + anterior; si hay, se exige firma HMAC
+	WebhookToleranceSeconds int      // ventana del timestamp firmado (0 = default)
 }
 
 func Load() Config {
@@ -31,14 +46,47 @@ func Load() Config {
 	cfg := Config{
 		DatabaseURL:   get("DATABASE_URL", true),
 		DiscordToken:  get("DISCORD_BOT_TOKEN", true),
-		DiscordGuild:  get("DISCORD_GUILD_ID", true),
+		DiscordGuild:  get("DISCORD_GUILD_ID", false),
 		FaceitAPIKey:  get("FACEIT_API_KEY", true),
-		FaceitHubID:   get("FACEIT_HUB_ID", true),
-		WebhookSecret: get("WEBHOOK_HEADER_VALUE", true),
+		FaceitHubID:   get("FACEIT_HUB_ID", false),
+		WebhookSecret: get("WEBHOOK_HEADER_VALUE", false),
 		HTTPAddr:      get("HTTP_ADDR", false), // puede quedar vacío
 		// nuevos
 		VoiceCategoryID: get("VOICE_CATEGORY_ID", false),
 		AFKChannelID:    get("AFK_CHANNEL_ID", false),
+		NotifyChannelID: get("NOTIFY_CHANNEL_ID", false),
+		TeamStrategy:    get("TEAM_STRATEGY", false),
+
+		FaceitOAuthClientID:     get("FACEIT_OAUTH_CLIENT_ID", false),
+		FaceitOAuthClientSecret: get("FACEIT_OAUTH_CLIENT_SECRET", false),
+		FaceitOAuthRedirectURL:  get("FACEIT_OAUTH_REDIRECT_URL", false),
+		OAuthStateSecret:        get("OAUTH_STATE_SECRET", false),
+	}
+	if cfg.FaceitOAuthClientID != "" && (cfg.FaceitOAuthClientSecret == "" || cfg.FaceitOAuthRedirectURL == "") {
+		log.Fatalf("FACEIT_OAUTH_CLIENT_ID requiere FACEIT_OAUTH_CLIENT_SECRET y FACEIT_OAUTH_REDIRECT_URL")
+	}
+	if cfg.OAuthStateSecret == "" {
+		cfg.OAuthStateSecret = cfg.FaceitOAuthClientSecret
+	}
+	cfg.WebhookSecretPrevious = get("WEBHOOK_HEADER_VALUE_PREVIOUS", false)
+	for _, k := range []string{"WEBHOOK_SIGNING_SECRET", "WEBHOOK_SIGNING_SECRET_PREVIOUS"} {
+		if v := strings.TrimSpace(get(k, false)); v != "" {
+			cfg.WebhookSigningSecrets = append(cfg.WebhookSigningSecrets, v)
+		}
+	}
+	if cfg.WebhookSecret == "" && len(cfg.WebhookSigningSecrets) == 0 {
+		log.Fatalf("faltante env WEBHOOK_HEADER_VALUE (o WEBHOOK_SIGNING_SECRET)")
+	}
+	if v := get("WEBHOOK_TOLERANCE_SECONDS", false); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("WEBHOOK_TOLERANCE_SECONDS inválido: %q", v)
+		}
+		cfg.WebhookToleranceSeconds = n
+	}
+	switch strings.ToLower(get("DISCORD_COMMANDS_GLOBAL", false)) {
+	case "1", "true", "yes":
+		cfg.CommandsGlobal = true
 	}
 	if cfg.HTTPAddr == "" {
 		cfg.HTTPAddr = ":8080"
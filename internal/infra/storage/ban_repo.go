@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type QueueBan struct {
+	ID            int64
+	GuildID       string
+	DiscordUserID string
+	Reason        string
+	IssuedBy      string
+	Tier          int
+	CreatedAt     time.Time
+	ExpiresAt     *time.Time // nil = permanente
+}
+
+type BanRepo struct{ db *sql.DB }
+
+func NewBanRepo(db *sql.DB) *BanRepo { return &BanRepo{db: db} }
+
+const queueBanCols = `id, guild_id, discord_user_id, reason, issued_by, tier, created_at, expires_at`
+
+// activo = no revocado y sin vencer
+const activeBan = `revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`
+
+func (r *BanRepo) Add(ctx context.Context, b QueueBan) (QueueBan, error) {
+	err := r.db.QueryRowContext(ctx, `
+INSERT INTO queue_bans (guild_id, discord_user_id, reason, issued_by, tier, expires_at)
+VALUES ($1,$2,$3,$4,$5,$6)
+RETURNING id, created_at
+`, b.GuildID, b.DiscordUserID, b.Reason, b.IssuedBy, b.Tier, b.ExpiresAt).Scan(&b.ID, &b.CreatedAt)
+	return b, err
+}
+
+// Active: el ban vigente que más tarde vence (los permanentes primero)
+func (r *BanRepo) Active(ctx context.Context, guildID, discordID string) (QueueBan, bool, error) {
+	row := r.db.QueryRowContext(ctx, `
+SELECT `+queueBanCols+`
+  FROM queue_bans
+ WHERE guild_id = $1 AND discord_user_id = $2 AND `+activeBan+`
+ ORDER BY expires_at DESC NULLS FIRST
+ LIMIT 1
+`, guildID, discordID)
+	b, err := scanQueueBan(row)
+	if err == sql.ErrNoRows {
+		return QueueBan{}, false, nil
+	}
+	if err != nil {
+		return QueueBan{}, false, err
+	}
+	return b, true, nil
+}
+
+// ListActive: bans vigentes del guild, los que vencen antes primero
+func (r *BanRepo) ListActive(ctx context.Context, guildID string) ([]QueueBan, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+queueBanCols+`
+  FROM queue_bans
+ WHERE guild_id = $1 AND `+activeBan+`
+ ORDER BY expires_at ASC NULLS LAST
+`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []QueueBan
+	for rows.Next() {
+		b, err := scanQueueBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// Revoke: levanta los bans vigentes del jugador; devuelve cuántos
+func (r *BanRepo) Revoke(ctx context.Context, guildID, discordID, revokedBy string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE queue_bans SET revoked_at = now(), revoked_by = $3
+ WHERE guild_id = $1 AND discord_user_id = $2 AND `+activeBan+`
+`, guildID, discordID, revokedBy)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CountSince: bans (no revocados) desde since; alimenta la escalada
+func (r *BanRepo) CountSince(ctx context.Context, guildID, discordID string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM queue_bans
+ WHERE guild_id = $1 AND discord_user_id = $2 AND revoked_at IS NULL AND created_at >= $3
+`, guildID, discordID, since).Scan(&n)
+	return n, err
+}
+
+func scanQueueBan(row interface{ Scan(...any) error }) (QueueBan, error) {
+	var b QueueBan
+	err := row.Scan(&b.ID, &b.GuildID, &b.DiscordUserID, &b.Reason, &b.IssuedBy, &b.Tier, &b.CreatedAt, &b.ExpiresAt)
+	return b, err
+}
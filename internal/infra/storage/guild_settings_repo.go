@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type GuildSettings struct {
+	GuildID         string
+	VoiceCategoryID string // categoría de voz válida para la cola ("" = cualquiera)
+	AFKChannelID    string
+	MaxQueues       int    // en cuántas colas con nombre puede estar un jugador a la vez
+	MaxPartySize    int    // jugadores por party (líder incluido)
+	PriorityAging   int    // minutos de espera que valen +1 de prioridad (0 = orden estricto)
+	Timezone        string // zona IANA para los horarios de las colas ("" = UTC)
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// PriorityRole: peso en la cola para quienes tienen ese rol (gana el más alto)
+type PriorityRole struct {
+	GuildID   string
+	RoleID    string
+	Weight    int
+	CreatedAt time.Time
+}
+
+// GuildHub: hub FACEIT asociado a un guild (puede haber varios)
+type GuildHub struct {
+	GuildID   string
+	HubID     string
+	Name      string
+	CreatedAt time.Time
+}
+
+type GuildSettingsRepo struct{ db *sql.DB }
+
+func NewGuildSettingsRepo(db *sql.DB) *GuildSettingsRepo { return &GuildSettingsRepo{db: db} }
+
+// Get: ErrNotFound si el guild todavía no se configuró
+func (r *GuildSettingsRepo) Get(ctx context.Context, guildID string) (GuildSettings, error) {
+	var g GuildSettings
+	err := r.db.QueryRowContext(ctx, `
+SELECT guild_id, voice_category_id, afk_channel_id, max_queues_per_player, max_party_size, priority_aging_minutes, timezone, created_at, updated_at
+  FROM guild_settings
+ WHERE guild_id = $1
+`, guildID).Scan(&g.GuildID, &g.VoiceCategoryID, &g.AFKChannelID, &g.MaxQueues, &g.MaxPartySize, &g.PriorityAging, &g.Timezone, &g.CreatedAt, &g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return GuildSettings{}, ErrNotFound
+	}
+	return g, err
+}
+
+func (r *GuildSettingsRepo) Upsert(ctx context.Context, g GuildSettings) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO guild_settings (guild_id, voice_category_id, afk_channel_id, max_queues_per_player, max_party_size, priority_aging_minutes, timezone)
+VALUES ($1,$2,$3,$4,$5,$6,COALESCE(NULLIF($7,''),'UTC'))
+ON CONFLICT (guild_id) DO UPDATE SET
+  voice_category_id      = EXCLUDED.voice_category_id,
+  afk_channel_id         = EXCLUDED.afk_channel_id,
+  max_queues_per_player  = EXCLUDED.max_queues_per_player,
+  max_party_size         = EXCLUDED.max_party_size,
+  priority_aging_minutes = EXCLUDED.priority_aging_minutes,
+  timezone               = EXCLUDED.timezone,
+  updated_at             = now()
+`, g.GuildID, g.VoiceCategoryID, g.AFKChannelID, g.MaxQueues, g.MaxPartySize, g.PriorityAging, g.Timezone)
+	return err
+}
+
+func (r *GuildSettingsRepo) ListHubs(ctx context.Context, guildID string) ([]GuildHub, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT guild_id, hub_id, name, created_at
+  FROM guild_hubs
+ WHERE guild_id = $1
+ ORDER BY created_at
+`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GuildHub
+	for rows.Next() {
+		var h GuildHub
+		if err := rows.Scan(&h.GuildID, &h.HubID, &h.Name, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// AddHub: alta o refresco del nombre si ya estaba
+func (r *GuildSettingsRepo) AddHub(ctx context.Context, h GuildHub) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO guild_hubs (guild_id, hub_id, name)
+VALUES ($1,$2,$3)
+ON CONFLICT (guild_id, hub_id) DO UPDATE SET name = EXCLUDED.name
+`, h.GuildID, h.HubID, h.Name)
+	return err
+}
+
+func (r *GuildSettingsRepo) RemoveHub(ctx context.Context, guildID, hubID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM guild_hubs WHERE guild_id = $1 AND hub_id = $2`, guildID, hubID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GuildForHub: qué guild tiene configurado ese hub (para rutear webhooks de matches)
+func (r *GuildSettingsRepo) GuildForHub(ctx context.Context, hubID string) (string, error) {
+	var guildID string
+	err := r.db.QueryRowContext(ctx, `
+SELECT guild_id FROM guild_hubs WHERE hub_id = $1 ORDER BY created_at DESC LIMIT 1
+`, hubID).Scan(&guildID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return guildID, err
+}
+
+func (r *GuildSettingsRepo) ListPriorityRoles(ctx context.Context, guildID string) ([]PriorityRole, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT guild_id, role_id, weight, created_at
+  FROM queue_priority_roles
+ WHERE guild_id = $1
+ ORDER BY weight DESC, created_at
+`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PriorityRole
+	for rows.Next() {
+		var pr PriorityRole
+		if err := rows.Scan(&pr.GuildID, &pr.RoleID, &pr.Weight, &pr.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, pr)
+	}
+	return out, rows.Err()
+}
+
+// SetPriorityRole: alta o cambio de peso
+func (r *GuildSettingsRepo) SetPriorityRole(ctx context.Context, pr PriorityRole) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO queue_priority_roles (guild_id, role_id, weight)
+VALUES ($1,$2,$3)
+ON CONFLICT (guild_id, role_id) DO UPDATE SET weight = EXCLUDED.weight
+`, pr.GuildID, pr.RoleID, pr.Weight)
+	return err
+}
+
+func (r *GuildSettingsRepo) RemovePriorityRole(ctx context.Context, guildID, roleID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM queue_priority_roles WHERE guild_id = $1 AND role_id = $2`, guildID, roleID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
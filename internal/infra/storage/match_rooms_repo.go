@@ -60,6 +60,30 @@ UPDATE match_voice_rooms SET last_status=$2, updated_at=now() WHERE match_id=$1
 	return err
 }
 
+// ListExpired: salas con vencimiento ya pasado (las de matches armados por el bot)
+func (r *MatchRoomsRepo) ListExpired(ctx context.Context) ([]MatchVoiceRoom, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT match_id, guild_id, category_id, team1_channel_id, team2_channel_id,
+       team1_label, team2_label, last_status, created_at, updated_at, expires_at
+  FROM match_voice_rooms
+ WHERE expires_at IS NOT NULL AND expires_at <= now()
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MatchVoiceRoom
+	for rows.Next() {
+		var m MatchVoiceRoom
+		if err := rows.Scan(&m.MatchID, &m.GuildID, &m.CategoryID, &m.Team1ChannelID, &m.Team2ChannelID,
+			&m.Team1Label, &m.Team2Label, &m.LastStatus, &m.CreatedAt, &m.UpdatedAt, &m.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
 func (r *MatchRoomsRepo) Delete(ctx context.Context, matchID string) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM match_voice_rooms WHERE match_id=$1`, matchID)
 	return err
@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS match_size integer NOT NULL DEFAULT 10;
+
+CREATE TABLE IF NOT EXISTS pending_matches (
+  id          BIGSERIAL PRIMARY KEY,
+  guild_id    text NOT NULL,
+  status      text NOT NULL DEFAULT 'pending',
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  updated_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_pending_matches_guild_status
+  ON pending_matches (guild_id, status);
+
+CREATE TABLE IF NOT EXISTS pending_match_players (
+  match_id        bigint NOT NULL REFERENCES pending_matches (id) ON DELETE CASCADE,
+  discord_user_id text NOT NULL,
+  faceit_user_id  text NOT NULL,
+  nickname        text NOT NULL,
+  joined_at       timestamptz NOT NULL,
+  PRIMARY KEY (match_id, discord_user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_match_players;
+DROP TABLE IF EXISTS pending_matches;
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS match_size;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE pending_match_players
+  ADD COLUMN IF NOT EXISTS team   smallint,  -- 1 | 2 (NULL = sin asignar)
+  ADD COLUMN IF NOT EXISTS rating integer;   -- elo usado para balancear
+
+-- +goose Down
+ALTER TABLE pending_match_players
+  DROP COLUMN IF EXISTS rating,
+  DROP COLUMN IF EXISTS team;
@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS ready_check_seconds   integer NOT NULL DEFAULT 60,  -- 0 = sin ready-check
+  ADD COLUMN IF NOT EXISTS ready_penalty_seconds integer NOT NULL DEFAULT 300; -- castigo por decline/timeout
+
+ALTER TABLE pending_matches
+  ADD COLUMN IF NOT EXISTS ready_deadline timestamptz,
+  ADD COLUMN IF NOT EXISTS channel_id     text,
+  ADD COLUMN IF NOT EXISTS message_id     text;
+
+ALTER TABLE pending_match_players
+  ADD COLUMN IF NOT EXISTS ready_state  text NOT NULL DEFAULT 'pending', -- pending|accepted|declined|timeout
+  ADD COLUMN IF NOT EXISTS responded_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS queue_penalties (
+  id              BIGSERIAL PRIMARY KEY,
+  guild_id        text NOT NULL,
+  discord_user_id text NOT NULL,
+  reason          text NOT NULL,
+  created_at      timestamptz NOT NULL DEFAULT now(),
+  expires_at      timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_queue_penalties_user
+  ON queue_penalties (guild_id, discord_user_id, expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS queue_penalties;
+ALTER TABLE pending_match_players
+  DROP COLUMN IF EXISTS responded_at,
+  DROP COLUMN IF EXISTS ready_state;
+ALTER TABLE pending_matches
+  DROP COLUMN IF EXISTS message_id,
+  DROP COLUMN IF EXISTS channel_id,
+  DROP COLUMN IF EXISTS ready_deadline;
+ALTER TABLE guild_policies
+  DROP COLUMN IF EXISTS ready_penalty_seconds,
+  DROP COLUMN IF EXISTS ready_check_seconds;
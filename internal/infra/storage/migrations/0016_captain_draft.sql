@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS team_mode text NOT NULL DEFAULT 'balance'; -- balance | captains
+
+ALTER TABLE pending_matches
+  ADD COLUMN IF NOT EXISTS mode             text NOT NULL DEFAULT 'balance',
+  ADD COLUMN IF NOT EXISTS captain1_id      text, -- team 1, elige primero
+  ADD COLUMN IF NOT EXISTS captain2_id      text,
+  ADD COLUMN IF NOT EXISTS draft_channel_id text,
+  ADD COLUMN IF NOT EXISTS draft_message_id text;
+
+CREATE TABLE IF NOT EXISTS match_draft_picks (
+  match_id        bigint NOT NULL REFERENCES pending_matches (id) ON DELETE CASCADE,
+  pick_no         integer NOT NULL, -- 0..n (orden snake)
+  team            smallint NOT NULL,
+  captain_id      text NOT NULL,
+  discord_user_id text NOT NULL,
+  picked_at       timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (match_id, pick_no),
+  UNIQUE (match_id, discord_user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS match_draft_picks;
+ALTER TABLE pending_matches
+  DROP COLUMN IF EXISTS draft_message_id,
+  DROP COLUMN IF EXISTS draft_channel_id,
+  DROP COLUMN IF EXISTS captain2_id,
+  DROP COLUMN IF EXISTS captain1_id,
+  DROP COLUMN IF EXISTS mode;
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS team_mode;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS queue_events (
+  id              BIGSERIAL PRIMARY KEY,
+  guild_id        text NOT NULL,
+  discord_user_id text NOT NULL,
+  event           text NOT NULL, -- join
+  created_at      timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_queue_events_guild_event
+  ON queue_events (guild_id, event, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS queue_events;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- join | rejoin | leave | kick | left | afk | back | pruned | popped | requeued
+ALTER TABLE queue_events
+  ADD COLUMN IF NOT EXISTS actor  text NOT NULL DEFAULT 'system', -- discord_user_id o 'system'
+  ADD COLUMN IF NOT EXISTS reason text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_queue_events_user
+  ON queue_events (guild_id, discord_user_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_queue_events_user;
+ALTER TABLE queue_events
+  DROP COLUMN IF EXISTS reason,
+  DROP COLUMN IF EXISTS actor;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS join_enforcement text NOT NULL DEFAULT 'warn'; -- warn | block | remove
+
+-- status ahora también puede ser 'blocked' (falló una validación post-join)
+ALTER TABLE queue_entries
+  ADD COLUMN IF NOT EXISTS status_reason text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE queue_entries DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS join_enforcement;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- oauth_states (0002) pasa a usarse para el flujo authorization-code + PKCE de /link
+ALTER TABLE oauth_states
+  ADD COLUMN IF NOT EXISTS guild_id      text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS code_verifier text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS used_at       timestamptz; -- un state sirve una sola vez
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires ON oauth_states (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_oauth_states_expires;
+ALTER TABLE oauth_states
+  DROP COLUMN IF EXISTS used_at,
+  DROP COLUMN IF EXISTS code_verifier,
+  DROP COLUMN IF EXISTS guild_id;
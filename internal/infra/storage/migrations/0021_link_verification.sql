@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE user_links
+  ADD COLUMN IF NOT EXISTS verified_at            timestamptz,
+  ADD COLUMN IF NOT EXISTS verification_method    text,        -- oauth | code
+  ADD COLUMN IF NOT EXISTS verify_code            text,        -- código a poner en el perfil FACEIT
+  ADD COLUMN IF NOT EXISTS verify_code_expires_at timestamptz;
+
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS require_verified boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS require_verified;
+ALTER TABLE user_links
+  DROP COLUMN IF EXISTS verify_code_expires_at,
+  DROP COLUMN IF EXISTS verify_code,
+  DROP COLUMN IF EXISTS verification_method,
+  DROP COLUMN IF EXISTS verified_at;
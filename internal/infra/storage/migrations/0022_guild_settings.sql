@@ -0,0 +1,14 @@
+-- +goose Up
+-- config por guild (antes venía del .env y el proceso servía un solo guild)
+CREATE TABLE IF NOT EXISTS guild_settings (
+  guild_id          text PRIMARY KEY,
+  hub_id            text NOT NULL DEFAULT '',
+  voice_category_id text NOT NULL DEFAULT '',
+  afk_channel_id    text NOT NULL DEFAULT '',
+  created_at        timestamptz NOT NULL DEFAULT now(),
+  updated_at        timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_guild_settings_hub ON guild_settings (hub_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS guild_settings;
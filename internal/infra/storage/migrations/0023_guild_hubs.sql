@@ -0,0 +1,26 @@
+-- +goose Up
+-- un guild puede apuntar a varios hubs (ej: hub principal + academy)
+CREATE TABLE IF NOT EXISTS guild_hubs (
+  guild_id   text NOT NULL,
+  hub_id     text NOT NULL,
+  name       text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (guild_id, hub_id)
+);
+CREATE INDEX IF NOT EXISTS idx_guild_hubs_hub ON guild_hubs (hub_id);
+
+INSERT INTO guild_hubs (guild_id, hub_id)
+SELECT guild_id, hub_id FROM guild_settings WHERE hub_id <> ''
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_guild_settings_hub;
+ALTER TABLE guild_settings DROP COLUMN IF EXISTS hub_id;
+
+-- +goose Down
+ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS hub_id text NOT NULL DEFAULT '';
+UPDATE guild_settings gs
+   SET hub_id = h.hub_id
+  FROM (SELECT DISTINCT ON (guild_id) guild_id, hub_id FROM guild_hubs ORDER BY guild_id, created_at) h
+ WHERE h.guild_id = gs.guild_id;
+CREATE INDEX IF NOT EXISTS idx_guild_settings_hub ON guild_settings (hub_id);
+DROP TABLE IF EXISTS guild_hubs;
@@ -0,0 +1,53 @@
+-- +goose Up
+-- colas con nombre por guild ("main", "lvl 1-5", "5v5 wingman"...).
+-- Todo lo que existía queda en la cola 'main'.
+ALTER TABLE queue_entries ADD COLUMN IF NOT EXISTS queue_name text NOT NULL DEFAULT 'main';
+ALTER TABLE queue_entries DROP CONSTRAINT IF EXISTS queue_entries_pkey;
+ALTER TABLE queue_entries ADD PRIMARY KEY (guild_id, queue_name, discord_user_id);
+DROP INDEX IF EXISTS idx_queue_entries_guild_joined;
+CREATE INDEX IF NOT EXISTS idx_queue_entries_queue_joined
+  ON queue_entries (guild_id, queue_name, joined_at);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_user
+  ON queue_entries (guild_id, discord_user_id);
+
+-- cada cola tiene su policy
+ALTER TABLE guild_policies ADD COLUMN IF NOT EXISTS queue_name text NOT NULL DEFAULT 'main';
+ALTER TABLE guild_policies DROP CONSTRAINT IF EXISTS guild_policies_pkey;
+ALTER TABLE guild_policies ADD PRIMARY KEY (guild_id, queue_name);
+
+-- ...su mensaje de UI y su categoría de voz ('' = la del guild)
+ALTER TABLE guild_ui ADD COLUMN IF NOT EXISTS queue_name text NOT NULL DEFAULT 'main';
+ALTER TABLE guild_ui ADD COLUMN IF NOT EXISTS voice_category_id text NOT NULL DEFAULT '';
+ALTER TABLE guild_ui DROP CONSTRAINT IF EXISTS guild_ui_pkey;
+ALTER TABLE guild_ui ADD PRIMARY KEY (guild_id, queue_name);
+
+ALTER TABLE queue_events ADD COLUMN IF NOT EXISTS queue_name text NOT NULL DEFAULT 'main';
+ALTER TABLE pending_matches ADD COLUMN IF NOT EXISTS queue_name text NOT NULL DEFAULT 'main';
+
+-- en cuántas colas a la vez puede estar un jugador
+ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS max_queues_per_player integer NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE guild_settings DROP COLUMN IF EXISTS max_queues_per_player;
+ALTER TABLE pending_matches DROP COLUMN IF EXISTS queue_name;
+ALTER TABLE queue_events DROP COLUMN IF EXISTS queue_name;
+
+DELETE FROM guild_ui WHERE queue_name <> 'main';
+ALTER TABLE guild_ui DROP CONSTRAINT IF EXISTS guild_ui_pkey;
+ALTER TABLE guild_ui DROP COLUMN IF EXISTS voice_category_id;
+ALTER TABLE guild_ui DROP COLUMN IF EXISTS queue_name;
+ALTER TABLE guild_ui ADD PRIMARY KEY (guild_id);
+
+DELETE FROM guild_policies WHERE queue_name <> 'main';
+ALTER TABLE guild_policies DROP CONSTRAINT IF EXISTS guild_policies_pkey;
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS queue_name;
+ALTER TABLE guild_policies ADD PRIMARY KEY (guild_id);
+
+DROP INDEX IF EXISTS idx_queue_entries_user;
+DROP INDEX IF EXISTS idx_queue_entries_queue_joined;
+DELETE FROM queue_entries WHERE queue_name <> 'main';
+ALTER TABLE queue_entries DROP CONSTRAINT IF EXISTS queue_entries_pkey;
+ALTER TABLE queue_entries DROP COLUMN IF EXISTS queue_name;
+ALTER TABLE queue_entries ADD PRIMARY KEY (guild_id, discord_user_id);
+CREATE INDEX IF NOT EXISTS idx_queue_entries_guild_joined
+  ON queue_entries (guild_id, joined_at);
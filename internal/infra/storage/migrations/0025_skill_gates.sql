@@ -0,0 +1,19 @@
+-- +goose Up
+-- rango de nivel/elo por cola (0 = sin límite)
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS min_skill_level int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS max_skill_level int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS min_elo int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS max_elo int NOT NULL DEFAULT 0;
+
+-- cuándo se tomaron elo_snapshot/skill_level_snapshot (para saber si están viejos)
+ALTER TABLE user_links
+  ADD COLUMN IF NOT EXISTS snapshot_at timestamptz;
+
+-- +goose Down
+ALTER TABLE user_links DROP COLUMN IF EXISTS snapshot_at;
+ALTER TABLE guild_policies
+  DROP COLUMN IF EXISTS max_elo,
+  DROP COLUMN IF EXISTS min_elo,
+  DROP COLUMN IF EXISTS max_skill_level,
+  DROP COLUMN IF EXISTS min_skill_level;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- parties (premades): entran y salen de la cola juntas y caen en el mismo equipo
+CREATE TABLE IF NOT EXISTS parties (
+  id         BIGSERIAL PRIMARY KEY,
+  guild_id   text NOT NULL,
+  leader_id  text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS party_members (
+  party_id        bigint NOT NULL REFERENCES parties (id) ON DELETE CASCADE,
+  guild_id        text NOT NULL,
+  discord_user_id text NOT NULL,
+  state           text NOT NULL DEFAULT 'invited', -- invited | accepted
+  invited_by      text NOT NULL,
+  created_at      timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (party_id, discord_user_id)
+);
+-- una sola party (aceptada) por jugador y guild
+CREATE UNIQUE INDEX IF NOT EXISTS uq_party_members_accepted
+  ON party_members (guild_id, discord_user_id) WHERE state = 'accepted';
+
+ALTER TABLE queue_entries
+  ADD COLUMN IF NOT EXISTS party_id bigint REFERENCES parties (id) ON DELETE SET NULL;
+ALTER TABLE pending_match_players
+  ADD COLUMN IF NOT EXISTS party_id bigint;
+
+ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS max_party_size integer NOT NULL DEFAULT 2;
+
+-- +goose Down
+ALTER TABLE guild_settings DROP COLUMN IF EXISTS max_party_size;
+ALTER TABLE pending_match_players DROP COLUMN IF EXISTS party_id;
+ALTER TABLE queue_entries DROP COLUMN IF EXISTS party_id;
+DROP TABLE IF EXISTS party_members;
+DROP TABLE IF EXISTS parties;
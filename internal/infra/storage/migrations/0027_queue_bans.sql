@@ -0,0 +1,19 @@
+-- +goose Up
+-- bans de cola puestos por admins (las penalizaciones automáticas siguen en queue_penalties)
+CREATE TABLE IF NOT EXISTS queue_bans (
+  id              BIGSERIAL PRIMARY KEY,
+  guild_id        text NOT NULL,
+  discord_user_id text NOT NULL,
+  reason          text NOT NULL,
+  issued_by       text NOT NULL,
+  tier            integer NOT NULL DEFAULT 1, -- escalón de la escalada (1 = primer ban)
+  created_at      timestamptz NOT NULL DEFAULT now(),
+  expires_at      timestamptz,                -- NULL = permanente
+  revoked_at      timestamptz,
+  revoked_by      text
+);
+CREATE INDEX IF NOT EXISTS idx_queue_bans_user
+  ON queue_bans (guild_id, discord_user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS queue_bans;
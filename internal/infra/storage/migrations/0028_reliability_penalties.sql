@@ -0,0 +1,16 @@
+-- +goose Up
+-- penalizaciones automáticas por salir/ser podado de la cola seguido (0 = apagado)
+ALTER TABLE guild_policies
+  ADD COLUMN IF NOT EXISTS leave_limit           integer NOT NULL DEFAULT 0,   -- salidas toleradas en la ventana
+  ADD COLUMN IF NOT EXISTS leave_window_minutes  integer NOT NULL DEFAULT 60,
+  ADD COLUMN IF NOT EXISTS leave_penalty_seconds integer NOT NULL DEFAULT 300; -- primer cooldown; se duplica al reincidir
+
+ALTER TABLE queue_penalties
+  ADD COLUMN IF NOT EXISTS kind text NOT NULL DEFAULT 'ready_check'; -- ready_check | reliability
+
+-- +goose Down
+ALTER TABLE queue_penalties DROP COLUMN IF EXISTS kind;
+ALTER TABLE guild_policies
+  DROP COLUMN IF EXISTS leave_penalty_seconds,
+  DROP COLUMN IF EXISTS leave_window_minutes,
+  DROP COLUMN IF EXISTS leave_limit;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- prioridad en la cola por rol de Discord (VIP, staff, ganadores de eventos)
+CREATE TABLE IF NOT EXISTS queue_priority_roles (
+  guild_id   text NOT NULL,
+  role_id    text NOT NULL,
+  weight     integer NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (guild_id, role_id)
+);
+
+-- la prioridad se fija al entrar (con los roles de ese momento)
+ALTER TABLE queue_entries ADD COLUMN IF NOT EXISTS priority integer NOT NULL DEFAULT 0;
+-- para devolverla intacta si el ready-check falla
+ALTER TABLE pending_match_players ADD COLUMN IF NOT EXISTS priority integer NOT NULL DEFAULT 0;
+
+-- envejecimiento: +1 de prioridad por cada N minutos esperando (0 = orden estricto)
+ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS priority_aging_minutes integer NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE guild_settings DROP COLUMN IF EXISTS priority_aging_minutes;
+ALTER TABLE pending_match_players DROP COLUMN IF EXISTS priority;
+ALTER TABLE queue_entries DROP COLUMN IF EXISTS priority;
+DROP TABLE IF EXISTS queue_priority_roles;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- ventanas en las que la cola acepta joins (sin filas = siempre abierta)
+CREATE TABLE IF NOT EXISTS queue_schedules (
+  id           BIGSERIAL PRIMARY KEY,
+  guild_id     text NOT NULL,
+  queue_name   text NOT NULL DEFAULT 'main',
+  weekday      integer NOT NULL CHECK (weekday BETWEEN 0 AND 6), -- 0 = domingo
+  open_minute  integer NOT NULL CHECK (open_minute BETWEEN 0 AND 1439),
+  close_minute integer NOT NULL CHECK (close_minute BETWEEN 0 AND 1439), -- <= open: cierra al día siguiente
+  created_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_queue_schedules_queue ON queue_schedules (guild_id, queue_name);
+
+-- los horarios se leen en la zona del guild
+ALTER TABLE guild_settings ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+
+-- al cerrar, vaciar la cola (con aviso)
+ALTER TABLE guild_policies ADD COLUMN IF NOT EXISTS flush_on_close boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE guild_policies DROP COLUMN IF EXISTS flush_on_close;
+ALTER TABLE guild_settings DROP COLUMN IF EXISTS timezone;
+DROP TABLE IF EXISTS queue_schedules;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- colas cerradas a mano por un admin (/queue admin lock); sin fila = abierta
+CREATE TABLE IF NOT EXISTS queue_locks (
+  guild_id   text NOT NULL,
+  queue_name text NOT NULL,
+  locked_by  text NOT NULL,
+  reason     text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (guild_id, queue_name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS queue_locks;
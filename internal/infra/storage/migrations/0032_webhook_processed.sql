@@ -0,0 +1,11 @@
+-- +goose Up
+-- el bot marca cada evento cuando lo termina de procesar; al arrancar (o reconectar el LISTEN)
+-- retoma los que quedaron sin marcar
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS processed_at timestamptz;
+-- lo viejo no se reprocesa: sólo cuentan los eventos que lleguen desde ahora
+UPDATE webhook_events SET processed_at = received_at WHERE processed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_events_pending ON webhook_events (id) WHERE processed_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_webhook_events_pending;
+ALTER TABLE webhook_events DROP COLUMN IF EXISTS processed_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- dead letter de webhooks: eventos que fallaron al procesarse, con el último error y los intentos.
+-- Mientras next_attempt_at no sea NULL el worker del bot los reintenta solo; después quedan
+-- para /webhook replay. source: 'bot' (worker) o 'webhook' (receptor, cmd/webhook).
+CREATE TABLE IF NOT EXISTS webhook_failures (
+  event_id        BIGINT      NOT NULL REFERENCES webhook_events(id) ON DELETE CASCADE,
+  source          TEXT        NOT NULL,
+  hub_id          TEXT        NOT NULL DEFAULT '',
+  attempts        INT         NOT NULL DEFAULT 0,
+  last_error      TEXT        NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ,
+  first_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_failed_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  resolved_at     TIMESTAMPTZ,
+  PRIMARY KEY (event_id, source)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_failures_due ON webhook_failures (next_attempt_at) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_failures;
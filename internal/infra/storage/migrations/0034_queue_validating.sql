@@ -0,0 +1,7 @@
+-- +goose Up
+-- mientras corre la validación async del join (hub, derrota, membresía) el pop no toma al
+-- jugador; el vencimiento cubre validaciones que nunca terminan (bot caído a la mitad)
+ALTER TABLE queue_entries ADD COLUMN IF NOT EXISTS validating_until timestamptz;
+
+-- +goose Down
+ALTER TABLE queue_entries DROP COLUMN IF EXISTS validating_until;
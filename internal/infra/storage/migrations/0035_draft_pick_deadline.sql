@@ -0,0 +1,9 @@
+-- +goose Up
+-- draft de capitanes: hasta cuándo tiene el capitán de turno para elegir; vencido, el
+-- sweeper del bot elige por él (el de más elo libre) para que el match no quede colgado
+ALTER TABLE pending_matches ADD COLUMN IF NOT EXISTS pick_deadline timestamptz;
+-- los drafts que ya estaban colgados arrancan a correr desde ahora
+UPDATE pending_matches SET pick_deadline = now() + INTERVAL '1 minute' WHERE status = 'drafting';
+
+-- +goose Down
+ALTER TABLE pending_matches DROP COLUMN IF EXISTS pick_deadline;
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type OAuthState struct {
+	State         string
+	DiscordUserID string
+	GuildID       string
+	CodeVerifier  string // PKCE: se manda en el intercambio del code
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type OAuthStateRepo struct{ db *sql.DB }
+
+func NewOAuthStateRepo(db *sql.DB) *OAuthStateRepo { return &OAuthStateRepo{db: db} }
+
+func (r *OAuthStateRepo) Create(ctx context.Context, st OAuthState) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO oauth_states (state, discord_user_id, guild_id, code_verifier, expires_at)
+VALUES ($1,$2,$3,$4,$5)
+`, st.State, st.DiscordUserID, st.GuildID, st.CodeVerifier, st.ExpiresAt)
+	return err
+}
+
+// Consume: marca el state como usado y lo devuelve. ErrNotFound si no existe, venció o ya se usó.
+func (r *OAuthStateRepo) Consume(ctx context.Context, state string) (OAuthState, error) {
+	var st OAuthState
+	err := r.db.QueryRowContext(ctx, `
+UPDATE oauth_states
+   SET used_at = now()
+ WHERE state = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING state, discord_user_id, guild_id, code_verifier, created_at, expires_at
+`, state).Scan(&st.State, &st.DiscordUserID, &st.GuildID, &st.CodeVerifier, &st.CreatedAt, &st.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return OAuthState{}, ErrNotFound
+	}
+	return st, err
+}
+
+// DeleteExpired: limpieza de states vencidos (los usados también se van al vencer)
+func (r *OAuthStateRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
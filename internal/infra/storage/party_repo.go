@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Party struct {
+	ID        int64
+	GuildID   string
+	LeaderID  string
+	CreatedAt time.Time
+	Members   []PartyMember // aceptados e invitados; el líder primero
+}
+
+type PartyMember struct {
+	DiscordUserID string
+	State         string // invited | accepted
+	InvitedBy     string
+	CreatedAt     time.Time
+}
+
+// Accepted: discord IDs de los que ya están en la party (líder incluido)
+func (p Party) Accepted() []string {
+	out := make([]string, 0, len(p.Members))
+	for _, m := range p.Members {
+		if m.State == "accepted" {
+			out = append(out, m.DiscordUserID)
+		}
+	}
+	return out
+}
+
+type PartyRepo struct{ db *sql.DB }
+
+func NewPartyRepo(db *sql.DB) *PartyRepo { return &PartyRepo{db: db} }
+
+// Create: party nueva con el líder ya adentro
+func (r *PartyRepo) Create(ctx context.Context, guildID, leaderID string) (Party, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Party{}, err
+	}
+	defer tx.Rollback()
+
+	p := Party{GuildID: guildID, LeaderID: leaderID}
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO parties (guild_id, leader_id) VALUES ($1,$2) RETURNING id, created_at
+`, guildID, leaderID).Scan(&p.ID, &p.CreatedAt); err != nil {
+		return Party{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO party_members (party_id, guild_id, discord_user_id, state, invited_by)
+VALUES ($1,$2,$3,'accepted',$3)
+`, p.ID, guildID, leaderID); err != nil {
+		return Party{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Party{}, err
+	}
+	p.Members = []PartyMember{{DiscordUserID: leaderID, State: "accepted", InvitedBy: leaderID, CreatedAt: p.CreatedAt}}
+	return p, nil
+}
+
+func (r *PartyRepo) Get(ctx context.Context, partyID int64) (Party, error) {
+	var p Party
+	err := r.db.QueryRowContext(ctx, `
+SELECT id, guild_id, leader_id, created_at FROM parties WHERE id = $1
+`, partyID).Scan(&p.ID, &p.GuildID, &p.LeaderID, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Party{}, ErrNotFound
+	}
+	if err != nil {
+		return Party{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT discord_user_id, state, invited_by, created_at
+  FROM party_members
+ WHERE party_id = $1
+ ORDER BY (discord_user_id = $2) DESC, created_at ASC
+`, partyID, p.LeaderID)
+	if err != nil {
+		return Party{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m PartyMember
+		if err := rows.Scan(&m.DiscordUserID, &m.State, &m.InvitedBy, &m.CreatedAt); err != nil {
+			return Party{}, err
+		}
+		p.Members = append(p.Members, m)
+	}
+	return p, rows.Err()
+}
+
+// Of: la party en la que está (aceptado) el jugador. ErrNotFound si juega solo.
+func (r *PartyRepo) Of(ctx context.Context, guildID, discordID string) (Party, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+SELECT party_id FROM party_members
+ WHERE guild_id = $1 AND discord_user_id = $2 AND state = 'accepted'
+`, guildID, discordID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return Party{}, ErrNotFound
+	}
+	if err != nil {
+		return Party{}, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Invite: deja la invitación pendiente; false si ya estaba invitado o adentro
+func (r *PartyRepo) Invite(ctx context.Context, partyID int64, guildID, discordID, invitedBy string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+INSERT INTO party_members (party_id, guild_id, discord_user_id, state, invited_by)
+VALUES ($1,$2,$3,'invited',$4)
+ON CONFLICT (party_id, discord_user_id) DO NOTHING
+`, partyID, guildID, discordID, invitedBy)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Accept: la invitación pasa a aceptada; false si no había invitación pendiente.
+// Si el jugador ya está en otra party, el índice único lo rechaza (error).
+func (r *PartyRepo) Accept(ctx context.Context, partyID int64, discordID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE party_members SET state = 'accepted'
+ WHERE party_id = $1 AND discord_user_id = $2 AND state = 'invited'
+`, partyID, discordID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RemoveMember: sirve para declinar, salir o echar a alguien
+func (r *PartyRepo) RemoveMember(ctx context.Context, partyID int64, discordID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+DELETE FROM party_members WHERE party_id = $1 AND discord_user_id = $2
+`, partyID, discordID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *PartyRepo) SetLeader(ctx context.Context, partyID int64, discordID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE parties SET leader_id = $2 WHERE id = $1`, partyID, discordID)
+	return err
+}
+
+// Disband: borra la party (los miembros e invitaciones se van en cascada)
+func (r *PartyRepo) Disband(ctx context.Context, partyID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM parties WHERE id = $1`, partyID)
+	return err
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type QueuePenalty struct {
+	ID            int64
+	GuildID       string
+	DiscordUserID string
+	Kind          string // ready_check | reliability
+	Reason        string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type PenaltyRepo struct{ db *sql.DB }
+
+func NewPenaltyRepo(db *sql.DB) *PenaltyRepo { return &PenaltyRepo{db: db} }
+
+const queuePenaltyCols = `id, guild_id, discord_user_id, kind, reason, created_at, expires_at`
+
+func (r *PenaltyRepo) Add(ctx context.Context, p QueuePenalty) error {
+	if p.Kind == "" {
+		p.Kind = "ready_check"
+	}
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO queue_penalties (guild_id, discord_user_id, kind, reason, expires_at)
+VALUES ($1,$2,$3,$4,$5)
+`, p.GuildID, p.DiscordUserID, p.Kind, p.Reason, p.ExpiresAt)
+	return err
+}
+
+// Active: la penalización vigente que más tarde vence (si hay alguna)
+func (r *PenaltyRepo) Active(ctx context.Context, guildID, discordID string) (QueuePenalty, bool, error) {
+	var p QueuePenalty
+	err := r.db.QueryRowContext(ctx, `
+SELECT `+queuePenaltyCols+`
+  FROM queue_penalties
+ WHERE guild_id = $1 AND discord_user_id = $2 AND expires_at > now()
+ ORDER BY expires_at DESC
+ LIMIT 1
+`, guildID, discordID).Scan(&p.ID, &p.GuildID, &p.DiscordUserID, &p.Kind, &p.Reason, &p.CreatedAt, &p.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return QueuePenalty{}, false, nil
+	}
+	if err != nil {
+		return QueuePenalty{}, false, err
+	}
+	return p, true, nil
+}
+
+// LastOf: la última penalización de un tipo (vigente o no)
+func (r *PenaltyRepo) LastOf(ctx context.Context, guildID, discordID, kind string) (QueuePenalty, bool, error) {
+	var p QueuePenalty
+	err := r.db.QueryRowContext(ctx, `
+SELECT `+queuePenaltyCols+`
+  FROM queue_penalties
+ WHERE guild_id = $1 AND discord_user_id = $2 AND kind = $3
+ ORDER BY created_at DESC
+ LIMIT 1
+`, guildID, discordID, kind).Scan(&p.ID, &p.GuildID, &p.DiscordUserID, &p.Kind, &p.Reason, &p.CreatedAt, &p.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return QueuePenalty{}, false, nil
+	}
+	if err != nil {
+		return QueuePenalty{}, false, err
+	}
+	return p, true, nil
+}
+
+// CountSince: cuántas penalizaciones de un tipo recibió desde since (para escalar)
+func (r *PenaltyRepo) CountSince(ctx context.Context, guildID, discordID, kind string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM queue_penalties
+ WHERE guild_id = $1 AND discord_user_id = $2 AND kind = $3 AND created_at >= $4
+`, guildID, discordID, kind, since).Scan(&n)
+	return n, err
+}
+
+// History: últimas penalizaciones del jugador (más nuevas primero)
+func (r *PenaltyRepo) History(ctx context.Context, guildID, discordID string, limit int) ([]QueuePenalty, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+queuePenaltyCols+`
+  FROM queue_penalties
+ WHERE guild_id = $1 AND discord_user_id = $2
+ ORDER BY created_at DESC
+ LIMIT $3
+`, guildID, discordID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []QueuePenalty
+	for rows.Next() {
+		var p QueuePenalty
+		if err := rows.Scan(&p.ID, &p.GuildID, &p.DiscordUserID, &p.Kind, &p.Reason, &p.CreatedAt, &p.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,497 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	pq "github.com/lib/pq"
+
+	"github.com/jose-valero/faceit-queue-bot/internal/domain/teams"
+)
+
+type PendingMatch struct {
+	ID            int64
+	GuildID       string
+	QueueName     string // cola de la que salió
+	Status        string // pending | ready_check | drafting | confirmed | failed
+	ReadyDeadline *time.Time
+	ChannelID     string // mensaje del ready-check (si hubo)
+	MessageID     string
+	Mode          string // balance | captains
+	Captain1ID    string // captains: team 1 (elige primero)
+	Captain2ID    string
+	DraftChannel  string // mensaje del draft
+	DraftMessage  string
+	PickDeadline  *time.Time // drafting: vencido, el bot elige por el capitán de turno
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Players       []PendingMatchPlayer
+	Picks         []DraftPick // en orden de pick
+}
+
+type DraftPick struct {
+	MatchID       int64
+	PickNo        int
+	Team          int
+	CaptainID     string
+	DiscordUserID string
+	PickedAt      time.Time
+}
+
+type PendingMatchPlayer struct {
+	MatchID       int64
+	DiscordUserID string
+	FaceitUserID  string
+	Nickname      string
+	JoinedAt      time.Time // joined_at original en la cola
+	Team          int       // 0 = sin asignar, 1 | 2
+	Rating        *int      // elo usado para balancear
+	ReadyState    string    // pending | accepted | declined | timeout
+	RespondedAt   *time.Time
+	PartyID       int64 // 0 = solo; los de la misma party van al mismo equipo
+	Priority      int   // la de la cola; vuelve con el jugador si el ready-check falla
+}
+
+// ErrNotEnoughPlayers: la cola todavía no llega al tamaño del match
+var ErrNotEnoughPlayers = errors.New("not enough players")
+
+type PendingMatchRepo struct{ db *sql.DB }
+
+func NewPendingMatchRepo(db *sql.DB) *PendingMatchRepo { return &PendingMatchRepo{db: db} }
+
+// Pop: en una sola transacción toma los primeros `size` jugadores en 'waiting' de la cola,
+// los saca de queue_entries (de todas las colas del guild: ya tienen match) y registra el match pendiente.
+// Las parties entran enteras o no entran (y sólo si están todos en 'waiting'); ver teams.PickGroups.
+// Con readyDeadline != nil el match nace en 'ready_check' con esa fecha límite.
+// Si no alcanzan devuelve ErrNotEnoughPlayers y no toca nada.
+func (r *PendingMatchRepo) Pop(ctx context.Context, guildID, queue string, size int, readyDeadline *time.Time) (PendingMatch, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PendingMatch{}, err
+	}
+	defer tx.Rollback()
+
+	// serializa los pops del guild, no de la cola: alguien anotado en dos colas no puede caer en dos matches
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "pop:"+guildID); err != nil {
+		return PendingMatch{}, err
+	}
+
+	// toda la cola (no sólo waiting): una party con alguien afk/left no puede salir
+	rows, err := tx.QueryContext(ctx, `
+SELECT discord_user_id, faceit_user_id, nickname, joined_at, status, COALESCE(party_id, 0), priority,
+       COALESCE(validating_until > now(), false)
+  FROM queue_entries
+ WHERE guild_id = $1 AND queue_name = $2
+ ORDER BY `+queueOrder+`
+ FOR UPDATE
+`, guildID, queue)
+	if err != nil {
+		return PendingMatch{}, err
+	}
+	// unidades en orden de cola (prioridad, llegada): cada solo es una, cada party otra
+	var units [][]PendingMatchPlayer
+	ready := []bool{}
+	unitOf := make(map[int64]int)
+	for rows.Next() {
+		var p PendingMatchPlayer
+		var status string
+		var validating bool
+		if err := rows.Scan(&p.DiscordUserID, &p.FaceitUserID, &p.Nickname, &p.JoinedAt, &status, &p.PartyID, &p.Priority, &validating); err != nil {
+			rows.Close()
+			return PendingMatch{}, err
+		}
+		i, ok := unitOf[p.PartyID]
+		if !ok {
+			i = len(units)
+			units = append(units, nil)
+			ready = append(ready, true)
+			if p.PartyID != 0 {
+				unitOf[p.PartyID] = i
+			}
+		}
+		units[i] = append(units[i], p)
+		// con la validación del join en curso todavía puede terminar bloqueado o afuera
+		ready[i] = ready[i] && status == "waiting" && !validating
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return PendingMatch{}, err
+	}
+
+	sizes := make([]int, len(units))
+	for i, u := range units {
+		if ready[i] {
+			sizes[i] = len(u) // 0 = no elegible
+		}
+	}
+	picked, ok := teams.PickGroups(sizes, size)
+	if !ok {
+		return PendingMatch{}, ErrNotEnoughPlayers
+	}
+	players := make([]PendingMatchPlayer, 0, size)
+	for _, i := range picked {
+		players = append(players, units[i]...)
+	}
+
+	ids := make([]string, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.DiscordUserID)
+	}
+	if _, err := tx.ExecContext(ctx, `
+DELETE FROM queue_entries
+ WHERE guild_id = $1 AND discord_user_id = ANY($2)
+`, guildID, pq.Array(ids)); err != nil {
+		return PendingMatch{}, err
+	}
+
+	status := "pending"
+	if readyDeadline != nil {
+		status = "ready_check"
+	}
+	m := PendingMatch{GuildID: guildID, QueueName: queue, ReadyDeadline: readyDeadline}
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO pending_matches (guild_id, queue_name, status, ready_deadline) VALUES ($1,$4,$2,$3)
+RETURNING id, status, created_at, updated_at
+`, guildID, status, readyDeadline, queue).Scan(&m.ID, &m.Status, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return PendingMatch{}, err
+	}
+
+	for i := range players {
+		players[i].MatchID = m.ID
+		players[i].ReadyState = "pending"
+		p := players[i]
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO pending_match_players (match_id, discord_user_id, faceit_user_id, nickname, joined_at, party_id, priority)
+VALUES ($1,$2,$3,$4,$5,NULLIF($6,0),$7)
+`, p.MatchID, p.DiscordUserID, p.FaceitUserID, p.Nickname, p.JoinedAt, p.PartyID, p.Priority); err != nil {
+			return PendingMatch{}, err
+		}
+	}
+	m.Players = players
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+SELECT $1, $4, u, 'popped', 'system', 'match #' || $3::bigint FROM unnest($2::text[]) AS u
+`, guildID, pq.Array(ids), m.ID, queue); err != nil {
+		return PendingMatch{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PendingMatch{}, err
+	}
+	return m, nil
+}
+
+func (r *PendingMatchRepo) Get(ctx context.Context, id int64) (PendingMatch, error) {
+	var m PendingMatch
+	err := r.db.QueryRowContext(ctx, `
+SELECT id, guild_id, queue_name, status, ready_deadline, COALESCE(channel_id,''), COALESCE(message_id,''),
+       mode, COALESCE(captain1_id,''), COALESCE(captain2_id,''),
+       COALESCE(draft_channel_id,''), COALESCE(draft_message_id,''), pick_deadline, created_at, updated_at
+  FROM pending_matches
+ WHERE id = $1
+`, id).Scan(&m.ID, &m.GuildID, &m.QueueName, &m.Status, &m.ReadyDeadline, &m.ChannelID, &m.MessageID,
+		&m.Mode, &m.Captain1ID, &m.Captain2ID,
+		&m.DraftChannel, &m.DraftMessage, &m.PickDeadline, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return PendingMatch{}, ErrNotFound
+	}
+	if err != nil {
+		return PendingMatch{}, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT match_id, discord_user_id, faceit_user_id, nickname, joined_at, COALESCE(team, 0), rating,
+       ready_state, responded_at, COALESCE(party_id, 0)
+  FROM pending_match_players
+ WHERE match_id = $1
+ ORDER BY joined_at ASC
+`, id)
+	if err != nil {
+		return PendingMatch{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p PendingMatchPlayer
+		if err := rows.Scan(&p.MatchID, &p.DiscordUserID, &p.FaceitUserID, &p.Nickname, &p.JoinedAt, &p.Team, &p.Rating,
+			&p.ReadyState, &p.RespondedAt, &p.PartyID); err != nil {
+			return PendingMatch{}, err
+		}
+		m.Players = append(m.Players, p)
+	}
+	if err := rows.Err(); err != nil {
+		return PendingMatch{}, err
+	}
+
+	prows, err := r.db.QueryContext(ctx, `
+SELECT match_id, pick_no, team, captain_id, discord_user_id, picked_at
+  FROM match_draft_picks
+ WHERE match_id = $1
+ ORDER BY pick_no ASC
+`, id)
+	if err != nil {
+		return PendingMatch{}, err
+	}
+	defer prows.Close()
+	for prows.Next() {
+		var pk DraftPick
+		if err := prows.Scan(&pk.MatchID, &pk.PickNo, &pk.Team, &pk.CaptainID, &pk.DiscordUserID, &pk.PickedAt); err != nil {
+			return PendingMatch{}, err
+		}
+		m.Picks = append(m.Picks, pk)
+	}
+	return m, prows.Err()
+}
+
+// Confirm: pasa el match de from a 'confirmed' guardando equipo y rating de cada jugador,
+// todo en una transacción (nunca queda confirmado sin sus equipos).
+// Devuelve false si el match ya no estaba en from.
+func (r *PendingMatchRepo) Confirm(ctx context.Context, matchID int64, from string, players []PendingMatchPlayer) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE pending_matches SET status = 'confirmed', mode = 'balance', updated_at = now() WHERE id = $1 AND status = $2
+`, matchID, from)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	for _, p := range players {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE pending_match_players
+   SET team = $3, rating = $4
+ WHERE match_id = $1 AND discord_user_id = $2
+`, matchID, p.DiscordUserID, p.Team, p.Rating); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// SetMessage: guarda dónde quedó publicado el mensaje del match (para editarlo después)
+func (r *PendingMatchRepo) SetMessage(ctx context.Context, matchID int64, channelID, messageID string) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE pending_matches SET channel_id = $2, message_id = $3, updated_at = now() WHERE id = $1
+`, matchID, channelID, messageID)
+	return err
+}
+
+// SetStatus: transición condicional (from -> to). Devuelve false si el match ya no estaba en `from`.
+func (r *PendingMatchRepo) SetStatus(ctx context.Context, matchID int64, from, to string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE pending_matches SET status = $3, updated_at = now() WHERE id = $1 AND status = $2
+`, matchID, from, to)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SetReadyState: registra la respuesta de un jugador. Sólo aplica si el match sigue en
+// ready_check y el jugador todavía no respondió.
+func (r *PendingMatchRepo) SetReadyState(ctx context.Context, matchID int64, discordID, state string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE pending_match_players p
+   SET ready_state = $3, responded_at = now()
+ WHERE p.match_id = $1 AND p.discord_user_id = $2
+   AND p.ready_state = 'pending'
+   AND EXISTS (SELECT 1 FROM pending_matches m WHERE m.id = p.match_id AND m.status = 'ready_check')
+`, matchID, discordID, state)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListExpiredReadyChecks: matches en ready_check cuya ventana ya venció
+func (r *PendingMatchRepo) ListExpiredReadyChecks(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id
+  FROM pending_matches
+ WHERE status = 'ready_check' AND ready_deadline <= now()
+ ORDER BY id ASC
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// FailReadyCheck: cierra un ready-check fallido en una transacción:
+// los que no respondieron quedan en 'timeout' y los que aceptaron vuelven a la cola
+// con su joined_at original (o sea, adelante de todo).
+// Devuelve false si el match ya no estaba en ready_check (otro lo resolvió antes).
+func (r *PendingMatchRepo) FailReadyCheck(ctx context.Context, matchID int64) (PendingMatch, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PendingMatch{}, false, err
+	}
+	defer tx.Rollback()
+
+	var guildID, queue string
+	err = tx.QueryRowContext(ctx, `
+UPDATE pending_matches SET status = 'failed', updated_at = now()
+ WHERE id = $1 AND status = 'ready_check'
+RETURNING guild_id, queue_name
+`, matchID).Scan(&guildID, &queue)
+	if err == sql.ErrNoRows {
+		return PendingMatch{}, false, nil
+	}
+	if err != nil {
+		return PendingMatch{}, false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE pending_match_players
+   SET ready_state = 'timeout', responded_at = now()
+ WHERE match_id = $1 AND ready_state = 'pending'
+`, matchID); err != nil {
+		return PendingMatch{}, false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+WITH back AS (
+  INSERT INTO queue_entries (guild_id, queue_name, discord_user_id, faceit_user_id, nickname, joined_at, status, party_id, priority)
+  SELECT $2, $3, pmp.discord_user_id, pmp.faceit_user_id, pmp.nickname, pmp.joined_at, 'waiting', pa.id, pmp.priority
+    FROM pending_match_players pmp
+    LEFT JOIN parties pa ON pa.id = pmp.party_id -- la party puede haberse disuelto mientras tanto
+   WHERE pmp.match_id = $1 AND pmp.ready_state = 'accepted'
+  ON CONFLICT (guild_id, queue_name, discord_user_id) DO UPDATE SET
+    joined_at    = LEAST(queue_entries.joined_at, EXCLUDED.joined_at),
+    status       = 'waiting',
+    last_seen_at = now()
+  RETURNING discord_user_id
+)
+INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+SELECT $2, $3, discord_user_id, 'requeued', 'system', 'ready-check fallido #' || $1::bigint FROM back
+`, matchID, guildID, queue); err != nil {
+		return PendingMatch{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PendingMatch{}, false, err
+	}
+	m, err := r.Get(ctx, matchID)
+	return m, true, err
+}
+
+// StartDraft: modo captains; pasa el match de from a 'drafting' con los capitanes (cada uno
+// en su equipo), los ratings de todos y el plazo del primer pick, en una transacción: no hay
+// draft sin capitanes. Devuelve false si el match ya no estaba en from.
+func (r *PendingMatchRepo) StartDraft(ctx context.Context, matchID int64, from, captain1, captain2 string, players []PendingMatchPlayer, pickDeadline time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE pending_matches
+   SET status = 'drafting', mode = 'captains', captain1_id = $3, captain2_id = $4, pick_deadline = $5, updated_at = now()
+ WHERE id = $1 AND status = $2
+`, matchID, from, captain1, captain2, pickDeadline)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	for _, p := range players {
+		team := 0
+		switch p.DiscordUserID {
+		case captain1:
+			team = 1
+		case captain2:
+			team = 2
+		}
+		if _, err := tx.ExecContext(ctx, `
+UPDATE pending_match_players
+   SET team = NULLIF($3, 0), rating = $4
+ WHERE match_id = $1 AND discord_user_id = $2
+`, matchID, p.DiscordUserID, team, p.Rating); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// AddPick: registra un pick del draft, asigna el equipo del jugador y corre el plazo al
+// próximo pick. Devuelve false si ese turno (pick_no) o ese jugador ya estaban tomados.
+func (r *PendingMatchRepo) AddPick(ctx context.Context, pk DraftPick, nextDeadline time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO match_draft_picks (match_id, pick_no, team, captain_id, discord_user_id)
+VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT DO NOTHING
+`, pk.MatchID, pk.PickNo, pk.Team, pk.CaptainID, pk.DiscordUserID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE pending_match_players SET team = $3 WHERE match_id = $1 AND discord_user_id = $2
+`, pk.MatchID, pk.DiscordUserID, pk.Team); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE pending_matches SET pick_deadline = $2, updated_at = now() WHERE id = $1`, pk.MatchID, nextDeadline); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ListExpiredDrafts: matches en drafting cuyo capitán de turno se pasó del plazo
+func (r *PendingMatchRepo) ListExpiredDrafts(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id
+  FROM pending_matches
+ WHERE status = 'drafting' AND pick_deadline <= now()
+ ORDER BY id ASC
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// SetDraftMessage: dónde quedó el mensaje del draft (para seguir editándolo tras un reinicio)
+func (r *PendingMatchRepo) SetDraftMessage(ctx context.Context, matchID int64, channelID, messageID string) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE pending_matches SET draft_channel_id = $2, draft_message_id = $3, updated_at = now() WHERE id = $1
+`, matchID, channelID, messageID)
+	return err
+}
@@ -12,28 +12,37 @@ type PolicyRepo struct{ db *sql.DB }
 
 func NewPolicyRepo(db *sql.DB) *PolicyRepo { return &PolicyRepo{db: db} }
 
-func (r *PolicyRepo) Get(ctx context.Context, guildID string) (GuildPolicy, error) {
+// Get: policy de una cola; si todavía no tiene, se crea con los defaults
+func (r *PolicyRepo) Get(ctx context.Context, guildID, queue string) (GuildPolicy, error) {
 	var p GuildPolicy
 	err := r.db.QueryRowContext(ctx, `
-SELECT guild_id, require_member, afk_timeout_seconds, drop_if_left_seconds, voice_required,
-       COALESCE(cooldown_after_loss_seconds,120), created_at, updated_at
+SELECT guild_id, queue_name, require_member, afk_timeout_seconds, drop_if_left_seconds, voice_required,
+       COALESCE(cooldown_after_loss_seconds,120), match_size,
+       ready_check_seconds, ready_penalty_seconds, team_mode, join_enforcement, require_verified,
+       min_skill_level, max_skill_level, min_elo, max_elo,
+       leave_limit, leave_window_minutes, leave_penalty_seconds, flush_on_close, created_at, updated_at
   FROM guild_policies
- WHERE guild_id = $1
-`, guildID).Scan(
-		&p.GuildID, &p.RequireMember, &p.AFKTimeoutSeconds, &p.DropIfLeftSeconds, &p.VoiceRequired,
-		&p.CooldownAfterLossSeconds, &p.CreatedAt, &p.UpdatedAt,
+ WHERE guild_id = $1 AND queue_name = $2
+`, guildID, queue).Scan(
+		&p.GuildID, &p.QueueName, &p.RequireMember, &p.AFKTimeoutSeconds, &p.DropIfLeftSeconds, &p.VoiceRequired,
+		&p.CooldownAfterLossSeconds, &p.MatchSize,
+		&p.ReadyCheckSeconds, &p.ReadyPenaltySeconds, &p.TeamMode, &p.JoinEnforcement, &p.RequireVerified,
+		&p.MinSkillLevel, &p.MaxSkillLevel, &p.MinElo, &p.MaxElo,
+		&p.LeaveLimit, &p.LeaveWindowMinutes, &p.LeavePenaltySeconds, &p.FlushOnClose, &p.CreatedAt, &p.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		_, err := r.db.ExecContext(ctx, `INSERT INTO guild_policies (guild_id) VALUES ($1)`, guildID)
+		_, err := r.db.ExecContext(ctx, `
+INSERT INTO guild_policies (guild_id, queue_name) VALUES ($1,$2) ON CONFLICT DO NOTHING
+`, guildID, queue)
 		if err != nil {
 			return GuildPolicy{}, err
 		}
-		return r.Get(ctx, guildID)
+		return r.Get(ctx, guildID, queue)
 	}
 	return p, err
 }
 
-func (r *PolicyRepo) Update(ctx context.Context, guildID string, u GuildPolicyUpdate) (GuildPolicy, error) {
+func (r *PolicyRepo) Update(ctx context.Context, guildID, queue string, u GuildPolicyUpdate) (GuildPolicy, error) {
 	sets := make([]string, 0, 4)
 	args := make([]any, 0, 5)
 	i := 1
@@ -60,37 +69,57 @@ func (r *PolicyRepo) Update(ctx context.Context, guildID string, u GuildPolicyUp
 	}
 	if len(sets) == 0 {
 		// nada que cambiar
-		return r.Get(ctx, guildID)
+		return r.Get(ctx, guildID, queue)
 	}
 	sets = append(sets, fmt.Sprintf("updated_at = $%d", i))
 	args = append(args, time.Now())
 	i++
 
-	args = append(args, guildID)
+	args = append(args, guildID, queue)
 
 	_, err := r.db.ExecContext(ctx, `
 UPDATE guild_policies
    SET `+strings.Join(sets, ", ")+`
- WHERE guild_id = $`+fmt.Sprint(i), args...)
+ WHERE guild_id = $`+fmt.Sprint(i)+` AND queue_name = $`+fmt.Sprint(i+1), args...)
 	if err != nil {
 		return GuildPolicy{}, err
 	}
-	return r.Get(ctx, guildID)
+	return r.Get(ctx, guildID, queue)
 }
 
 func (r *PolicyRepo) Upsert(ctx context.Context, p GuildPolicy) error {
 	_, err := r.db.ExecContext(ctx, `
 INSERT INTO guild_policies (
-  guild_id, require_member, afk_timeout_seconds, drop_if_left_seconds, voice_required,
-  cooldown_after_loss_seconds, created_at, updated_at
-) VALUES ($1,$2,$3,$4,$5,$6, now(), now())
-ON CONFLICT (guild_id) DO UPDATE SET
+  guild_id, queue_name, require_member, afk_timeout_seconds, drop_if_left_seconds, voice_required,
+  cooldown_after_loss_seconds, match_size, ready_check_seconds, ready_penalty_seconds,
+  team_mode, join_enforcement, require_verified,
+  min_skill_level, max_skill_level, min_elo, max_elo,
+  leave_limit, leave_window_minutes, leave_penalty_seconds, flush_on_close, created_at, updated_at
+) VALUES ($1,$13,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$14,$15,$16,$17,$18,$19,$20,$21, now(), now())
+ON CONFLICT (guild_id, queue_name) DO UPDATE SET
   require_member = EXCLUDED.require_member,
   afk_timeout_seconds = EXCLUDED.afk_timeout_seconds,
   drop_if_left_seconds = EXCLUDED.drop_if_left_seconds,
   voice_required = EXCLUDED.voice_required,
   cooldown_after_loss_seconds = EXCLUDED.cooldown_after_loss_seconds,
+  match_size = EXCLUDED.match_size,
+  ready_check_seconds = EXCLUDED.ready_check_seconds,
+  ready_penalty_seconds = EXCLUDED.ready_penalty_seconds,
+  team_mode = EXCLUDED.team_mode,
+  join_enforcement = EXCLUDED.join_enforcement,
+  require_verified = EXCLUDED.require_verified,
+  min_skill_level = EXCLUDED.min_skill_level,
+  max_skill_level = EXCLUDED.max_skill_level,
+  min_elo = EXCLUDED.min_elo,
+  max_elo = EXCLUDED.max_elo,
+  leave_limit = EXCLUDED.leave_limit,
+  leave_window_minutes = EXCLUDED.leave_window_minutes,
+  leave_penalty_seconds = EXCLUDED.leave_penalty_seconds,
+  flush_on_close = EXCLUDED.flush_on_close,
   updated_at = now()
-`, p.GuildID, p.RequireMember, p.AFKTimeoutSeconds, p.DropIfLeftSeconds, p.VoiceRequired, p.CooldownAfterLossSeconds)
+`, p.GuildID, p.RequireMember, p.AFKTimeoutSeconds, p.DropIfLeftSeconds, p.VoiceRequired, p.CooldownAfterLossSeconds,
+		p.MatchSize, p.ReadyCheckSeconds, p.ReadyPenaltySeconds, p.TeamMode, p.JoinEnforcement, p.RequireVerified, p.QueueName,
+		p.MinSkillLevel, p.MaxSkillLevel, p.MinElo, p.MaxElo,
+		p.LeaveLimit, p.LeaveWindowMinutes, p.LeavePenaltySeconds, p.FlushOnClose)
 	return err
 }
@@ -12,42 +12,290 @@ type QueueRepo struct{ db *sql.DB }
 
 func NewQueueRepo(db *sql.DB) *QueueRepo { return &QueueRepo{db: db} }
 
-// Join: inserta o refresca (upsert). Siempre deja status=waiting y last_seen=now().
-func (r *QueueRepo) Join(ctx context.Context, e QueueEntry) error {
+// JoinValidationWindow: cuánto espera el pop a que termine la validación async de un join;
+// pasado esto lo toma igual (la validación tiene timeouts mucho más cortos: si no terminó,
+// es que el bot se cayó en el medio)
+const JoinValidationWindow = 30 * time.Second
+
+// Join: inserta o refresca (upsert) en la cola e.QueueName. Siempre deja status=waiting y last_seen=now().
+// e.PartyID (0 = solo) queda en la fila para que el pop no separe a la party.
+// Registra 'join' si la fila es nueva (lo usamos para estimar ETAs) o 'rejoin'
+// si volvía de afk/left. Queda "validando" (fuera del pop) hasta Validated; devuelve el
+// vencimiento de esa ventana, que identifica a este join (truncado a lo que guarda Postgres).
+func (r *QueueRepo) Join(ctx context.Context, e QueueEntry) (time.Time, error) {
+	until := time.Now().Add(JoinValidationWindow).Truncate(time.Microsecond)
+	return until, r.upsert(ctx, e, e.DiscordUserID, &until)
+}
+
+// Add: un admin mete al jugador en la cola. Igual que Join pero queda como 'added'
+// (no cuenta para las ETAs) con el admin de actor, y sin validación pendiente.
+func (r *QueueRepo) Add(ctx context.Context, e QueueEntry, actorID string) error {
+	return r.upsert(ctx, e, actorID, nil)
+}
+
+// Validated: terminó la validación async del join que abrió la ventana until; el pop ya lo
+// puede tomar. Si mientras tanto volvió a joinear, la ventana es otra y no se toca: todavía
+// falta que termine la validación de ese join.
+func (r *QueueRepo) Validated(ctx context.Context, guildID, queue, discordID string, until time.Time) error {
 	_, err := r.db.ExecContext(ctx, `
-INSERT INTO queue_entries (guild_id, discord_user_id, faceit_user_id, nickname, status)
-VALUES ($1,$2,$3,$4,'waiting')
-ON CONFLICT (guild_id, discord_user_id) DO UPDATE SET
-  faceit_user_id = EXCLUDED.faceit_user_id,
-  nickname       = EXCLUDED.nickname,
-  status         = 'waiting',
-  last_seen_at   = now()
+UPDATE queue_entries SET validating_until = NULL
+ WHERE guild_id = $1 AND queue_name = $2 AND discord_user_id = $3 AND validating_until = $4
+`, guildID, queue, discordID, until)
+	return err
+}
+
+func (r *QueueRepo) upsert(ctx context.Context, e QueueEntry, actor string, validatingUntil *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+WITH prev AS (
+  SELECT status FROM queue_entries WHERE guild_id = $1 AND queue_name = $5 AND discord_user_id = $2
+), up AS (
+  INSERT INTO queue_entries (guild_id, queue_name, discord_user_id, faceit_user_id, nickname, status, party_id, priority, validating_until)
+  VALUES ($1,$5,$2,$3,$4,'waiting',NULLIF($6,0),$7,$9)
+  ON CONFLICT (guild_id, queue_name, discord_user_id) DO UPDATE SET
+    validating_until = EXCLUDED.validating_until,
+    faceit_user_id = EXCLUDED.faceit_user_id,
+    nickname       = EXCLUDED.nickname,
+    party_id       = EXCLUDED.party_id,
+    priority       = EXCLUDED.priority,
+    status         = 'waiting',
+    status_reason  = '',
+    last_seen_at   = now()
+  RETURNING (xmax = 0) AS inserted
+)
+INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+SELECT $1, $5, $2, CASE WHEN $8 = $2 THEN 'join' ELSE 'added' END, $8, '' FROM up WHERE inserted
+UNION ALL
+SELECT $1, $5, $2, 'rejoin', $8, 'estaba ' || prev.status FROM up, prev WHERE NOT up.inserted AND prev.status <> 'waiting'
 `,
-		e.GuildID, e.DiscordUserID, e.FaceitUserID, e.Nickname,
+		e.GuildID, e.DiscordUserID, e.FaceitUserID, e.Nickname, e.QueueName, e.PartyID, e.Priority, actor, validatingUntil,
 	)
 	return err
 }
 
-func (r *QueueRepo) Leave(ctx context.Context, guildID, discordID string) (bool, error) {
-	res, err := r.db.ExecContext(ctx, `
-DELETE FROM queue_entries
- WHERE guild_id = $1 AND discord_user_id = $2
-`, guildID, discordID)
+// Leave: el jugador sale por su cuenta
+func (r *QueueRepo) Leave(ctx context.Context, guildID, queue, discordID string) (bool, error) {
+	return r.remove(ctx, guildID, queue, discordID, "leave", discordID, "")
+}
+
+// Kick: un admin (o el sistema) lo saca de la cola. Si estaba con su party sale la party
+// entera: sin él no puede salir en un match (el resto queda con el motivo en el historial).
+func (r *QueueRepo) Kick(ctx context.Context, guildID, queue, discordID, actorID, reason string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH tgt AS (
+  SELECT party_id FROM queue_entries WHERE guild_id = $1 AND queue_name = $2 AND discord_user_id = $3
+), del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $2
+     AND (discord_user_id = $3 OR party_id = (SELECT party_id FROM tgt))
+  RETURNING discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, discord_user_id, 'kick', $4,
+         CASE WHEN discord_user_id = $3 THEN $5 ELSE 'sacaron a <@' || $3 || '> de su party: ' || $5 END
+    FROM del
+)
+SELECT COUNT(*) FROM del WHERE discord_user_id = $3
+`, guildID, queue, discordID, actorID, reason).Scan(&n)
+	return n > 0, err
+}
+
+// Clear: vacía la cola (cierre por horario, admin); devuelve a quiénes sacó
+func (r *QueueRepo) Clear(ctx context.Context, guildID, queue, actorID, reason string) ([]string, error) {
+	return r.listIDs(ctx, `
+WITH del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $2
+  RETURNING discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, discord_user_id, 'cleared', $3, $4 FROM del
+)
+SELECT discord_user_id FROM del
+`, guildID, queue, actorID, reason)
+}
+
+// Move: reubica al jugador poniéndole la prioridad y el joined_at que le tocan en el
+// lugar nuevo (los calcula QueueService.Move a partir de sus vecinos)
+func (r *QueueRepo) Move(ctx context.Context, guildID, queue, discordID string, priority int, joinedAt time.Time, actorID, reason string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH up AS (
+  UPDATE queue_entries
+     SET priority = $4, joined_at = $5
+   WHERE guild_id = $1 AND queue_name = $2 AND discord_user_id = $3
+  RETURNING discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, discord_user_id, 'moved', $6, $7 FROM up
+)
+SELECT COUNT(*) FROM up
+`, guildID, queue, discordID, priority, joinedAt, actorID, reason).Scan(&n)
 	if err != nil {
 		return false, err
 	}
-	n, _ := res.RowsAffected()
 	return n > 0, nil
 }
 
-func (r *QueueRepo) List(ctx context.Context, guildID string, limit int) ([]QueueEntry, error) {
-	rows, err := r.db.QueryContext(ctx, `
-SELECT guild_id, discord_user_id, faceit_user_id, nickname, joined_at, last_seen_at, status
+// Swap: intercambia los lugares (prioridad y joined_at) de dos jugadores de la misma cola.
+// false si alguno de los dos no está.
+func (r *QueueRepo) Swap(ctx context.Context, guildID, queue, a, b, actorID string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH a AS (
+  SELECT priority, joined_at FROM queue_entries WHERE guild_id = $1 AND queue_name = $2 AND discord_user_id = $3
+), b AS (
+  SELECT priority, joined_at FROM queue_entries WHERE guild_id = $1 AND queue_name = $2 AND discord_user_id = $4
+), up AS (
+  UPDATE queue_entries q
+     SET priority  = CASE WHEN q.discord_user_id = $3 THEN b.priority ELSE a.priority END,
+         joined_at = CASE WHEN q.discord_user_id = $3 THEN b.joined_at ELSE a.joined_at END
+    FROM a, b
+   WHERE q.guild_id = $1 AND q.queue_name = $2 AND q.discord_user_id IN ($3, $4)
+  RETURNING q.discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, discord_user_id, 'swapped', $5,
+         'con <@' || CASE WHEN discord_user_id = $3 THEN $4 ELSE $3 END || '>'
+    FROM up
+)
+SELECT COUNT(*) FROM up
+`, guildID, queue, a, b, actorID).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n == 2, nil
+}
+
+// Lock: cierra la cola a joins nuevos (los que están siguen). false si ya estaba cerrada.
+// Queda en queue_events a nombre del admin, como auditoría.
+func (r *QueueRepo) Lock(ctx context.Context, guildID, queue, actorID, reason string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH ins AS (
+  INSERT INTO queue_locks (guild_id, queue_name, locked_by, reason)
+  VALUES ($1, $2, $3, $4)
+  ON CONFLICT (guild_id, queue_name) DO NOTHING
+  RETURNING locked_by
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, locked_by, 'locked', locked_by, $4 FROM ins
+)
+SELECT COUNT(*) FROM ins
+`, guildID, queue, actorID, reason).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Unlock: reabre la cola. false si no estaba cerrada.
+func (r *QueueRepo) Unlock(ctx context.Context, guildID, queue, actorID string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH del AS (
+  DELETE FROM queue_locks
+   WHERE guild_id = $1 AND queue_name = $2
+  RETURNING guild_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, $3, 'unlocked', $3, '' FROM del
+)
+SELECT COUNT(*) FROM del
+`, guildID, queue, actorID).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Locked: el lock vigente de la cola (found=false si está abierta)
+func (r *QueueRepo) Locked(ctx context.Context, guildID, queue string) (QueueLock, bool, error) {
+	var l QueueLock
+	err := r.db.QueryRowContext(ctx, `
+SELECT guild_id, queue_name, locked_by, reason, created_at
+  FROM queue_locks
+ WHERE guild_id = $1 AND queue_name = $2
+`, guildID, queue).Scan(&l.GuildID, &l.QueueName, &l.LockedBy, &l.Reason, &l.CreatedAt)
+	if err == sql.ErrNoRows {
+		return QueueLock{}, false, nil
+	}
+	if err != nil {
+		return QueueLock{}, false, err
+	}
+	return l, true, nil
+}
+
+// LeaveParty: sale la party entera (actor = quien pidió salir); devuelve cuántos salieron
+func (r *QueueRepo) LeaveParty(ctx context.Context, guildID, queue string, partyID int64, actorID string) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $2 AND party_id = $3
+  RETURNING discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $2, discord_user_id, 'leave', $4, 'salió la party' FROM del
+)
+SELECT COUNT(*) FROM del
+`, guildID, queue, partyID, actorID).Scan(&n)
+	return n, err
+}
+
+func (r *QueueRepo) remove(ctx context.Context, guildID, queue, discordID, event, actor, reason string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+WITH del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $6 AND discord_user_id = $2
+  RETURNING discord_user_id
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $6, discord_user_id, $3, $4, $5 FROM del
+)
+SELECT COUNT(*) FROM del
+`, guildID, discordID, event, actor, reason, queue).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+const queueEntryCols = `guild_id, queue_name, discord_user_id, faceit_user_id, nickname, joined_at, last_seen_at, status, status_reason,
+       COALESCE(party_id, 0), priority`
+
+// queueOrder: orden de la cola. Primero la prioridad (más alta antes) y después joined_at.
+// Con priority_aging_minutes > 0 en el guild, cada N minutos esperando suman +1 de prioridad,
+// así los que no tienen rol no quedan atrás para siempre.
+const queueOrder = `(queue_entries.priority + COALESCE((
+    SELECT floor(extract(epoch FROM now() - queue_entries.joined_at) / 60 / gs.priority_aging_minutes)::int
+      FROM guild_settings gs
+     WHERE gs.guild_id = queue_entries.guild_id AND gs.priority_aging_minutes > 0), 0)) DESC,
+  queue_entries.joined_at ASC`
+
+func (r *QueueRepo) List(ctx context.Context, guildID, queue string, limit int) ([]QueueEntry, error) {
+	return r.listEntries(ctx, `
+SELECT `+queueEntryCols+`
   FROM queue_entries
- WHERE guild_id = $1 AND status = 'waiting'
+ WHERE guild_id = $1 AND queue_name = $2 AND status = 'waiting'
+ ORDER BY `+queueOrder+`
+ LIMIT $3
+`, guildID, queue, limit)
+}
+
+// QueuesOf: entradas del jugador en todas las colas del guild
+func (r *QueueRepo) QueuesOf(ctx context.Context, guildID, discordID string) ([]QueueEntry, error) {
+	return r.listEntries(ctx, `
+SELECT `+queueEntryCols+`
+  FROM queue_entries
+ WHERE guild_id = $1 AND discord_user_id = $2
  ORDER BY joined_at ASC
- LIMIT $2
-`, guildID, limit)
+`, guildID, discordID)
+}
+
+func (r *QueueRepo) listEntries(ctx context.Context, query string, args ...any) ([]QueueEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +304,7 @@ SELECT guild_id, discord_user_id, faceit_user_id, nickname, joined_at, last_seen
 	var out []QueueEntry
 	for rows.Next() {
 		var e QueueEntry
-		if err := rows.Scan(&e.GuildID, &e.DiscordUserID, &e.FaceitUserID, &e.Nickname, &e.JoinedAt, &e.LastSeenAt, &e.Status); err != nil {
+		if err := rows.Scan(&e.GuildID, &e.QueueName, &e.DiscordUserID, &e.FaceitUserID, &e.Nickname, &e.JoinedAt, &e.LastSeenAt, &e.Status, &e.StatusReason, &e.PartyID, &e.Priority); err != nil {
 			return nil, err
 		}
 		out = append(out, e)
@@ -64,74 +312,167 @@ SELECT guild_id, discord_user_id, faceit_user_id, nickname, joined_at, last_seen
 	return out, rows.Err()
 }
 
-func (r *QueueRepo) TouchValid(ctx context.Context, guildID, discordID string) error {
-	_, err := r.db.ExecContext(ctx, `
-UPDATE queue_entries
-   SET last_seen_at = now(), status = 'waiting'
- WHERE guild_id = $1 AND discord_user_id = $2
-`, guildID, discordID)
-	return err
+// TouchValid: está en voz válida → waiting. Registra 'back' si venía de afk/left.
+func (r *QueueRepo) TouchValid(ctx context.Context, guildID, queue, discordID string) error {
+	return r.setStatus(ctx, guildID, queue, discordID, "waiting", "back", "voz válida")
+}
+
+func (r *QueueRepo) MarkLeft(ctx context.Context, guildID, queue, discordID, reason string) error {
+	return r.setStatus(ctx, guildID, queue, discordID, "left", "left", reason)
+}
+
+func (r *QueueRepo) MarkAFK(ctx context.Context, guildID, queue, discordID, reason string) error {
+	return r.setStatus(ctx, guildID, queue, discordID, "afk", "afk", reason)
 }
 
-func (r *QueueRepo) MarkLeft(ctx context.Context, guildID, discordID string) error {
+// Block: falló una validación post-join; queda visible (con el motivo) pero no cuenta para el pop
+func (r *QueueRepo) Block(ctx context.Context, guildID, queue, discordID, reason string) error {
 	_, err := r.db.ExecContext(ctx, `
-UPDATE queue_entries
-   SET last_seen_at = now(), status = 'left'
- WHERE guild_id = $1 AND discord_user_id = $2
-`, guildID, discordID)
+WITH upd AS (
+  UPDATE queue_entries
+     SET status = 'blocked', status_reason = $3, last_seen_at = now()
+   WHERE guild_id = $1 AND queue_name = $4 AND discord_user_id = $2
+  RETURNING 1
+)
+INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+SELECT $1, $4, $2, 'blocked', 'system', $3 FROM upd
+`, guildID, discordID, reason, queue)
 	return err
 }
 
-func (r *QueueRepo) MarkAFK(ctx context.Context, guildID, discordID string) error {
+// setStatus: actualiza status/last_seen y sólo registra evento si el status cambió
+// (los updates de voz son muy frecuentes y no queremos un evento por cada uno).
+// Un 'blocked' no se pisa desde voz: sólo sale con un nuevo join, leave o prune.
+func (r *QueueRepo) setStatus(ctx context.Context, guildID, queue, discordID, status, event, reason string) error {
 	_, err := r.db.ExecContext(ctx, `
-UPDATE queue_entries
-   SET last_seen_at = now(), status = 'afk'
- WHERE guild_id = $1 AND discord_user_id = $2
-`, guildID, discordID)
+WITH prev AS (
+  SELECT status FROM queue_entries WHERE guild_id = $1 AND queue_name = $6 AND discord_user_id = $2
+), upd AS (
+  UPDATE queue_entries
+     SET last_seen_at = now(), status = $3
+   WHERE guild_id = $1 AND queue_name = $6 AND discord_user_id = $2 AND status <> 'blocked'
+  RETURNING 1
+)
+INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+SELECT $1, $6, $2, $4, $2, $5 FROM prev, upd WHERE prev.status <> $3
+`, guildID, discordID, status, event, reason, queue)
 	return err
 }
 
 // Prune: elimina definitvamente segun ventanas de gracia para AFK/LEFT.
-func (r *QueueRepo) Prune(ctx context.Context, guildID string, afk, left time.Duration) (int64, int64, error) {
-	var nAfk, nLeft int64
+// Los 'blocked' usan la misma ventana que LEFT pero no se devuelven: no es culpa del jugador.
+// Cada fila borrada queda como evento 'pruned' (con el motivo) en queue_events. Con el podado
+// sale el resto de su party (como 'leave' del sistema: no cuenta como falta de ellos).
+// Devuelve los discord IDs podados por afk y por left (para la penalización por reincidencia).
+func (r *QueueRepo) Prune(ctx context.Context, guildID, queue string, afk, left time.Duration) ([]string, []string, error) {
+	var afkIDs, leftIDs []string
+	var err error
 
 	if afk > 0 {
-		res, err := r.db.ExecContext(ctx, `
-DELETE FROM queue_entries
- WHERE guild_id = $1
-   AND status   = 'afk'
-   AND last_seen_at <= now() - $2::interval
-`, guildID, durToInterval(afk))
+		afkIDs, err = r.listIDs(ctx, `
+WITH hit AS (
+  SELECT discord_user_id, party_id
+    FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $3
+     AND status   = 'afk'
+     AND last_seen_at <= now() - $2::interval
+), del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $3
+     AND (discord_user_id IN (SELECT discord_user_id FROM hit)
+          OR party_id IN (SELECT party_id FROM hit WHERE party_id IS NOT NULL))
+  RETURNING discord_user_id, discord_user_id IN (SELECT discord_user_id FROM hit) AS hit
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $3, discord_user_id,
+         CASE WHEN hit THEN 'pruned' ELSE 'leave' END, 'system',
+         CASE WHEN hit THEN 'afk más de ' || $2 ELSE 'podaron a alguien de su party' END
+    FROM del
+)
+SELECT discord_user_id FROM del WHERE hit
+`, guildID, durToInterval(afk), queue)
 		if err != nil {
-			return 0, 0, err
+			return nil, nil, err
 		}
-		n, _ := res.RowsAffected()
-		nAfk = n
 	}
 
 	if left > 0 {
-		res, err := r.db.ExecContext(ctx, `
-DELETE FROM queue_entries
- WHERE guild_id = $1
-   AND status   = 'left'
-   AND last_seen_at <= now() - $2::interval
-`, guildID, durToInterval(left))
+		leftIDs, err = r.listIDs(ctx, `
+WITH hit AS (
+  SELECT discord_user_id, party_id
+    FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $3
+     AND status   IN ('left','blocked')
+     AND last_seen_at <= now() - $2::interval
+), del AS (
+  DELETE FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $3
+     AND (discord_user_id IN (SELECT discord_user_id FROM hit)
+          OR party_id IN (SELECT party_id FROM hit WHERE party_id IS NOT NULL))
+  RETURNING discord_user_id, status, status_reason, discord_user_id IN (SELECT discord_user_id FROM hit) AS hit
+), ev AS (
+  INSERT INTO queue_events (guild_id, queue_name, discord_user_id, event, actor, reason)
+  SELECT $1, $3, discord_user_id,
+         CASE WHEN hit THEN 'pruned' ELSE 'leave' END, 'system',
+         CASE WHEN NOT hit THEN 'podaron a alguien de su party'
+              WHEN status = 'blocked' THEN 'bloqueado: ' || status_reason
+              ELSE 'fuera de voz más de ' || $2 END
+    FROM del
+)
+SELECT discord_user_id FROM del WHERE hit AND status = 'left'
+`, guildID, durToInterval(left), queue)
 		if err != nil {
-			return nAfk, 0, err
+			return afkIDs, nil, err
 		}
-		n, _ := res.RowsAffected()
-		nLeft = n
 	}
 
-	return nAfk, nLeft, nil
+	return afkIDs, leftIDs, nil
+}
+
+func (r *QueueRepo) listIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// CountOffenses: salidas "malas" del jugador en la cola desde since: podas por afk/left
+// (no las de 'blocked') y salidas propias a menos de `churn` de haber entrado.
+func (r *QueueRepo) CountOffenses(ctx context.Context, guildID, queue, discordID string, since time.Time, churn time.Duration) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*)
+  FROM queue_events e
+ WHERE e.guild_id = $1 AND e.queue_name = $2 AND e.discord_user_id = $3 AND e.created_at > $4
+   AND (
+        (e.event = 'pruned' AND e.reason NOT LIKE 'bloqueado:%')
+     OR (e.event = 'leave' AND e.actor = e.discord_user_id AND EXISTS (
+           SELECT 1 FROM queue_events j
+            WHERE j.guild_id = e.guild_id AND j.queue_name = e.queue_name
+              AND j.discord_user_id = e.discord_user_id
+              AND j.event IN ('join','rejoin')
+              AND j.created_at BETWEEN e.created_at - $5::interval AND e.created_at))
+   )
+`, guildID, queue, discordID, since, durToInterval(churn)).Scan(&n)
+	return n, err
 }
 
 // ListWithGrace devuelve waiting + (afk dentro de graceAFK) + (left dentro de graceLeft)
-func (r *QueueRepo) ListWithGrace(ctx context.Context, guildID string, limit int, graceAFK, graceLeft time.Duration) ([]QueueEntry, error) {
-	conds := []string{"status = 'waiting'"} // siempre mostramos waiting
+func (r *QueueRepo) ListWithGrace(ctx context.Context, guildID, queue string, limit int, graceAFK, graceLeft time.Duration) ([]QueueEntry, error) {
+	conds := []string{"status IN ('waiting','blocked')"} // siempre mostramos waiting y blocked (con su motivo)
 
-	args := []any{guildID}
-	i := 2
+	args := []any{guildID, queue}
+	i := 3
 
 	if graceAFK > 0 {
 		conds = append(conds, fmt.Sprintf("(status = 'afk'  AND last_seen_at > now() - $%d::interval)", i))
@@ -147,42 +488,91 @@ func (r *QueueRepo) ListWithGrace(ctx context.Context, guildID string, limit int
 
 	args = append(args, limit)
 
-	rows, err := r.db.QueryContext(ctx, `
-SELECT guild_id, discord_user_id, faceit_user_id, nickname, joined_at, last_seen_at, status
+	return r.listEntries(ctx, `
+SELECT `+queueEntryCols+`
   FROM queue_entries
- WHERE guild_id = $1
+ WHERE guild_id = $1 AND queue_name = $2
 `+where+`
- ORDER BY joined_at ASC
+ ORDER BY `+queueOrder+`
  LIMIT $`+fmt.Sprint(i), args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var out []QueueEntry
-	for rows.Next() {
-		var e QueueEntry
-		if err := rows.Scan(&e.GuildID, &e.DiscordUserID, &e.FaceitUserID, &e.Nickname, &e.JoinedAt, &e.LastSeenAt, &e.Status); err != nil {
-			return nil, err
-		}
-		out = append(out, e)
-	}
-	return out, rows.Err()
 }
 
-func (r *QueueRepo) Exists(ctx context.Context, guildID, discordID string) (bool, error) {
+func (r *QueueRepo) Exists(ctx context.Context, guildID, queue, discordID string) (bool, error) {
 	var x int
 	err := r.db.QueryRowContext(ctx, `
 SELECT 1
   FROM queue_entries
- WHERE guild_id = $1 AND discord_user_id = $2
-`, guildID, discordID).Scan(&x)
+ WHERE guild_id = $1 AND queue_name = $3 AND discord_user_id = $2
+`, guildID, discordID, queue).Scan(&x)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
 	return err == nil, err
 }
 
+// Position: lugar del jugador entre los 'waiting' (1-based) y total de 'waiting'.
+// Si no está en la cola devuelve found=false; si está afk/left, pos=0 y la entry trae el status.
+func (r *QueueRepo) Position(ctx context.Context, guildID, queue, discordID string) (QueueEntry, int, int, bool, error) {
+	var e QueueEntry
+	var pos, total int
+	err := r.db.QueryRowContext(ctx, `
+WITH w AS (
+  SELECT discord_user_id, ROW_NUMBER() OVER (ORDER BY `+queueOrder+`) AS pos
+    FROM queue_entries
+   WHERE guild_id = $1 AND queue_name = $3 AND status = 'waiting'
+)
+SELECT q.guild_id, q.queue_name, q.discord_user_id, q.faceit_user_id, q.nickname, q.joined_at, q.last_seen_at, q.status, q.status_reason,
+       COALESCE(q.party_id, 0), q.priority,
+       COALESCE((SELECT pos FROM w WHERE w.discord_user_id = q.discord_user_id), 0),
+       (SELECT COUNT(*) FROM w)
+  FROM queue_entries q
+ WHERE q.guild_id = $1 AND q.queue_name = $3 AND q.discord_user_id = $2
+`, guildID, discordID, queue).Scan(&e.GuildID, &e.QueueName, &e.DiscordUserID, &e.FaceitUserID, &e.Nickname, &e.JoinedAt, &e.LastSeenAt, &e.Status, &e.StatusReason, &e.PartyID, &e.Priority, &pos, &total)
+	if err == sql.ErrNoRows {
+		return QueueEntry{}, 0, 0, false, nil
+	}
+	if err != nil {
+		return QueueEntry{}, 0, 0, false, err
+	}
+	return e, pos, total, true, nil
+}
+
+// CountEvents: cuántos eventos de un tipo hubo en la cola en la ventana `within`
+func (r *QueueRepo) CountEvents(ctx context.Context, guildID, queue, event string, within time.Duration) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*)
+  FROM queue_events
+ WHERE guild_id = $1 AND queue_name = $4 AND event = $2 AND created_at > now() - $3::interval
+`, guildID, event, durToInterval(within), queue).Scan(&n)
+	return n, err
+}
+
+// ListEvents: últimos eventos de un jugador en el guild, de todas las colas (más nuevos primero)
+func (r *QueueRepo) ListEvents(ctx context.Context, guildID, discordID string, limit int) ([]QueueEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, guild_id, queue_name, discord_user_id, event, actor, reason, created_at
+  FROM queue_events
+ WHERE guild_id = $1 AND discord_user_id = $2
+ ORDER BY created_at DESC, id DESC
+ LIMIT $3
+`, guildID, discordID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []QueueEvent
+	for rows.Next() {
+		var ev QueueEvent
+		if err := rows.Scan(&ev.ID, &ev.GuildID, &ev.QueueName, &ev.DiscordUserID, &ev.Event, &ev.Actor, &ev.Reason, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
 func durToInterval(d time.Duration) string {
 	secs := int64(d.Seconds())
 	if secs <= 0 {
@@ -17,6 +17,11 @@ type UserLink struct {
 	EloSnapshot        *int
 	SkillLevelSnapshot *int
 	GuildID            string
+	VerifiedAt         *time.Time // nil = vínculo sin probar que la cuenta es suya
+	VerificationMethod string     // oauth | code
+	VerifyCode         string
+	VerifyCodeExpires  *time.Time
+	SnapshotAt         *time.Time // cuándo se tomaron elo/nivel
 }
 
 type UserRepo struct{ db *sql.DB }
@@ -26,35 +31,66 @@ func NewUserRepo(db *sql.DB) *UserRepo { return &UserRepo{db: db} }
 var ErrNotFound = errors.New("not found")
 
 // Upsert por faceit_user_id; mantiene discord_id único.
+// La verificación se conserva mientras sea el mismo discord; si la cuenta cambia de dueño se resetea.
 func (r *UserRepo) UpsertLink(ctx context.Context, ul UserLink) error {
 	_, err := r.db.ExecContext(ctx, `
 INSERT INTO user_links
-  (faceit_user_id, discord_user_id, nickname, is_member, member_checked_at, elo_snapshot, skill_level_snapshot, guild_id, deleted_at)
+  (faceit_user_id, discord_user_id, nickname, is_member, member_checked_at, elo_snapshot, skill_level_snapshot, guild_id, deleted_at,
+   verified_at, verification_method, snapshot_at)
 VALUES
-  ($1,$2,$3,$4,$5,$6,$7,$8,NULL)
+  ($1,$2,$3,$4,$5,$6,$7,$8,NULL,$9,NULLIF($10,''),$11)
 ON CONFLICT (faceit_user_id) DO UPDATE SET
+  verified_at = CASE WHEN user_links.discord_user_id = EXCLUDED.discord_user_id AND user_links.deleted_at IS NULL
+                     THEN COALESCE(EXCLUDED.verified_at, user_links.verified_at)
+                     ELSE EXCLUDED.verified_at END,
+  verification_method = CASE WHEN user_links.discord_user_id = EXCLUDED.discord_user_id AND user_links.deleted_at IS NULL
+                     THEN COALESCE(EXCLUDED.verification_method, user_links.verification_method)
+                     ELSE EXCLUDED.verification_method END,
+  verify_code = CASE WHEN user_links.discord_user_id = EXCLUDED.discord_user_id THEN user_links.verify_code END,
+  verify_code_expires_at = CASE WHEN user_links.discord_user_id = EXCLUDED.discord_user_id THEN user_links.verify_code_expires_at END,
   discord_user_id = EXCLUDED.discord_user_id,
   nickname        = EXCLUDED.nickname,
   is_member       = EXCLUDED.is_member,
   member_checked_at = EXCLUDED.member_checked_at,
   elo_snapshot    = EXCLUDED.elo_snapshot,
   skill_level_snapshot = EXCLUDED.skill_level_snapshot,
+  snapshot_at     = COALESCE(EXCLUDED.snapshot_at, user_links.snapshot_at),
   guild_id        = EXCLUDED.guild_id,
   deleted_at      = NULL
-`, ul.FaceitUserID, ul.DiscordUserID, ul.Nickname, ul.IsMember, ul.MemberCheckedAt, ul.EloSnapshot, ul.SkillLevelSnapshot, ul.GuildID)
+`, ul.FaceitUserID, ul.DiscordUserID, ul.Nickname, ul.IsMember, ul.MemberCheckedAt, ul.EloSnapshot, ul.SkillLevelSnapshot, ul.GuildID,
+		ul.VerifiedAt, ul.VerificationMethod, ul.SnapshotAt)
 	return err
 }
 
-func (r *UserRepo) GetByDiscordID(ctx context.Context, discordID string) (UserLink, error) {
-	row := r.db.QueryRowContext(ctx, `
-SELECT faceit_user_id, discord_user_id, nickname, linked_at, is_member, member_checked_at,
-       elo_snapshot, skill_level_snapshot, guild_id
+const userLinkCols = `faceit_user_id, discord_user_id, nickname, linked_at, is_member, member_checked_at,
+       elo_snapshot, skill_level_snapshot, guild_id,
+       verified_at, COALESCE(verification_method,''), COALESCE(verify_code,''), verify_code_expires_at, snapshot_at`
+
+// GetByDiscordIDInGuild: el vínculo activo del jugador en ese guild. Los vínculos son por
+// guild (uno activo por guild y discord, migración 0003): uno hecho en otro servidor no cuenta.
+func (r *UserRepo) GetByDiscordIDInGuild(ctx context.Context, guildID, discordID string) (UserLink, error) {
+	return r.getOne(ctx, `
+SELECT `+userLinkCols+`
+FROM user_links
+WHERE guild_id = $1 AND discord_user_id = $2 AND deleted_at IS NULL
+`, guildID, discordID)
+}
+
+// GetByFaceitID: el vínculo activo de una cuenta FACEIT (sea de quien sea)
+func (r *UserRepo) GetByFaceitID(ctx context.Context, faceitUserID string) (UserLink, error) {
+	return r.getOne(ctx, `
+SELECT `+userLinkCols+`
 FROM user_links
-WHERE discord_user_id = $1 AND deleted_at IS NULL
-`, discordID)
+WHERE faceit_user_id = $1 AND deleted_at IS NULL
+`, faceitUserID)
+}
+
+func (r *UserRepo) getOne(ctx context.Context, query string, args ...any) (UserLink, error) {
+	row := r.db.QueryRowContext(ctx, query, args...)
 	var ul UserLink
 	err := row.Scan(&ul.FaceitUserID, &ul.DiscordUserID, &ul.Nickname, &ul.LinkedAt, &ul.IsMember, &ul.MemberCheckedAt,
-		&ul.EloSnapshot, &ul.SkillLevelSnapshot, &ul.GuildID)
+		&ul.EloSnapshot, &ul.SkillLevelSnapshot, &ul.GuildID,
+		&ul.VerifiedAt, &ul.VerificationMethod, &ul.VerifyCode, &ul.VerifyCodeExpires, &ul.SnapshotAt)
 	if err == sql.ErrNoRows {
 		return UserRepo{}.zero(), ErrNotFound
 	}
@@ -76,6 +112,37 @@ UPDATE user_links
 	return n > 0, nil
 }
 
+// SetVerifyCode: guarda el código que el jugador tiene que poner en su perfil FACEIT
+func (r *UserRepo) SetVerifyCode(ctx context.Context, faceitUserID, code string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE user_links
+   SET verify_code = $2, verify_code_expires_at = $3
+ WHERE faceit_user_id = $1 AND deleted_at IS NULL
+`, faceitUserID, code, expiresAt)
+	return err
+}
+
+// MarkVerified: la cuenta quedó probada como propia; el código ya no sirve
+func (r *UserRepo) MarkVerified(ctx context.Context, faceitUserID, method string) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE user_links
+   SET verified_at = now(), verification_method = $2,
+       verify_code = NULL, verify_code_expires_at = NULL
+ WHERE faceit_user_id = $1 AND deleted_at IS NULL
+`, faceitUserID, method)
+	return err
+}
+
+// UpdateSnapshots: refresca elo/nivel sin tocar el resto del vínculo
+func (r *UserRepo) UpdateSnapshots(ctx context.Context, faceitUserID string, elo, skill int) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE user_links
+   SET elo_snapshot = $2, skill_level_snapshot = $3, snapshot_at = now()
+ WHERE faceit_user_id = $1 AND deleted_at IS NULL
+`, faceitUserID, elo, skill)
+	return err
+}
+
 func (UserRepo) zero() UserLink { return UserLink{} }
 
 // internal/infra/storage/repo.go
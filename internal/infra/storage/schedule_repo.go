@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// QueueSchedule: una ventana de apertura de la cola (ver domain/schedule)
+type QueueSchedule struct {
+	ID          int64
+	GuildID     string
+	QueueName   string
+	Weekday     time.Weekday
+	OpenMinute  int
+	CloseMinute int
+	CreatedAt   time.Time
+}
+
+type ScheduleRepo struct{ db *sql.DB }
+
+func NewScheduleRepo(db *sql.DB) *ScheduleRepo { return &ScheduleRepo{db: db} }
+
+func (r *ScheduleRepo) List(ctx context.Context, guildID, queue string) ([]QueueSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, guild_id, queue_name, weekday, open_minute, close_minute, created_at
+  FROM queue_schedules
+ WHERE guild_id = $1 AND queue_name = $2
+ ORDER BY weekday, open_minute
+`, guildID, queue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []QueueSchedule
+	for rows.Next() {
+		var s QueueSchedule
+		if err := rows.Scan(&s.ID, &s.GuildID, &s.QueueName, &s.Weekday, &s.OpenMinute, &s.CloseMinute, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Add: una fila por día (todas en la misma transacción)
+func (r *ScheduleRepo) Add(ctx context.Context, guildID, queue string, days []time.Weekday, open, close int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, d := range days {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO queue_schedules (guild_id, queue_name, weekday, open_minute, close_minute)
+VALUES ($1,$2,$3,$4,$5)
+`, guildID, queue, int(d), open, close); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Clear: borra los horarios de la cola (vuelve a estar siempre abierta)
+func (r *ScheduleRepo) Clear(ctx context.Context, guildID, queue string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM queue_schedules WHERE guild_id = $1 AND queue_name = $2`, guildID, queue)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -2,23 +2,66 @@ package storage
 
 import "time"
 
+// DefaultQueue: la cola de siempre (la única que había antes de las colas con nombre)
+const DefaultQueue = "main"
+
 type QueueEntry struct {
 	GuildID       string
+	QueueName     string
 	DiscordUserID string
 	FaceitUserID  string
 	Nickname      string
 	JoinedAt      time.Time
 	LastSeenAt    time.Time
-	Status        string // waiting | afk | left
+	Status        string // waiting | afk | left | blocked
+	StatusReason  string // por qué está blocked (se muestra en el embed)
+	PartyID       int64  // 0 = solo
+	Priority      int    // peso por rol al entrar (0 = normal)
+}
+
+// QueueEvent: una transición en la cola (quién, por qué y cuándo)
+type QueueEvent struct {
+	ID            int64
+	GuildID       string
+	QueueName     string
+	DiscordUserID string
+	Event         string // join | rejoin | added | leave | kick | left | afk | back | blocked | pruned | popped | requeued | cleared | moved | swapped | locked | unlocked
+	Actor         string // discord_user_id o "system"
+	Reason        string
+	CreatedAt     time.Time
+}
+
+// QueueLock: cola cerrada a mano por un admin
+type QueueLock struct {
+	GuildID   string
+	QueueName string
+	LockedBy  string
+	Reason    string
+	CreatedAt time.Time
 }
 
 type GuildPolicy struct {
 	GuildID                  string
+	QueueName                string
 	RequireMember            bool
 	AFKTimeoutSeconds        int
 	DropIfLeftSeconds        int
 	VoiceRequired            bool
 	CooldownAfterLossSeconds int
+	MatchSize                int    // jugadores necesarios para armar un match (pop)
+	ReadyCheckSeconds        int    // ventana para aceptar el match (0 = sin ready-check)
+	ReadyPenaltySeconds      int    // penalización por decline/timeout en el ready-check
+	TeamMode                 string // balance | captains
+	JoinEnforcement          string // warn | block | remove (qué hacer si falla la validación post-join)
+	RequireVerified          bool   // sólo links verificados (oauth o código) pueden unirse
+	MinSkillLevel            int    // rango de nivel FACEIT (1-10) para unirse; 0 = sin límite
+	MaxSkillLevel            int
+	MinElo                   int // rango de elo; 0 = sin límite
+	MaxElo                   int
+	LeaveLimit               int // salidas/podas toleradas en la ventana antes del cooldown; 0 = apagado
+	LeaveWindowMinutes       int
+	LeavePenaltySeconds      int  // primer cooldown; se duplica con cada reincidencia
+	FlushOnClose             bool // al cerrar por horario, vaciar la cola
 	CreatedAt, UpdatedAt     time.Time
 }
 
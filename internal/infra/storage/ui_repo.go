@@ -6,36 +6,83 @@ import (
 	"time"
 )
 
+// GuildUI: mensaje publicado de una cola (una fila por cola con nombre)
 type GuildUI struct {
-	GuildID        string
-	QueueChannelID string
-	QueueMessageID string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	GuildID         string
+	QueueName       string
+	QueueChannelID  string
+	QueueMessageID  string
+	VoiceCategoryID string // categoría de voz propia de la cola ("" = la del guild)
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type UIRepo struct{ db *sql.DB }
 
 func NewUIRepo(db *sql.DB) *UIRepo { return &UIRepo{db: db} }
 
-func (r *UIRepo) Get(ctx context.Context, guildID string) (GuildUI, error) {
+const guildUICols = `guild_id, queue_name, queue_channel_id, queue_message_id, voice_category_id, created_at, updated_at`
+
+// Get: ErrNotFound si la cola no tiene UI publicada
+func (r *UIRepo) Get(ctx context.Context, guildID, queue string) (GuildUI, error) {
 	var u GuildUI
 	err := r.db.QueryRowContext(ctx, `
-SELECT guild_id, queue_channel_id, queue_message_id, created_at, updated_at
+SELECT `+guildUICols+`
   FROM guild_ui
- WHERE guild_id = $1
-`, guildID).Scan(&u.GuildID, &u.QueueChannelID, &u.QueueMessageID, &u.CreatedAt, &u.UpdatedAt)
+ WHERE guild_id = $1 AND queue_name = $2
+`, guildID, queue).Scan(&u.GuildID, &u.QueueName, &u.QueueChannelID, &u.QueueMessageID, &u.VoiceCategoryID, &u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return GuildUI{}, ErrNotFound
+	}
 	return u, err
 }
 
-func (r *UIRepo) Upsert(ctx context.Context, guildID, channelID, messageID string) error {
+// Upsert: la categoría de voz sólo se pisa si viene una nueva
+func (r *UIRepo) Upsert(ctx context.Context, u GuildUI) error {
 	_, err := r.db.ExecContext(ctx, `
-INSERT INTO guild_ui (guild_id, queue_channel_id, queue_message_id)
-VALUES ($1,$2,$3)
-ON CONFLICT (guild_id) DO UPDATE SET
-  queue_channel_id = EXCLUDED.queue_channel_id,
-  queue_message_id = EXCLUDED.queue_message_id,
-  updated_at       = now()
-`, guildID, channelID, messageID)
+INSERT INTO guild_ui (guild_id, queue_name, queue_channel_id, queue_message_id, voice_category_id)
+VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (guild_id, queue_name) DO UPDATE SET
+  queue_channel_id  = EXCLUDED.queue_channel_id,
+  queue_message_id  = EXCLUDED.queue_message_id,
+  voice_category_id = COALESCE(NULLIF(EXCLUDED.voice_category_id, ''), guild_ui.voice_category_id),
+  updated_at        = now()
+`, u.GuildID, u.QueueName, u.QueueChannelID, u.QueueMessageID, u.VoiceCategoryID)
 	return err
 }
+
+// ListByGuild: colas publicadas del guild ('main' primero)
+func (r *UIRepo) ListByGuild(ctx context.Context, guildID string) ([]GuildUI, error) {
+	return r.list(ctx, `
+SELECT `+guildUICols+`
+  FROM guild_ui
+ WHERE guild_id = $1
+ ORDER BY queue_name <> 'main', queue_name
+`, guildID)
+}
+
+// ListAll: todas las colas publicadas (las que el pruner/refresher tienen que mirar)
+func (r *UIRepo) ListAll(ctx context.Context) ([]GuildUI, error) {
+	return r.list(ctx, `
+SELECT `+guildUICols+`
+  FROM guild_ui
+ ORDER BY guild_id, queue_name
+`)
+}
+
+func (r *UIRepo) list(ctx context.Context, query string, args ...any) ([]GuildUI, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GuildUI
+	for rows.Next() {
+		var u GuildUI
+		if err := rows.Scan(&u.GuildID, &u.QueueName, &u.QueueChannelID, &u.QueueMessageID, &u.VoiceCategoryID, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
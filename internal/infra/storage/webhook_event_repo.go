@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookEvent: evento de FACEIT tal cual lo guardó el receptor (cmd/webhook)
+type WebhookEvent struct {
+	ID          int64
+	Type        string
+	Payload     string // JSON crudo
+	ReceivedAt  time.Time
+	ProcessedAt *time.Time // nil = el bot todavía no lo procesó
+}
+
+type WebhookEventRepo struct{ db *sql.DB }
+
+func NewWebhookEventRepo(db *sql.DB) *WebhookEventRepo { return &WebhookEventRepo{db: db} }
+
+const webhookEventCols = `id, type, payload::text, received_at, processed_at`
+
+// Get: ErrNotFound si no existe
+func (r *WebhookEventRepo) Get(ctx context.Context, id int64) (WebhookEvent, error) {
+	var e WebhookEvent
+	err := r.db.QueryRowContext(ctx, `SELECT `+webhookEventCols+` FROM webhook_events WHERE id = $1`, id).
+		Scan(&e.ID, &e.Type, &e.Payload, &e.ReceivedAt, &e.ProcessedAt)
+	if err == sql.ErrNoRows {
+		return WebhookEvent{}, ErrNotFound
+	}
+	return e, err
+}
+
+// ListPending: sin procesar, en orden de llegada
+func (r *WebhookEventRepo) ListPending(ctx context.Context, limit int) ([]WebhookEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+webhookEventCols+`
+  FROM webhook_events
+ WHERE processed_at IS NULL
+ ORDER BY id
+ LIMIT $1
+`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WebhookEvent
+	for rows.Next() {
+		var e WebhookEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.ReceivedAt, &e.ProcessedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookEventRepo) MarkProcessed(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_events SET processed_at = now() WHERE id = $1`, id)
+	return err
+}
+
+// HasNewerMatchStatus: si después de id ya se procesó otro match_status_* del mismo match
+// (un reintento viejo no tiene que pisar lo que pasó después, ej. recrear salas de un match terminado)
+func (r *WebhookEventRepo) HasNewerMatchStatus(ctx context.Context, id int64, matchID string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRowContext(ctx, `
+SELECT EXISTS (
+  SELECT 1
+    FROM webhook_events
+   WHERE id > $1
+     AND processed_at IS NOT NULL
+     AND type LIKE 'match_status_%'
+     AND $2 IN (COALESCE(payload->'payload', payload->'data')->>'match_id',
+                COALESCE(payload->'payload', payload->'data')->>'matchId',
+                COALESCE(payload->'payload', payload->'data')->>'id')
+)`, id, matchID).Scan(&ok)
+	return ok, err
+}
+
+// UpsertMatchStatus: lo mismo que hace el receptor con faceit_match_status, para reintentar
+// desde el bot lo que a él le falló. at es cuándo llegó el evento: si ya hay un estado más
+// nuevo no lo pisa.
+func (r *WebhookEventRepo) UpsertMatchStatus(ctx context.Context, matchID, hubID, status, demoURL string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO faceit_match_status (match_id, hub_id, status, demo_url, last_event)
+VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+ON CONFLICT (match_id) DO UPDATE
+SET status     = EXCLUDED.status,
+    demo_url   = EXCLUDED.demo_url,
+    last_event = EXCLUDED.last_event,
+    updated_at = now()
+WHERE faceit_match_status.last_event IS NULL OR faceit_match_status.last_event <= EXCLUDED.last_event
+`, matchID, hubID, status, demoURL, at)
+	return err
+}
+
+// WebhookDedupRepo: claves de entregas ya vistas (webhook_dedup; el janitor borra las de +7 días)
+type WebhookDedupRepo struct{ db *sql.DB }
+
+func NewWebhookDedupRepo(db *sql.DB) *WebhookDedupRepo { return &WebhookDedupRepo{db: db} }
+
+// Claim: true si la clave es nueva (y queda anotada); false si ya estaba
+func (r *WebhookDedupRepo) Claim(ctx context.Context, key string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO webhook_dedup (dedup_key) VALUES ($1) ON CONFLICT DO NOTHING`, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Release: borra la clave (la entrega no se pudo procesar y tiene que poder reintentarse)
+func (r *WebhookDedupRepo) Release(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM webhook_dedup WHERE dedup_key = $1`, key)
+	return err
+}
+
+// WebhookFailure: evento que falló al procesarse. Con NextAttemptAt en nil ya no se
+// reintenta solo (dead letter) y queda para /webhook replay.
+type WebhookFailure struct {
+	EventID       int64
+	Source        string // "bot" (worker) o "webhook" (receptor)
+	Type          string // el de webhook_events
+	HubID         string // "" si el payload no lo trae
+	Attempts      int
+	LastError     string
+	NextAttemptAt *time.Time
+	FirstFailedAt time.Time
+	LastFailedAt  time.Time
+}
+
+type WebhookFailureRepo struct{ db *sql.DB }
+
+func NewWebhookFailureRepo(db *sql.DB) *WebhookFailureRepo { return &WebhookFailureRepo{db: db} }
+
+const webhookFailureCols = `f.event_id, f.source, e.type, f.hub_id, f.attempts, f.last_error,
+       f.next_attempt_at, f.first_failed_at, f.last_failed_at`
+
+func scanWebhookFailures(rows *sql.Rows) ([]WebhookFailure, error) {
+	defer rows.Close()
+	var out []WebhookFailure
+	for rows.Next() {
+		var f WebhookFailure
+		if err := rows.Scan(&f.EventID, &f.Source, &f.Type, &f.HubID, &f.Attempts, &f.LastError,
+			&f.NextAttemptAt, &f.FirstFailedAt, &f.LastFailedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// Get: la falla abierta (sin resolver) del evento; ErrNotFound si no hay
+func (r *WebhookFailureRepo) Get(ctx context.Context, eventID int64, source string) (WebhookFailure, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+webhookFailureCols+`
+  FROM webhook_failures f
+  JOIN webhook_events e ON e.id = f.event_id
+ WHERE f.event_id = $1 AND f.source = $2 AND f.resolved_at IS NULL
+`, eventID, source)
+	if err != nil {
+		return WebhookFailure{}, err
+	}
+	fs, err := scanWebhookFailures(rows)
+	if err != nil {
+		return WebhookFailure{}, err
+	}
+	if len(fs) == 0 {
+		return WebhookFailure{}, ErrNotFound
+	}
+	return fs[0], nil
+}
+
+// Record: guarda el intento fallido (Attempts, LastError y NextAttemptAt los decide quien
+// llama). Si la falla estaba resuelta vuelve a abrirse.
+func (r *WebhookFailureRepo) Record(ctx context.Context, f WebhookFailure) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO webhook_failures (event_id, source, hub_id, attempts, last_error, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (event_id, source) DO UPDATE
+SET hub_id          = EXCLUDED.hub_id,
+    attempts        = EXCLUDED.attempts,
+    last_error      = EXCLUDED.last_error,
+    next_attempt_at = EXCLUDED.next_attempt_at,
+    first_failed_at = CASE WHEN webhook_failures.resolved_at IS NULL THEN webhook_failures.first_failed_at ELSE now() END,
+    last_failed_at  = now(),
+    resolved_at     = NULL
+`, f.EventID, f.Source, f.HubID, f.Attempts, f.LastError, f.NextAttemptAt)
+	return err
+}
+
+// Due: fallas de source a las que ya les toca el reintento, las más viejas primero
+func (r *WebhookFailureRepo) Due(ctx context.Context, source string, limit int) ([]WebhookFailure, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+webhookFailureCols+`
+  FROM webhook_failures f
+  JOIN webhook_events e ON e.id = f.event_id
+ WHERE f.source = $1 AND f.resolved_at IS NULL AND f.next_attempt_at <= now()
+ ORDER BY f.next_attempt_at, f.event_id
+ LIMIT $2
+`, source, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookFailures(rows)
+}
+
+// ListOpen: fallas sin resolver de esos hubs (y las que no traen hub), las últimas primero
+func (r *WebhookFailureRepo) ListOpen(ctx context.Context, hubIDs []string, limit int) ([]WebhookFailure, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+webhookFailureCols+`
+  FROM webhook_failures f
+  JOIN webhook_events e ON e.id = f.event_id
+ WHERE f.resolved_at IS NULL AND (f.hub_id = '' OR f.hub_id = ANY($1))
+ ORDER BY f.last_failed_at DESC
+ LIMIT $2
+`, pq.Array(hubIDs), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookFailures(rows)
+}
+
+// Resolve: da por resuelta la falla abierta del evento en ese source
+func (r *WebhookFailureRepo) Resolve(ctx context.Context, eventID int64, source string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_failures SET resolved_at = now(), next_attempt_at = NULL WHERE event_id = $1 AND source = $2 AND resolved_at IS NULL`, eventID, source)
+	return err
+}
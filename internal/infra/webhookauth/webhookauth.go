@@ -0,0 +1,145 @@
+// Package webhookauth autentica los webhooks de FACEIT para los dos receptores
+// (httpfaceit y cmd/webhook). Con secretos de firma exige HMAC-SHA256 del body con
+// timestamp y devuelve la clave de replay de la entrega; sin ellos cae al header con secreto
+// compartido de siempre. En ambos modos se aceptan dos secretos a la vez para rotarlos sin
+// cortar entregas.
+package webhookauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Faceit-Signature" // "sha256=<hex>" (o el hex solo)
+	TimestampHeader = "X-Faceit-Timestamp" // epoch en segundos
+
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrNotConfigured = errors.New("webhook sin secretos configurados")
+	ErrMissing       = errors.New("falta la firma o el timestamp")
+	ErrStale         = errors.New("timestamp fuera de la ventana permitida")
+	ErrBadSignature  = errors.New("firma o secreto inválido")
+)
+
+type Config struct {
+	SigningSecrets []string      // HMAC: actual y, durante una rotación, el anterior
+	StaticSecrets  []string      // header compartido (modo viejo), también actual + anterior
+	Tolerance      time.Duration // 0 = DefaultTolerance
+}
+
+type Verifier struct {
+	signing   []string
+	static    []string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+func New(cfg Config) *Verifier {
+	v := &Verifier{
+		signing:   nonEmpty(cfg.SigningSecrets),
+		static:    nonEmpty(cfg.StaticSecrets),
+		tolerance: cfg.Tolerance,
+		now:       time.Now,
+	}
+	if v.tolerance <= 0 {
+		v.tolerance = DefaultTolerance
+	}
+	return v
+}
+
+// Signed: si exige firma (hay secretos de firma configurados)
+func (v *Verifier) Signed() bool { return len(v.signing) > 0 }
+
+// Request: lo que cada receptor saca de su request (headers y body crudo)
+type Request struct {
+	Signature string
+	Timestamp string
+	Static    string // valor del header compartido
+	Body      []byte
+}
+
+// Verify: nil si el webhook es auténtico. Devuelve la clave de replay de la entrega
+// ("" en modo header: sin firma no hay nada que la identifique). No la anota: el receptor
+// la guarda en webhook_dedup recién cuando acepta la entrega (cmd/webhook en la misma
+// transacción que el evento), así un 500 nuestro no convierte el reintento en replay.
+func (v *Verifier) Verify(r Request) (string, error) {
+	if !v.Signed() {
+		return "", v.verifyStatic(r.Static)
+	}
+	if r.Signature == "" || r.Timestamp == "" {
+		return "", ErrMissing
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(r.Timestamp), 10, 64)
+	if err != nil {
+		return "", ErrMissing
+	}
+	if d := v.now().Sub(time.Unix(sec, 0)); d > v.tolerance || d < -v.tolerance {
+		return "", ErrStale
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(r.Signature), "sha256="))
+	if err != nil {
+		return "", ErrBadSignature
+	}
+	for _, secret := range v.signing {
+		if hmac.Equal(got, sign(secret, r.Timestamp, r.Body)) {
+			// la firma cubre timestamp + body: la misma firma dos veces es la misma entrega reenviada
+			return "sig:" + hex.EncodeToString(got), nil
+		}
+	}
+	return "", ErrBadSignature
+}
+
+func (v *Verifier) verifyStatic(got string) error {
+	if len(v.static) == 0 {
+		return ErrNotConfigured
+	}
+	for _, secret := range v.static {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) == 1 {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// Sign: firma "sha256=<hex>" de timestamp + "." + body (para quien envía y para pruebas a mano)
+func Sign(secret, timestamp string, body []byte) string {
+	return "sha256=" + hex.EncodeToString(sign(secret, timestamp, body))
+}
+
+func sign(secret, timestamp string, body []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(timestamp))
+	m.Write([]byte("."))
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// Status: código HTTP para responder según el error de Verify
+func Status(err error) int {
+	switch {
+	case err == nil:
+		return 200
+	case errors.Is(err, ErrMissing), errors.Is(err, ErrStale), errors.Is(err, ErrBadSignature), errors.Is(err, ErrNotConfigured):
+		return 401
+	}
+	return 500
+}
+
+func nonEmpty(ss []string) []string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}